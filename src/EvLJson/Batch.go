@@ -0,0 +1,171 @@
+package EvLJson
+
+import (
+	"io"
+)
+
+// DEFAULT_BATCH_SIZE is the number of events of a batch when the caller
+// provides no room for them
+const DEFAULT_BATCH_SIZE = 256
+
+type batchReceiver_t func(parser *Parser, events []BatchEvent)
+
+// BatchEvent is the compact record of a parser event in batched mode
+//
+// EVT_ENTER is implied by the EVT_ARRAY, EVT_DICT, EVT_STRING and
+// EVT_NUMBER records and is not recorded. The EVT_LEAVE of a string or
+// number carries the span of its data, in the input unless InArena is set
+// because it had to be copied, see Parser.BatchBytes
+type BatchEvent struct {
+	Kind    event_t
+	Key     bool // the EVT_STRING or EVT_LEAVE of a dict key
+	InArena bool
+	Offset  int // of the byte the event was fired on
+	Start   int
+	End     int
+}
+
+// parserBatch is the state of ParseBatched and ParseBytesBatched
+type parserBatch struct {
+	events  []BatchEvent
+	onBatch batchReceiver_t
+	counter countingByteReader
+
+	open       bool // a string or number is being recorded
+	key        bool
+	dataStart  int // of the open value in BatchData
+	aliased    bool
+	aliasStart int
+	aliasEnd   int
+}
+
+func batchOffset(p *Parser) int {
+	if p.input != nil {
+		return p.input.pos - 1
+	}
+	return int(p.batch.counter.offset())
+}
+
+// flushBatch hands the recorded events over; the data of a value still
+// being recorded is kept for its EVT_LEAVE
+//
+// Note: user can signal within this function
+func flushBatch(p *Parser) {
+	b := &p.batch
+	b.onBatch(p, b.events)
+	b.events = b.events[:0]
+	if b.open && !b.aliased {
+		p.BatchData = p.BatchData[:copy(p.BatchData, p.BatchData[b.dataStart:])]
+		b.dataStart = 0
+	} else {
+		p.BatchData = p.BatchData[:0]
+	}
+}
+
+// batchHandler is the parseHandler recording events into the batch
+type batchHandler struct{}
+
+func (batchHandler) OnEvent(p *Parser, evt event_t) {
+	if evt == EVT_ENTER {
+		return
+	}
+	b := &p.batch
+	n := len(b.events)
+	b.events = b.events[:n+1]
+	event := &b.events[n]
+	*event = BatchEvent{Kind: evt, Offset: batchOffset(p)}
+	switch evt {
+	case EVT_STRING, EVT_NUMBER:
+		b.open, b.key, b.aliased = true, evt == EVT_STRING && p.IsDictKey(), false
+		b.dataStart = len(p.BatchData)
+		event.Key = b.key
+	case EVT_LEAVE:
+		if b.open {
+			event.Key = b.key
+			if b.aliased {
+				event.Start, event.End = b.aliasStart, b.aliasEnd
+			} else {
+				event.InArena, event.Start, event.End = true, b.dataStart, len(p.BatchData)
+			}
+			b.open = false
+		}
+	}
+	if n+1 == cap(b.events) {
+		flushBatch(p)
+	}
+}
+
+func (batchHandler) OnData(p *Parser, endOfData bool) {
+	b := &p.batch
+	if p.DataAliasesInput && endOfData && len(p.BatchData) == b.dataStart {
+		// the whole value is a slice of the input
+		b.aliased, b.aliasStart, b.aliasEnd = true, p.aliasStart, p.aliasEnd
+		return
+	}
+	p.BatchData = append(p.BatchData, p.DataBuffer...)
+}
+
+func (p *Parser) beginBatch(events []BatchEvent, onBatch batchReceiver_t) {
+	if cap(events) == 0 {
+		events = make([]BatchEvent, 0, DEFAULT_BATCH_SIZE)
+	}
+	p.batch = parserBatch{events: events[:0], onBatch: onBatch}
+	p.BatchData = p.BatchData[:0]
+}
+
+// endBatch hands over the events recorded since the last batch, unless the
+// user stopped the parser
+func (p *Parser) endBatch() {
+	if len(p.batch.events) != 0 && p.userSignal != SIG_STOP {
+		flushBatch(p)
+	}
+	p.batch = parserBatch{}
+}
+
+// ParseBatched parses like Parse but records the events into events,
+// calling onBatch with them whenever it is full and once more at the end
+// of the input or at an error; a single callback then handles many events
+// in a tight loop. A nil or empty events gets room for DEFAULT_BATCH_SIZE
+//
+// The data spans of a batch are valid until onBatch returns, and onBatch
+// can stop the parser
+func (p *Parser) ParseBatched(byteReader io.ByteReader, events []BatchEvent, onBatch batchReceiver_t) error {
+	p.beginBatch(events, onBatch)
+	p.batch.counter = countingByteReader{reader: byteReader}
+	err := parseWith(p, &p.batch.counter, batchHandler{})
+	p.endBatch()
+	return err
+}
+
+// ParseBytesBatched is ParseBatched for a document in memory; strings and
+// numbers without escapes are spans of data rather than copies
+func (p *Parser) ParseBytesBatched(data []byte, events []BatchEvent, onBatch batchReceiver_t) error {
+	return p.parseBytesBatchedFrom(data, 0, HANDLE_START, events, onBatch)
+}
+
+// parseBytesBatchedFrom is ParseBytesBatched resuming at data[start] in
+// state handle, inside the top level array unless handle is HANDLE_START;
+// offsets and spans stay those of data
+func (p *Parser) parseBytesBatchedFrom(data []byte, start int, handle handle_t, events []BatchEvent, onBatch batchReceiver_t) error {
+	p.beginBatch(events, onBatch)
+	p.beginInput(data)
+	p.input.pos = start
+	if handle != HANDLE_START {
+		p.ContextStack = append(p.ContextStack[:0], HANDLE_END)
+	}
+	p.onEvent, p.OnData, p.wantsData = nil, nil, true
+	err := parse(p, p.input, batchHandler{}, handle)
+	p.endBatch()
+	p.endInput()
+	return err
+}
+
+// BatchBytes is the data of a string or number given the record of its
+// EVT_LEAVE, empty for other records; only valid within the onBatch call
+// the record is passed to
+func (p *Parser) BatchBytes(event BatchEvent) []byte {
+	if event.InArena || p.input == nil {
+		return p.BatchData[event.Start:event.End]
+	}
+	return p.input.data[event.Start:event.End]
+}
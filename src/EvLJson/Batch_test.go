@@ -0,0 +1,213 @@
+package EvLJson
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"testing"
+)
+
+const TEST_BATCH_DOC = ` {"plain": "value", "esc\"aped": ["tab\there", "é😀", ""], "n": [-12.5e+3, 0, 1E5, 0.25],` +
+	` "t": true, "f": false, "z": null, "d": {"a long key without escapes": {}, "e": []}} `
+
+// batchRecord is a recorded event as text: the kind, the key flag and the
+// data of EVT_LEAVE
+func batchRecord(evt event_t, key bool, data string) string {
+	return fmt.Sprintf("%d %v %q", evt, key, data)
+}
+
+// collectCallbackRecords is the event stream of the plain callbacks as
+// batch records are expected to describe it
+func collectCallbackRecords(doc string, options uint8) ([]string, error) {
+	var records []string
+	var value []byte
+	var keys []bool
+	evLJsonParser := NewParser(make([]byte, 0, TEST_DATA_BUFFER_SIZE), nil, options)
+	onEvent := func(parser *Parser, evt event_t) {
+		switch evt {
+		case EVT_ENTER:
+			return
+		case EVT_STRING, EVT_NUMBER:
+			keys = append(keys, evt == EVT_STRING && parser.IsDictKey())
+			value = []byte{}
+			records = append(records, batchRecord(evt, keys[len(keys)-1], ""))
+			return
+		case EVT_LEAVE:
+			if value != nil {
+				records = append(records, batchRecord(evt, keys[len(keys)-1], string(value)))
+				keys, value = keys[:len(keys)-1], nil
+				return
+			}
+		}
+		records = append(records, batchRecord(evt, false, ""))
+	}
+	onData := func(parser *Parser, endOfData bool) {
+		value = append(value, parser.DataBuffer...)
+	}
+	err := evLJsonParser.Parse(bytes.NewReader([]byte(doc)), onEvent, onData)
+	return records, err
+}
+
+func collectBatchRecords(doc string, options uint8, size int, zeroCopy bool) ([]string, []BatchEvent, int, error) {
+	var records []string
+	var events []BatchEvent
+	batches := 0
+	input := []byte(doc)
+	evLJsonParser := NewParser(make([]byte, 0, TEST_DATA_BUFFER_SIZE), nil, options)
+	onBatch := func(parser *Parser, batch []BatchEvent) {
+		if len(batch) == 0 || len(batch) > size {
+			log.Fatalf("batch of %d events", len(batch))
+		}
+		batches++
+		for _, event := range batch {
+			data := ""
+			if event.Kind == EVT_LEAVE {
+				data = string(parser.BatchBytes(event))
+			}
+			records = append(records, batchRecord(event.Kind, event.Key, data))
+		}
+		events = append(events, batch...)
+	}
+	var err error
+	if zeroCopy {
+		err = evLJsonParser.ParseBytesBatched(input, make([]BatchEvent, size), onBatch)
+	} else {
+		err = evLJsonParser.ParseBatched(bytes.NewReader(input), make([]BatchEvent, 0, size), onBatch)
+	}
+	return records, events, batches, err
+}
+
+func TestBatchMatchesCallbacks(t *testing.T) {
+	docs := []string{TEST_BATCH_DOC, string(BENCHMARK_BYTES), `[1, "x" `, `{"a": [1, 2,, 3]}`}
+	for _, doc := range docs {
+		for _, options := range []uint8{OPT_ALLOW_EXTRA_WHITESPACE, OPT_ALLOW_EXTRA_WHITESPACE | OPT_DECODE_UNICODE_ESCAPES} {
+			expected, expectedErr := collectCallbackRecords(doc, options)
+			for _, size := range []int{1, 2, 3, 7, 1024} {
+				for _, zeroCopy := range []bool{false, true} {
+					records, _, _, err := collectBatchRecords(doc, options, size, zeroCopy)
+					if (err == nil) != (expectedErr == nil) {
+						t.Fatalf("%d %v: %v != %v", size, zeroCopy, err, expectedErr)
+					}
+					if len(records) != len(expected) {
+						t.Fatalf("%d %v: %d records != %d", size, zeroCopy, len(records), len(expected))
+					}
+					for i := range expected {
+						if records[i] != expected[i] {
+							t.Fatalf("%d %v: %s != %s", size, zeroCopy, records[i], expected[i])
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestBatchSpans(t *testing.T) {
+	doc := `{"k": "plain", "e": "x\ty", "n": 12.5e3}`
+	_, events, batches, err := collectBatchRecords(doc, OPT_ALLOW_EXTRA_WHITESPACE, 64, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batches != 1 {
+		t.Fatalf("%d batches", batches)
+	}
+	if events[0].Kind != EVT_DICT || events[0].Offset != 0 || events[1].Kind != EVT_STRING || events[1].Offset != 1 || !events[1].Key {
+		t.Fatalf("%+v", events[:2])
+	}
+	var leaves []BatchEvent
+	for _, event := range events {
+		if event.Kind == EVT_LEAVE && event.End != 0 {
+			leaves = append(leaves, event)
+		}
+	}
+	expected := []struct {
+		data    string
+		inArena bool
+	}{{"k", false}, {"plain", false}, {"e", false}, {"x\ty", true}, {"n", false}, {"12.5e3", false}}
+	if len(leaves) != len(expected) {
+		t.Fatalf("%+v", leaves)
+	}
+	for i, leave := range leaves {
+		if leave.InArena != expected[i].inArena || !leave.InArena && doc[leave.Start:leave.End] != expected[i].data {
+			t.Fatalf("%+v != %+v", leave, expected[i])
+		}
+	}
+}
+
+func TestBatchStop(t *testing.T) {
+	evLJsonParser := NewParser(nil, nil, 0)
+	batches := 0
+	onBatch := func(parser *Parser, events []BatchEvent) {
+		batches++
+		parser.ParseStop()
+	}
+	if err := evLJsonParser.ParseBytesBatched([]byte(`[1,2,3,4,5,6]`), make([]BatchEvent, 2), onBatch); err != nil {
+		t.Fatal(err)
+	}
+	if batches != 1 {
+		t.Fatalf("%d batches after stop", batches)
+	}
+	evLJsonParser = NewParser(nil, nil, 0)
+	if err := evLJsonParser.ParseBatched(bytes.NewReader([]byte(`[1,2,3,4,5,6]`)), nil, onBatch); err != nil || batches != 2 {
+		t.Fatal(err, batches)
+	}
+}
+
+func benchmarkCorpusBatched(b *testing.B, zeroCopy bool) {
+	evLJsonParser := NewParser(make([]byte, TEST_DATA_BUFFER_SIZE), nil, OPT_ALLOW_EXTRA_WHITESPACE)
+	events := make([]BatchEvent, DEFAULT_BATCH_SIZE)
+	strings := 0
+	onBatch := func(parser *Parser, events []BatchEvent) {
+		for i := range events {
+			if events[i].Kind == EVT_STRING {
+				strings++
+			}
+		}
+	}
+	b.SetBytes(int64(len(BENCHMARK_BYTES)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var err error
+		if zeroCopy {
+			err = evLJsonParser.ParseBytesBatched(BENCHMARK_BYTES, events, onBatch)
+		} else {
+			err = evLJsonParser.ParseBatched(bytes.NewReader(BENCHMARK_BYTES), events, onBatch)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		evLJsonParser.Reset()
+	}
+}
+
+func BenchmarkCorpusBatched(b *testing.B)      { benchmarkCorpusBatched(b, false) }
+func BenchmarkCorpusBytesBatched(b *testing.B) { benchmarkCorpusBatched(b, true) }
+
+// benchmarkCorpusCallbacks is what the batched benchmarks save on: the
+// same work done with a call per event
+func benchmarkCorpusCallbacks(b *testing.B, zeroCopy bool) {
+	evLJsonParser := NewParser(make([]byte, TEST_DATA_BUFFER_SIZE), nil, OPT_ALLOW_EXTRA_WHITESPACE)
+	strings := 0
+	onEvent := func(parser *Parser, evt event_t) {
+		if evt == EVT_STRING {
+			strings++
+		}
+	}
+	onData := func(parser *Parser, endOfData bool) {}
+	b.SetBytes(int64(len(BENCHMARK_BYTES)))
+	for i := 0; i < b.N; i++ {
+		var err error
+		if zeroCopy {
+			err = evLJsonParser.ParseBytes(BENCHMARK_BYTES, onEvent, onData)
+		} else {
+			err = evLJsonParser.Parse(bytes.NewReader(BENCHMARK_BYTES), onEvent, onData)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		evLJsonParser.Reset()
+	}
+}
+
+func BenchmarkCorpusCallbacks(b *testing.B)      { benchmarkCorpusCallbacks(b, false) }
+func BenchmarkCorpusBytesCallbacks(b *testing.B) { benchmarkCorpusCallbacks(b, true) }
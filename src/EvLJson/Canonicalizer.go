@@ -0,0 +1,302 @@
+package EvLJson
+
+import (
+	"bufio"
+	"bytes"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+type DuplicateKeyError struct {
+	Key string
+}
+
+func (err DuplicateKeyError) Error() string {
+	return "Duplicate dict key: " + strconv.Quote(err.Key)
+}
+
+type NumberOutOfRangeError struct {
+	Number string
+}
+
+func (err NumberOutOfRangeError) Error() string {
+	return "Number is not representable as an IEEE 754 double: " + err.Number
+}
+
+type jcsMember struct {
+	sortKey []uint16 // key as utf-16 code units, the order RFC 8785 sorts by
+	text    []byte   // canonical "key":value
+}
+
+type jcsFrame struct {
+	isDict  bool
+	count   int
+	members []jcsMember
+}
+
+// canonicalizer turns parser events into RFC 8785 (JCS) output; arrays
+// outside of any dict are written straight through, everything inside a
+// dict is held until that dict closes and its members can be sorted
+type canonicalizer struct {
+	out       io.Writer
+	err       error
+	frames    []jcsFrame
+	innerDict int // index into frames of the innermost dict, -1 for none
+	token     writerToken_t
+	value     []byte
+	scratch   []byte
+}
+
+func (c *canonicalizer) emit(data []byte) {
+	if c.innerDict >= 0 {
+		members := c.frames[c.innerDict].members
+		member := &members[len(members)-1]
+		member.text = append(member.text, data...)
+		return
+	}
+	if c.err == nil {
+		_, c.err = c.out.Write(data)
+	}
+}
+
+func (c *canonicalizer) beforeValue() {
+	if len(c.frames) == 0 {
+		return
+	}
+	frame := &c.frames[len(c.frames)-1]
+	if !frame.isDict {
+		if frame.count != 0 {
+			c.emit([]byte{','})
+		}
+		frame.count++
+	}
+}
+
+func (c *canonicalizer) push(isDict bool) {
+	c.beforeValue()
+	if isDict {
+		c.frames = append(c.frames, jcsFrame{isDict: true})
+		c.innerDict = len(c.frames) - 1
+		return
+	}
+	c.frames = append(c.frames, jcsFrame{})
+	c.emit([]byte{'['})
+}
+
+func (c *canonicalizer) pop() {
+	last := len(c.frames) - 1
+	frame := c.frames[last]
+	c.frames = c.frames[:last]
+	if !frame.isDict {
+		c.emit([]byte{']'})
+		return
+	}
+	c.innerDict--
+	for c.innerDict >= 0 && !c.frames[c.innerDict].isDict {
+		c.innerDict--
+	}
+	members := frame.members
+	sort.Slice(members, func(i, j int) bool {
+		return compareUtf16(members[i].sortKey, members[j].sortKey) < 0
+	})
+	c.emit([]byte{'{'})
+	for i := range members {
+		if i != 0 {
+			if compareUtf16(members[i-1].sortKey, members[i].sortKey) == 0 {
+				c.err = DuplicateKeyError{string(utf16.Decode(members[i].sortKey))}
+				return
+			}
+			c.emit([]byte{','})
+		}
+		c.emit(members[i].text)
+	}
+	c.emit([]byte{'}'})
+}
+
+func compareUtf16(a, b []uint16) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+func (c *canonicalizer) endScalar() {
+	switch c.token {
+	case WRITER_TOKEN_KEY:
+		frame := &c.frames[c.innerDict]
+		text := append(appendEscaped([]byte{'"'}, c.value), '"', ':')
+		frame.members = append(frame.members, jcsMember{utf16.Encode(bytes.Runes(c.value)), text})
+	case WRITER_TOKEN_STRING:
+		c.emit([]byte{'"'})
+	case WRITER_TOKEN_NUMBER:
+		var err error
+		if c.scratch, err = AppendEcmaScriptNumber(c.scratch[:0], c.value); err != nil {
+			c.err = err
+			return
+		}
+		c.emit(c.scratch)
+	}
+	c.token = WRITER_TOKEN_NONE
+	c.value = c.value[:0]
+}
+
+func canonicalizeOnEvent(p *Parser, evt event_t) {
+	c := p.UserData.(*canonicalizer)
+	switch evt {
+	case EVT_NULL:
+		c.beforeValue()
+		c.emit([]byte(VALUE_STR_NULL))
+	case EVT_TRUE:
+		c.beforeValue()
+		c.emit([]byte(VALUE_STR_TRUE))
+	case EVT_FALSE:
+		c.beforeValue()
+		c.emit([]byte(VALUE_STR_FALSE))
+	case EVT_ARRAY:
+		c.push(false)
+	case EVT_DICT:
+		c.push(true)
+	case EVT_STRING:
+		if p.IsDictKey() {
+			c.token = WRITER_TOKEN_KEY
+			break
+		}
+		c.beforeValue()
+		c.token = WRITER_TOKEN_STRING
+		c.emit([]byte{'"'})
+	case EVT_NUMBER:
+		c.beforeValue()
+		c.token = WRITER_TOKEN_NUMBER
+	case EVT_LEAVE:
+		if c.token != WRITER_TOKEN_NONE {
+			c.endScalar()
+		} else {
+			c.pop()
+		}
+	}
+	if c.err != nil {
+		p.ParseStop()
+	}
+}
+
+func canonicalizeOnData(p *Parser, endOfData bool) {
+	c := p.UserData.(*canonicalizer)
+	if c.token == WRITER_TOKEN_STRING {
+		c.scratch = appendEscaped(c.scratch[:0], p.DataBuffer)
+		c.emit(c.scratch)
+	} else {
+		c.value = append(c.value, p.DataBuffer...)
+	}
+	if c.err != nil {
+		p.ParseStop()
+	}
+}
+
+// Canonicalize writes the RFC 8785 JSON Canonicalization Scheme form of the
+// document read from byteReader to out: dict members sorted by their utf-16
+// code units, numbers as ECMAScript would print them, minimal string
+// escaping and no whitespace
+//
+// Only one dict level at a time is buffered where possible, but every dict
+// is held in full until it closes since its members must be sorted
+//
+// Lone surrogate escapes are replaced with U+FFFD rather than rejected
+func Canonicalize(byteReader io.ByteReader, out io.Writer) error {
+	c := canonicalizer{out: out, innerDict: -1}
+	parser := documentParsers.Get()
+	defer documentParsers.Put(parser)
+	parser.UserData = &c
+	err := parser.Parse(byteReader, canonicalizeOnEvent, canonicalizeOnData)
+	if c.err != nil {
+		return c.err
+	}
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Digest hashes the canonical form of the document read from reader, so
+// the result does not depend on how the document was formatted
+func Digest(reader io.Reader, h hash.Hash) ([]byte, error) {
+	byteReader, ok := reader.(io.ByteReader)
+	if !ok {
+		byteReader = bufio.NewReader(reader)
+	}
+	out := bufio.NewWriter(h)
+	if err := Canonicalize(byteReader, out); err != nil {
+		return nil, err
+	}
+	if err := out.Flush(); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// AppendEcmaScriptNumber appends the json number text as ECMAScript's
+// Number.prototype.toString would print the nearest IEEE 754 double
+func AppendEcmaScriptNumber(dst []byte, text []byte) ([]byte, error) {
+	value, err := strconv.ParseFloat(string(text), 64)
+	if err != nil {
+		return dst, NumberOutOfRangeError{string(text)}
+	}
+	if value == 0 {
+		// includes negative zero
+		return append(dst, '0'), nil
+	}
+	if value < 0 {
+		dst = append(dst, '-')
+		value = -value
+	}
+
+	// shortest round trip digits, the same ones ECMAScript picks
+	var buffer [32]byte
+	formatted := strconv.AppendFloat(buffer[:0], value, 'e', -1, 64)
+	mark := bytes.IndexByte(formatted, 'e')
+	exponent, _ := strconv.Atoi(string(formatted[mark+1:]))
+	digits := formatted[:mark]
+	if len(digits) > 1 {
+		// drop the decimal point, d.ddd -> dddd
+		digits = append(digits[:1:1], digits[2:]...)
+	}
+	k := len(digits)
+	n := exponent + 1
+
+	switch {
+	case k <= n && n <= 21:
+		dst = append(dst, digits...)
+		for i := k; i < n; i++ {
+			dst = append(dst, '0')
+		}
+	case 0 < n && n <= 21:
+		dst = append(dst, digits[:n]...)
+		dst = append(dst, '.')
+		dst = append(dst, digits[n:]...)
+	case -6 < n && n <= 0:
+		dst = append(dst, '0', '.')
+		for i := n; i < 0; i++ {
+			dst = append(dst, '0')
+		}
+		dst = append(dst, digits...)
+	default:
+		dst = append(dst, digits[0])
+		if k > 1 {
+			dst = append(dst, '.')
+			dst = append(dst, digits[1:]...)
+		}
+		dst = append(dst, 'e')
+		if n-1 >= 0 {
+			dst = append(dst, '+')
+		}
+		dst = strconv.AppendInt(dst, int64(n-1), 10)
+	}
+	return dst, nil
+}
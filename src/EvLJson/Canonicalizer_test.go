@@ -0,0 +1,123 @@
+package EvLJson
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func canonicalizeString(jsonString string) (string, error) {
+	var out bytes.Buffer
+	err := Canonicalize(bytes.NewReader([]byte(jsonString)), &out)
+	return out.String(), err
+}
+
+func TestCanonicalizeRfc8785Example(t *testing.T) {
+	input := `{
+  "numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+  "literals": [null, true, false]
+}`
+	expected := `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`
+	canonical, err := canonicalizeString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canonical != expected {
+		t.Fatalf("%s\n!=\n%s", canonical, expected)
+	}
+}
+
+func TestCanonicalizeSortsByUtf16(t *testing.T) {
+	input := `{"\u20ac":"Euro Sign","\r":"Carriage Return","\ufb33":"Hebrew Letter Dalet With Dagesh","1":"One","\ud83d\ude00":"Emoji: Grinning Face","\u0080":"Control","\u00f6":"Latin Small Letter O With Diaeresis"}`
+	expected := "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}"
+	canonical, err := canonicalizeString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if canonical != expected {
+		t.Fatalf("%s\n!=\n%s", canonical, expected)
+	}
+}
+
+func TestCanonicalizeNested(t *testing.T) {
+	canonical, err := canonicalizeString(`[ {"b":[{"d":1,"c":2}],"a":{}}, [ ], 1.0 ]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `[{"a":{},"b":[{"c":2,"d":1}]},[],1]`; canonical != expected {
+		t.Fatalf("%s != %s", canonical, expected)
+	}
+}
+
+func TestCanonicalizeErrors(t *testing.T) {
+	testCases := []string{
+		`{"a":1,"b":2,"a":3}`,
+		`[1e400]`,
+		`[1,`,
+	}
+	for _, str := range testCases {
+		t.Logf(LOG_STMT_FMT, str)
+		if _, err := canonicalizeString(str); err == nil {
+			t.FailNow()
+		}
+	}
+}
+
+func TestEcmaScriptNumbers(t *testing.T) {
+	testCases := []struct {
+		bits     uint64
+		expected string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+	for _, testCase := range testCases {
+		text := strconv.FormatFloat(math.Float64frombits(testCase.bits), 'g', 17, 64)
+		number, err := AppendEcmaScriptNumber(nil, []byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(number) != testCase.expected {
+			t.Fatalf("%s: %s != %s", text, number, testCase.expected)
+		}
+	}
+}
+
+func TestDigestIgnoresFormatting(t *testing.T) {
+	first, err := Digest(bytes.NewReader([]byte(`{"b":[1,2.50],"a":"\u0041"}`)), sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Digest(bytes.NewBufferString("{ \"a\" : \"A\" ,\n\t\"b\" : [ 1e0 , 25E-1 ] }"), sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("%x != %x", first, second)
+	}
+}
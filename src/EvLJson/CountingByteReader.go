@@ -0,0 +1,41 @@
+package EvLJson
+
+import (
+	"io"
+)
+
+// countingByteReader tracks how many bytes the parser has consumed so
+// handlers can report where in the input a value started
+type countingByteReader struct {
+	reader io.ByteReader
+	count  int64
+}
+
+func (r *countingByteReader) ReadByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		r.count++
+	}
+	return b, err
+}
+
+// offset of the byte the parser is currently handling
+func (r *countingByteReader) offset() int64 {
+	return r.count - 1
+}
+
+// sliceByteReader reads from memory and lets the parser see where it is,
+// so values can be handed out as slices of the input
+type sliceByteReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *sliceByteReader) ReadByte() (byte, error) {
+	if r.pos == len(r.data) {
+		return 0, io.EOF
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b, nil
+}
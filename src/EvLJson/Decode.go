@@ -0,0 +1,650 @@
+package EvLJson
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	DECODE_DISALLOW_UNKNOWN_FIELDS = 0x01 // unknown dict members are errors instead of being skipped
+	DECODE_USE_NUMBER              = 0x02 // numbers in interface{} values become json.Number instead of float64
+)
+
+type InvalidDecodeTargetError struct {
+	Type reflect.Type
+}
+
+func (err InvalidDecodeTargetError) Error() string {
+	if err.Type == nil {
+		return "Cannot decode into nil"
+	}
+	return "Cannot decode into non-pointer or nil " + err.Type.String()
+}
+
+type UnknownFieldError struct {
+	Key string
+}
+
+func (err UnknownFieldError) Error() string {
+	return "Unknown field " + strconv.Quote(err.Key)
+}
+
+type EmbeddedPointerError struct {
+	Type reflect.Type
+}
+
+func (err EmbeddedPointerError) Error() string {
+	return "Cannot set embedded pointer to unexported struct " + err.Type.String()
+}
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	jsonNumberType      = reflect.TypeFor[json.Number]()
+)
+
+// structField is one json member of a struct type
+type structField struct {
+	name      string
+	index     []int // field index path through embedded structs
+	typ       reflect.Type
+	omitEmpty bool
+	quoted    bool // the ",string" option applies
+	tagged    bool
+	depth     int
+}
+
+type structFields struct {
+	list  []structField
+	exact map[string]int
+	fold  map[string]int // first field for each lowered name
+}
+
+var structFieldsCache sync.Map // reflect.Type to *structFields
+
+// quotable kinds take the ",string" option, directly or through an
+// unnamed pointer
+func quotable(t reflect.Type) bool {
+	if t.Name() == "" && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func collectStructFields(t reflect.Type, index []int, depth int, visiting map[reflect.Type]bool, fields []structField) []structField {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int(nil), index...), i)
+		fieldType := field.Type
+		if field.Anonymous {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if name == "" && fieldType.Kind() == reflect.Struct {
+				if !visiting[fieldType] {
+					visiting[fieldType] = true
+					fields = collectStructFields(fieldType, fieldIndex, depth+1, visiting, fields)
+					delete(visiting, fieldType)
+				}
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+		} else if !field.IsExported() {
+			continue
+		}
+		tagged := name != ""
+		if !tagged {
+			name = field.Name
+		}
+		optionList := "," + options + ","
+		fields = append(fields, structField{
+			name:      name,
+			index:     fieldIndex,
+			typ:       field.Type,
+			omitEmpty: strings.Contains(optionList, ",omitempty,"),
+			quoted:    strings.Contains(optionList, ",string,") && quotable(field.Type),
+			tagged:    tagged,
+			depth:     depth,
+		})
+	}
+	return fields
+}
+
+// cachedStructFields lists the json members of a struct type with
+// encoding/json's rules: for a repeated name the shallowest field wins,
+// then a tagged one, and remaining ties hide the name altogether
+func cachedStructFields(t reflect.Type) *structFields {
+	if cached, exists := structFieldsCache.Load(t); exists {
+		return cached.(*structFields)
+	}
+	all := collectStructFields(t, nil, 0, map[reflect.Type]bool{t: true}, nil)
+	byName := map[string][]structField{}
+	for _, field := range all {
+		byName[field.name] = append(byName[field.name], field)
+	}
+	fields := &structFields{exact: map[string]int{}, fold: map[string]int{}}
+	for _, field := range all {
+		candidates := byName[field.name]
+		if candidates == nil {
+			continue
+		}
+		byName[field.name] = nil
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].depth != candidates[j].depth {
+				return candidates[i].depth < candidates[j].depth
+			}
+			return candidates[i].tagged && !candidates[j].tagged
+		})
+		if len(candidates) > 1 && candidates[0].depth == candidates[1].depth && candidates[0].tagged == candidates[1].tagged {
+			continue
+		}
+		fields.list = append(fields.list, candidates[0])
+	}
+	// members come out in field order, as encoding/json writes them
+	sort.Slice(fields.list, func(i, j int) bool {
+		a, b := fields.list[i].index, fields.list[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	for i, field := range fields.list {
+		fields.exact[field.name] = i
+		lower := strings.ToLower(field.name)
+		if _, exists := fields.fold[lower]; !exists {
+			fields.fold[lower] = i
+		}
+	}
+	cached, _ := structFieldsCache.LoadOrStore(t, fields)
+	return cached.(*structFields)
+}
+
+// indirect walks down pointers, allocating nil ones, until it reaches a
+// value that is not a pointer or implements one of the unmarshalers; for
+// null it stops at the last pointer so that pointer can be set to nil
+func indirect(v reflect.Value, decodingNull bool) (json.Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
+	// methods on pointer receivers apply to addressable named values
+	if v.Kind() != reflect.Pointer && v.Type().Name() != "" && v.CanAddr() {
+		addr := v.Addr()
+		if addr.Type().NumMethod() > 0 && addr.CanInterface() {
+			if u, ok := addr.Interface().(json.Unmarshaler); ok {
+				return u, nil, reflect.Value{}
+			}
+			if u, ok := addr.Interface().(encoding.TextUnmarshaler); ok && !decodingNull {
+				return nil, u, reflect.Value{}
+			}
+		}
+	}
+	for {
+		// decode into what a non-nil interface points at, as encoding/json does
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			elem := v.Elem()
+			if elem.Kind() == reflect.Pointer && !elem.IsNil() && (!decodingNull || elem.Elem().Kind() == reflect.Pointer) {
+				v = elem
+				continue
+			}
+		}
+		if v.Kind() != reflect.Pointer {
+			return nil, nil, v
+		}
+		if decodingNull && v.CanSet() {
+			return nil, nil, v
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().NumMethod() > 0 && v.CanInterface() {
+			if u, ok := v.Interface().(json.Unmarshaler); ok {
+				return u, nil, reflect.Value{}
+			}
+			if u, ok := v.Interface().(encoding.TextUnmarshaler); ok && !decodingNull {
+				return nil, u, reflect.Value{}
+			}
+		}
+		v = v.Elem()
+	}
+}
+
+// reflectSink decodes into any go value the way encoding/json would; the
+// target is resolved again for every value since only then is it known
+// whether the value is null
+type reflectSink struct {
+	v       reflect.Value
+	options uint8
+	inner   Sink // unmarshalers and interface{} containers are handed off
+	any     interface{}
+	index   int
+	fields  *structFields
+	// map members are stored once the next member starts or the dict ends
+	mapKey   string
+	mapValue reflect.Value
+	pending  bool
+}
+
+func newReflectSink(v reflect.Value, options uint8) Sink {
+	return &reflectSink{v: v, options: options}
+}
+
+// ValueSink returns a Sink decoding into what v points at, with the same
+// semantics and options as DecodeWithOptions
+func ValueSink(v interface{}, options uint8) (Sink, error) {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return nil, InvalidDecodeTargetError{reflect.TypeOf(v)}
+	}
+	return newReflectSink(value.Elem(), options), nil
+}
+
+func (s *reflectSink) typeError(jsonType string, v reflect.Value) error {
+	if !v.IsValid() {
+		v = s.v
+	}
+	return SinkTypeError{jsonType, v.Type().String()}
+}
+
+func isEmptyInterface(v reflect.Value) bool {
+	return v.Kind() == reflect.Interface && v.NumMethod() == 0
+}
+
+func (s *reflectSink) Null() error {
+	u, _, v := indirect(s.v, true)
+	if u != nil {
+		return u.UnmarshalJSON([]byte(VALUE_STR_NULL))
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice:
+		v.SetZero()
+	}
+	return nil
+}
+
+func (s *reflectSink) Bool(value bool) error {
+	u, tu, v := indirect(s.v, false)
+	if u != nil {
+		if value {
+			return u.UnmarshalJSON([]byte(VALUE_STR_TRUE))
+		}
+		return u.UnmarshalJSON([]byte(VALUE_STR_FALSE))
+	}
+	if tu != nil {
+		return SinkTypeError{"bool", reflect.TypeOf(tu).String()}
+	}
+	switch {
+	case v.Kind() == reflect.Bool:
+		v.SetBool(value)
+	case isEmptyInterface(v):
+		v.Set(reflect.ValueOf(value))
+	default:
+		return s.typeError("bool", v)
+	}
+	return nil
+}
+
+func (s *reflectSink) String(value []byte) error {
+	u, tu, v := indirect(s.v, false)
+	if u != nil {
+		raw := append(appendEscaped([]byte{'"'}, value), '"')
+		return u.UnmarshalJSON(raw)
+	}
+	if tu != nil {
+		return tu.UnmarshalText(value)
+	}
+	switch {
+	case v.Kind() == reflect.String && v.Type() == jsonNumberType:
+		if _, err := strconv.ParseFloat(string(value), 64); err != nil {
+			return s.typeError("string", v)
+		}
+		v.SetString(string(value))
+	case v.Kind() == reflect.String:
+		v.SetString(string(value))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		decoded := make([]byte, base64.StdEncoding.DecodedLen(len(value)))
+		n, err := base64.StdEncoding.Decode(decoded, value)
+		if err != nil {
+			return err
+		}
+		v.SetBytes(decoded[:n])
+	case isEmptyInterface(v):
+		v.Set(reflect.ValueOf(string(value)))
+	default:
+		return s.typeError("string", v)
+	}
+	return nil
+}
+
+func (s *reflectSink) Number(text []byte) error {
+	u, tu, v := indirect(s.v, false)
+	if u != nil {
+		return u.UnmarshalJSON(text)
+	}
+	if tu != nil {
+		return SinkTypeError{"number", reflect.TypeOf(tu).String()}
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := strconv.ParseInt(string(text), 10, 64)
+		if err != nil || v.OverflowInt(number) {
+			return NumberRangeError{string(text), v.Type().String()}
+		}
+		v.SetInt(number)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		number, err := strconv.ParseUint(string(text), 10, 64)
+		if err != nil || v.OverflowUint(number) {
+			return NumberRangeError{string(text), v.Type().String()}
+		}
+		v.SetUint(number)
+	case reflect.Float32, reflect.Float64:
+		number, err := strconv.ParseFloat(string(text), v.Type().Bits())
+		if err != nil || v.OverflowFloat(number) {
+			return NumberRangeError{string(text), v.Type().String()}
+		}
+		v.SetFloat(number)
+	case reflect.String:
+		if v.Type() != jsonNumberType {
+			return s.typeError("number", v)
+		}
+		v.SetString(string(text))
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return s.typeError("number", v)
+		}
+		if s.options&DECODE_USE_NUMBER != 0 {
+			v.Set(reflect.ValueOf(json.Number(text)))
+			return nil
+		}
+		number, err := strconv.ParseFloat(string(text), 64)
+		if err != nil {
+			return NumberRangeError{string(text), "float64"}
+		}
+		v.Set(reflect.ValueOf(number))
+	default:
+		return s.typeError("number", v)
+	}
+	return nil
+}
+
+// beginContainer resolves the target of an array or dict, handing it off
+// to inner when the container is not decoded field by field
+func (s *reflectSink) beginContainer(jsonType string) (reflect.Value, error) {
+	u, tu, v := indirect(s.v, false)
+	if u != nil {
+		s.inner = UnmarshalerSink(u)
+		return v, nil
+	}
+	if tu != nil {
+		return v, SinkTypeError{jsonType, reflect.TypeOf(tu).String()}
+	}
+	if isEmptyInterface(v) {
+		s.inner = &anySink{p: &s.any, useNumber: s.options&DECODE_USE_NUMBER != 0}
+	}
+	s.v = v
+	return v, nil
+}
+
+func (s *reflectSink) BeginArray() error {
+	v, err := s.beginContainer("array")
+	if err != nil {
+		return err
+	}
+	if s.inner != nil {
+		return s.inner.BeginArray()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+	default:
+		return s.typeError("array", v)
+	}
+	s.index = 0
+	return nil
+}
+
+func (s *reflectSink) Item() (Sink, error) {
+	if s.inner != nil {
+		return s.inner.Item()
+	}
+	i := s.index
+	s.index++
+	if s.v.Kind() == reflect.Array {
+		if i >= s.v.Len() {
+			// encoding/json drops extra items
+			return SkipSink(), nil
+		}
+		return newReflectSink(s.v.Index(i), s.options), nil
+	}
+	// existing items are decoded into, as encoding/json does
+	if i >= s.v.Len() {
+		if i >= s.v.Cap() {
+			s.v.Grow(1)
+		}
+		s.v.SetLen(i + 1)
+		s.v.Index(i).SetZero()
+	}
+	return newReflectSink(s.v.Index(i), s.options), nil
+}
+
+func (s *reflectSink) BeginDict() error {
+	v, err := s.beginContainer("dict")
+	if err != nil {
+		return err
+	}
+	if s.inner != nil {
+		return s.inner.BeginDict()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		switch v.Type().Key().Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !reflect.PointerTo(v.Type().Key()).Implements(textUnmarshalerType) {
+				return s.typeError("dict", v)
+			}
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	case reflect.Struct:
+		s.fields = cachedStructFields(v.Type())
+	default:
+		return s.typeError("dict", v)
+	}
+	return nil
+}
+
+func (s *reflectSink) mapKeyValue() (reflect.Value, error) {
+	keyType := s.v.Type().Key()
+	if reflect.PointerTo(keyType).Implements(textUnmarshalerType) {
+		key := reflect.New(keyType)
+		if err := key.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s.mapKey)); err != nil {
+			return key, err
+		}
+		return key.Elem(), nil
+	}
+	switch keyType.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(s.mapKey).Convert(keyType), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, err := strconv.ParseInt(s.mapKey, 10, 64)
+		if err != nil || reflect.Zero(keyType).OverflowInt(number) {
+			return reflect.Value{}, SinkTypeError{"number " + s.mapKey, keyType.String()}
+		}
+		return reflect.ValueOf(number).Convert(keyType), nil
+	default:
+		number, err := strconv.ParseUint(s.mapKey, 10, 64)
+		if err != nil || reflect.Zero(keyType).OverflowUint(number) {
+			return reflect.Value{}, SinkTypeError{"number " + s.mapKey, keyType.String()}
+		}
+		return reflect.ValueOf(number).Convert(keyType), nil
+	}
+}
+
+func (s *reflectSink) commit() error {
+	if !s.pending {
+		return nil
+	}
+	s.pending = false
+	key, err := s.mapKeyValue()
+	if err != nil {
+		return err
+	}
+	s.v.SetMapIndex(key, s.mapValue)
+	return nil
+}
+
+// fieldValue walks the index path, allocating nil embedded pointers
+func fieldValue(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, fieldIndex := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return v, EmbeddedPointerError{v.Type().Elem()}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(fieldIndex)
+	}
+	return v, nil
+}
+
+func (s *reflectSink) Member(key []byte) (Sink, error) {
+	if s.inner != nil {
+		return s.inner.Member(key)
+	}
+	if s.v.Kind() == reflect.Map {
+		if err := s.commit(); err != nil {
+			return nil, err
+		}
+		s.mapKey = string(key)
+		s.mapValue = reflect.New(s.v.Type().Elem()).Elem()
+		s.pending = true
+		return newReflectSink(s.mapValue, s.options), nil
+	}
+	i, exists := s.fields.exact[string(key)]
+	if !exists {
+		i, exists = s.fields.fold[FoldKey(key)]
+	}
+	if !exists {
+		if s.options&DECODE_DISALLOW_UNKNOWN_FIELDS != 0 {
+			return nil, UnknownFieldError{string(key)}
+		}
+		return SkipSink(), nil
+	}
+	field := s.fields.list[i]
+	v, err := fieldValue(s.v, field.index)
+	if err != nil {
+		return nil, err
+	}
+	if field.quoted {
+		return &quotedSink{BaseSink{"quoted " + field.typ.String()}, newReflectSink(v, s.options)}, nil
+	}
+	return newReflectSink(v, s.options), nil
+}
+
+func (s *reflectSink) End() error {
+	if s.inner != nil {
+		if err := s.inner.End(); err != nil {
+			return err
+		}
+		if _, isAny := s.inner.(*anySink); isAny {
+			s.v.Set(reflect.ValueOf(s.any))
+		}
+		return nil
+	}
+	switch s.v.Kind() {
+	case reflect.Slice:
+		if s.index == 0 {
+			// [] is an empty slice, not nil
+			s.v.Set(reflect.MakeSlice(s.v.Type(), 0, 0))
+		} else {
+			s.v.SetLen(s.index)
+		}
+	case reflect.Array:
+		for i := s.index; i < s.v.Len(); i++ {
+			s.v.Index(i).SetZero()
+		}
+	case reflect.Map:
+		return s.commit()
+	}
+	return nil
+}
+
+// quotedSink implements the ",string" option: the value is json text
+// inside a json string
+type quotedSink struct {
+	BaseSink
+	inner Sink
+}
+
+// QuotedSink implements the ",string" option for inner: the value is read
+// from the json text inside a json string
+func QuotedSink(inner Sink) Sink {
+	return &quotedSink{BaseSink{"quoted value"}, inner}
+}
+
+func (s *quotedSink) Null() error {
+	return s.inner.Null()
+}
+
+func (s *quotedSink) String(value []byte) error {
+	if len(value) == 0 || isCharWhitespace(value[0]) || isCharWhitespace(value[len(value)-1]) {
+		// encoding/json takes the quoted text as it is, without whitespace
+		return SinkTypeError{"string " + strconv.Quote(string(value)), s.GoType}
+	}
+	err := decodeValue(value, s.inner)
+	if decodeErr, ok := err.(DecodeError); ok && decodeErr.Pointer == "/0" {
+		// the quoted value itself did not fit
+		return decodeErr.Err
+	}
+	if err != nil {
+		return SinkTypeError{"string " + strconv.Quote(string(value)), s.GoType}
+	}
+	return nil
+}
+
+// Decode reads one document from reader into v, which must be a non-nil
+// pointer, following encoding/json's rules for struct tags, embedded
+// structs, unmarshalers and what each json value can be stored in
+func Decode(reader io.Reader, v interface{}) error {
+	return DecodeWithOptions(reader, v, 0)
+}
+
+// DecodeWithOptions is Decode with DECODE_* options; mismatches come back
+// as a DecodeError with the json pointer and byte offset of the value. A
+// value that does not fit its go type is skipped as by encoding/json, and
+// the first of them returned once the rest of the document is decoded
+func DecodeWithOptions(reader io.Reader, v interface{}, options uint8) error {
+	sink, err := ValueSink(v, options)
+	if err != nil {
+		return err
+	}
+	byteReader, ok := reader.(io.ByteReader)
+	if !ok {
+		byteReader = bufio.NewReader(reader)
+	}
+	return decodeSink(byteReader, sink, true)
+}
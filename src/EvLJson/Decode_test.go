@@ -0,0 +1,237 @@
+package EvLJson
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type decodeUpper string
+
+func (u *decodeUpper) UnmarshalText(text []byte) error {
+	*u = decodeUpper(strings.ToUpper(string(text)))
+	return nil
+}
+
+type decodeRaw struct {
+	raw string
+}
+
+func (r *decodeRaw) UnmarshalJSON(raw []byte) error {
+	r.raw = string(raw)
+	return nil
+}
+
+type decodeInner struct {
+	A int    `json:"a"`
+	B string `json:"b,omitempty"`
+}
+
+type DecodeExported struct {
+	E float64
+}
+
+type decodeOuter struct {
+	decodeInner
+	*DecodeExported
+	A       string                  `json:"a"` // shadows decodeInner.A
+	Quoted  int64                   `json:"quoted,string"`
+	QBool   bool                    `json:",string"`
+	Ptr     *int                    `json:"ptr"`
+	PtrPtr  **string                `json:"ptr_ptr"`
+	Slice   []decodeInner           `json:"slice"`
+	Array   [2]int                  `json:"array"`
+	Map     map[string]*decodeInner `json:"map"`
+	IntMap  map[int]bool            `json:"int_map"`
+	TextMap map[decodeUpper]int     `json:"text_map"`
+	Any     interface{}             `json:"any"`
+	Bytes   []byte                  `json:"bytes"`
+	Upper   decodeUpper             `json:"upper"`
+	Raw     decodeRaw               `json:"raw"`
+	RawPtr  *decodeRaw              `json:"raw_ptr"`
+	When    time.Time               `json:"when"`
+	Addr    netip.Addr              `json:"addr"`
+	Number  json.Number             `json:"number"`
+	Skipped string                  `json:"-"`
+	Dash    string                  `json:"-,"`
+	private int
+}
+
+const TEST_DECODE_DOC = `{
+	"a": "shadowing", "b": "inner", "E": 2.5,
+	"quoted": "-42", "QBool": "true",
+	"ptr": 7, "ptr_ptr": "pp",
+	"slice": [{"a": 1}, {"A": 2, "B": "folded"}],
+	"array": [1, 2, 3],
+	"map": {"x": {"a": 1}, "y": null},
+	"int_map": {"-1": true, "2": false},
+	"text_map": {"k": 1},
+	"any": [1, {"z": [null]}, "s", false],
+	"bytes": "AAEC",
+	"upper": "shout",
+	"raw": [1, {"k": "é"}],
+	"raw_ptr": null,
+	"when": "2024-01-02T03:04:05.5Z",
+	"addr": "10.0.0.1",
+	"number": 1.50,
+	"Skipped": "x",
+	"-": "dash",
+	"unknown": {"x": [1, 2]}
+}`
+
+func TestDecodeMatchesEncodingJson(t *testing.T) {
+	var decoded, expected decodeOuter
+	if err := Decode(strings.NewReader(TEST_DECODE_DOC), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(TEST_DECODE_DOC), &expected); err != nil {
+		t.Fatal(err)
+	}
+	// the raw json is minified by the Writer rather than passed through
+	if decoded.Raw.raw != `[1,{"k":"é"}]` || expected.Raw.raw == "" {
+		t.Fatal(decoded.Raw.raw)
+	}
+	decoded.Raw, expected.Raw = decodeRaw{}, decodeRaw{}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Logf("%+v", decoded)
+		t.Fatalf("%+v", expected)
+	}
+}
+
+func TestDecodeIntoExisting(t *testing.T) {
+	doc := `{"slice":[{"a":5}],"map":{"x":null},"ptr":null,"any":null}`
+	seven := 7
+	existing := func() decodeOuter {
+		return decodeOuter{
+			Slice: []decodeInner{{1, "keep"}, {2, "drop"}},
+			Map:   map[string]*decodeInner{"x": {A: 1}, "old": {}},
+			Ptr:   &seven,
+			Any:   "something",
+		}
+	}
+	decoded, expected := existing(), existing()
+	if err := Decode(strings.NewReader(doc), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(doc), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Logf("%+v", decoded)
+		t.Fatalf("%+v", expected)
+	}
+}
+
+func TestDecodeUseNumber(t *testing.T) {
+	var decoded interface{}
+	if err := DecodeWithOptions(strings.NewReader(`[1.0, {"a": 1e400}]`), &decoded, DECODE_USE_NUMBER); err != nil {
+		t.Fatal(err)
+	}
+	expected := []interface{}{json.Number("1.0"), map[string]interface{}{"a": json.Number("1e400")}}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Fatalf("%#v", decoded)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		doc     string
+		options uint8
+		pointer string
+		offset  int64
+		err     error
+	}{
+		{`{"a": 1}`, 0, "/a", 6, SinkTypeError{"number", "string"}},
+		{`{"slice": [{"a": "1"}]}`, 0, "/slice/0/a", 17, SinkTypeError{"string", "int"}},
+		{`{"int_map": {"x": true}}`, 0, "/int_map/x", 18, SinkTypeError{"number x", "int"}},
+		{`{"array": [1, 300000000000000000000]}`, 0, "/array/1", 14, NumberRangeError{"300000000000000000000", "int"}},
+		{`{"upper": 1}`, 0, "/upper", 10, SinkTypeError{"number", "*EvLJson.decodeUpper"}},
+		{`{"c": 1}`, DECODE_DISALLOW_UNKNOWN_FIELDS, "/c", 6, UnknownFieldError{"c"}},
+		{`{"quoted": "x"}`, 0, "/quoted", 11, SinkTypeError{`string "x"`, "quoted int64"}},
+		{`{"quoted": " 1"}`, 0, "/quoted", 11, SinkTypeError{`string " 1"`, "quoted int64"}},
+		{`{"quoted": "1\n"}`, 0, "/quoted", 11, SinkTypeError{`string "1\n"`, "quoted int64"}},
+		{`{"quoted": "1,2"}`, 0, "/quoted", 11, SinkTypeError{`string "1,2"`, "quoted int64"}},
+		{`{"quoted": ""}`, 0, "/quoted", 11, SinkTypeError{`string ""`, "quoted int64"}},
+		{`{"QBool": "true "}`, 0, "/QBool", 10, SinkTypeError{`string "true "`, "quoted bool"}},
+	}
+	for _, test := range tests {
+		var decoded decodeOuter
+		err := DecodeWithOptions(strings.NewReader(test.doc), &decoded, test.options)
+		var decodeErr DecodeError
+		if !errors.As(err, &decodeErr) || decodeErr.Pointer != test.pointer || decodeErr.Offset != test.offset || decodeErr.Err != test.err {
+			t.Logf(LOG_STMT_FMT, test.doc)
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDecodeScalars(t *testing.T) {
+	for _, doc := range []string{`1`, `-2.5e3`, ` "s" `, `null`, `true`, "\n\"\\u00e9\"\n"} {
+		var decoded, expected interface{}
+		if err := Decode(strings.NewReader(doc), &decoded); err != nil {
+			t.Fatal(doc, err)
+		}
+		if err := json.Unmarshal([]byte(doc), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, expected) {
+			t.Fatalf("%q: %#v != %#v", doc, decoded, expected)
+		}
+	}
+	var number int
+	var decodeErr DecodeError
+	if err := Decode(strings.NewReader(` "s"`), &number); !errors.As(err, &decodeErr) || decodeErr.Pointer != "" || decodeErr.Offset != 1 {
+		t.Fatal(err)
+	}
+	for _, doc := range []string{``, ` `, `1 2`, `"s`, `1,`, `tru`} {
+		var decoded interface{}
+		if err := Decode(strings.NewReader(doc), &decoded); err == nil {
+			t.Fatalf("%q accepted", doc)
+		}
+	}
+}
+
+func TestDecodeGoesOnPastTypeErrors(t *testing.T) {
+	type target struct {
+		F     float64
+		I     int
+		S     string
+		L     []int
+		After string
+	}
+	doc := `{"f": 1e400, "i": "x", "s": 1, "l": {"a": [1]}, "after": "kept"}`
+	var decoded target
+	err := Decode(strings.NewReader(doc), &decoded)
+	if !reflect.DeepEqual(decoded, target{After: "kept"}) {
+		t.Fatalf("%+v", decoded)
+	}
+	var decodeErr DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Pointer != "/f" || decodeErr.Err != (NumberRangeError{"1e400", "float64"}) {
+		t.Fatal(err)
+	}
+	// a document in error wins over the values that did not fit
+	if err := Decode(strings.NewReader(`{"f": 1e400, "after": }`), &decoded); errors.As(err, &decodeErr) {
+		t.Fatal(err)
+	}
+}
+
+func TestDecodeInvalidTarget(t *testing.T) {
+	var decoded decodeOuter
+	for _, target := range []interface{}{nil, decoded, (*decodeOuter)(nil)} {
+		if err := Decode(strings.NewReader(`{}`), target); err == nil {
+			t.Fatal(target)
+		}
+	}
+	type unexportedPointer struct {
+		*decodeInner
+	}
+	var embedded unexportedPointer
+	var pointerErr EmbeddedPointerError
+	if err := Decode(strings.NewReader(`{"a": 1}`), &embedded); !errors.As(err, &pointerErr) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,635 @@
+package EvLJson
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"unsafe"
+)
+
+const ( // valueKind_t
+	VALUE_NULL = iota
+	VALUE_BOOL
+	VALUE_NUMBER
+	VALUE_STRING
+	VALUE_ARRAY
+	VALUE_OBJECT
+)
+
+const ( // duplicateKeys_t
+	DUPLICATE_KEYS_KEEP      = iota // every member is kept in document order
+	DUPLICATE_KEYS_REJECT           // a repeated key fails the parse
+	DUPLICATE_KEYS_LAST_WINS        // the last value takes the place of the first member
+)
+
+const (
+	DEFAULT_DOM_MAX_DEPTH = MAX_ENCODE_DEPTH
+	DEFAULT_DOM_MAX_SIZE  = 64 * 1024 * 1024 // bytes of keys, strings and numbers
+
+	DOM_SLAB_VALUES = 256
+	DOM_SLAB_BYTES  = 16 * 1024
+)
+
+// objects with more members than this index their keys while being built
+const domKeyIndexThreshold = 16
+
+type valueKind_t uint8
+type duplicateKeys_t uint8
+
+type DomLimitError struct {
+	Limit string
+	Value int
+}
+
+func (err DomLimitError) Error() string {
+	return "Document exceeds " + err.Limit + " of " + strconv.Itoa(err.Value)
+}
+
+type InvalidNumberError struct {
+	Text string
+}
+
+func (err InvalidNumberError) Error() string {
+	return "Invalid json number " + strconv.Quote(err.Text)
+}
+
+type Member struct {
+	Key   string
+	Value *Value
+}
+
+// Value is one node of a document tree; numbers keep the text they were
+// written with so nothing is lost to float conversion, and object members
+// keep document order
+type Value struct {
+	Kind    valueKind_t
+	Bool    bool
+	Text    string // the string, or the number as written
+	Items   []*Value
+	Members []Member
+}
+
+func NullValue() *Value {
+	return &Value{Kind: VALUE_NULL}
+}
+
+func BoolValue(value bool) *Value {
+	return &Value{Kind: VALUE_BOOL, Bool: value}
+}
+
+func StringValue(value string) *Value {
+	return &Value{Kind: VALUE_STRING, Text: value}
+}
+
+// NumberValue checks that text is a json number
+func NumberValue(text string) (*Value, error) {
+	if !isJsonNumber(text) {
+		return nil, InvalidNumberError{text}
+	}
+	return &Value{Kind: VALUE_NUMBER, Text: text}, nil
+}
+
+func FloatValue(value float64) *Value {
+	return &Value{Kind: VALUE_NUMBER, Text: strconv.FormatFloat(value, 'g', -1, 64)}
+}
+
+func IntValue(value int64) *Value {
+	return &Value{Kind: VALUE_NUMBER, Text: strconv.FormatInt(value, 10)}
+}
+
+func ArrayValue(items ...*Value) *Value {
+	return &Value{Kind: VALUE_ARRAY, Items: items}
+}
+
+func ObjectValue(members ...Member) *Value {
+	return &Value{Kind: VALUE_OBJECT, Members: members}
+}
+
+func (v *Value) Float64() (float64, error) {
+	return strconv.ParseFloat(v.Text, 64)
+}
+
+func (v *Value) Int64() (int64, error) {
+	return strconv.ParseInt(v.Text, 10, 64)
+}
+
+// Len is the number of items or members
+func (v *Value) Len() int {
+	if v.Kind == VALUE_OBJECT {
+		return len(v.Members)
+	}
+	return len(v.Items)
+}
+
+func (v *Value) memberIndex(key string) int {
+	// the last of repeated keys is the one decoders see
+	for i := len(v.Members) - 1; i >= 0; i-- {
+		if v.Members[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the value of the member named key, the last one if the key
+// is repeated, or nil
+func (v *Value) Get(key string) *Value {
+	if v.Kind != VALUE_OBJECT {
+		return nil
+	}
+	if i := v.memberIndex(key); i >= 0 {
+		return v.Members[i].Value
+	}
+	return nil
+}
+
+// Index returns the item at index or nil
+func (v *Value) Index(index int) *Value {
+	if v.Kind != VALUE_ARRAY || index < 0 || index >= len(v.Items) {
+		return nil
+	}
+	return v.Items[index]
+}
+
+// Set replaces the value of the member named key or adds a member
+func (v *Value) Set(key string, value *Value) {
+	if i := v.memberIndex(key); i >= 0 {
+		v.Members[i].Value = value
+		return
+	}
+	v.Members = append(v.Members, Member{key, value})
+}
+
+// Delete removes every member named key and reports whether there was one
+func (v *Value) Delete(key string) bool {
+	kept := v.Members[:0]
+	for _, member := range v.Members {
+		if member.Key != key {
+			kept = append(kept, member)
+		}
+	}
+	deleted := len(kept) != len(v.Members)
+	clear(v.Members[len(kept):])
+	v.Members = kept
+	return deleted
+}
+
+func (v *Value) Append(items ...*Value) {
+	v.Items = append(v.Items, items...)
+}
+
+// Remove takes the item at index out of the array and reports whether
+// there was one
+func (v *Value) Remove(index int) bool {
+	if v.Kind != VALUE_ARRAY || index < 0 || index >= len(v.Items) {
+		return false
+	}
+	last := len(v.Items) - 1
+	copy(v.Items[index:], v.Items[index+1:])
+	v.Items[last] = nil
+	v.Items = v.Items[:last]
+	return true
+}
+
+// child finds the value a single pointer token refers to
+func (v *Value) child(token string) *Value {
+	switch v.Kind {
+	case VALUE_OBJECT:
+		return v.Get(token)
+	case VALUE_ARRAY:
+		index, ok := pointerIndex(token)
+		if !ok {
+			return nil
+		}
+		return v.Index(index)
+	}
+	return nil
+}
+
+// pointerIndex reads an array index token, which has no sign or leading zeros
+func pointerIndex(token string) (int, bool) {
+	if token == "" || len(token) > 1 && token[0] == '0' {
+		return 0, false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return 0, false
+		}
+	}
+	index, err := strconv.Atoi(token)
+	return index, err == nil
+}
+
+// Pointer returns the value at the json pointer
+func (v *Value) Pointer(pointer string) (*Value, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		if v = v.child(token); v == nil {
+			return nil, PointerNotFoundError{pointer}
+		}
+	}
+	return v, nil
+}
+
+// SetPointer replaces the value at the json pointer; the last token may
+// also name a new member, or "-" to append to an array
+func (v *Value) SetPointer(pointer string, value *Value) error {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		*v = *value
+		return nil
+	}
+	last := len(tokens) - 1
+	for _, token := range tokens[:last] {
+		if v = v.child(token); v == nil {
+			return PointerNotFoundError{pointer}
+		}
+	}
+	token := tokens[last]
+	switch v.Kind {
+	case VALUE_OBJECT:
+		v.Set(token, value)
+		return nil
+	case VALUE_ARRAY:
+		if token == "-" {
+			v.Append(value)
+			return nil
+		}
+		if index, ok := pointerIndex(token); ok && index < len(v.Items) {
+			v.Items[index] = value
+			return nil
+		}
+	}
+	return PointerNotFoundError{pointer}
+}
+
+// DeletePointer removes the member or item at the json pointer
+func (v *Value) DeletePointer(pointer string) error {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return InvalidPointerError{pointer}
+	}
+	last := len(tokens) - 1
+	for _, token := range tokens[:last] {
+		if v = v.child(token); v == nil {
+			return PointerNotFoundError{pointer}
+		}
+	}
+	token := tokens[last]
+	switch v.Kind {
+	case VALUE_OBJECT:
+		if v.Delete(token) {
+			return nil
+		}
+	case VALUE_ARRAY:
+		if index, ok := pointerIndex(token); ok && v.Remove(index) {
+			return nil
+		}
+	}
+	return PointerNotFoundError{pointer}
+}
+
+// Write serialises the tree through w without flushing it
+func (v *Value) Write(w *Writer) {
+	switch v.Kind {
+	case VALUE_NULL:
+		w.Null()
+	case VALUE_BOOL:
+		w.Bool(v.Bool)
+	case VALUE_NUMBER:
+		w.Number(v.Text)
+	case VALUE_STRING:
+		w.String(v.Text)
+	case VALUE_ARRAY:
+		w.BeginArray()
+		for _, item := range v.Items {
+			item.Write(w)
+		}
+		w.EndArray()
+	case VALUE_OBJECT:
+		w.BeginDict()
+		for _, member := range v.Members {
+			w.Key(member.Key)
+			member.Value.Write(w)
+		}
+		w.EndDict()
+	}
+}
+
+func (v *Value) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	v.Write(&w)
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+type domFrame struct {
+	value *Value
+	start int            // first child on the builder's items or members stack
+	keys  map[string]int // member positions, once the object is large
+	key   string         // where the container sits in its parent, for errors
+	index int            // -1 when key applies
+}
+
+// DomBuilder assembles parser events into a Value tree; values, child
+// lists and text are carved out of slabs so a document costs a handful of
+// allocations rather than several per node
+//
+// Trees stay valid after the next Parse, which starts fresh slabs
+type DomBuilder struct {
+	Duplicates duplicateKeys_t // rejected keys fail with a DecodeError wrapping DuplicateKeyError
+	MaxDepth   int             // containers open at once
+	MaxSize    int             // bytes of keys, strings and numbers in the document
+
+	values  []Value
+	itemBuf []*Value
+	members []Member
+	text    []byte
+	start   int // first byte of the text being assembled
+
+	frames    []domFrame
+	itemStack []*Value
+	memStack  []Member
+	reader    countingByteReader
+	keyOffset int64
+	token     writerToken_t
+	size      int
+	result    *Value
+	err       error
+}
+
+func NewDomBuilder() DomBuilder {
+	return DomBuilder{MaxDepth: DEFAULT_DOM_MAX_DEPTH, MaxSize: DEFAULT_DOM_MAX_SIZE}
+}
+
+func (b *DomBuilder) newValue(kind valueKind_t) *Value {
+	if len(b.values) == cap(b.values) {
+		b.values = make([]Value, 0, DOM_SLAB_VALUES)
+	}
+	b.values = append(b.values, Value{Kind: kind})
+	return &b.values[len(b.values)-1]
+}
+
+// allocItems copies items into the slab, capped so appending to the result
+// never runs into a neighbour
+func (b *DomBuilder) allocItems(items []*Value) []*Value {
+	n := len(items)
+	if n == 0 {
+		return []*Value{}
+	}
+	if n > DOM_SLAB_VALUES/4 {
+		return append([]*Value(nil), items...)
+	}
+	if cap(b.itemBuf)-len(b.itemBuf) < n {
+		b.itemBuf = make([]*Value, 0, DOM_SLAB_VALUES)
+	}
+	start := len(b.itemBuf)
+	b.itemBuf = append(b.itemBuf, items...)
+	return b.itemBuf[start:len(b.itemBuf):len(b.itemBuf)]
+}
+
+func (b *DomBuilder) allocMembers(members []Member) []Member {
+	n := len(members)
+	if n == 0 {
+		return []Member{}
+	}
+	if n > DOM_SLAB_VALUES/4 {
+		return append([]Member(nil), members...)
+	}
+	if cap(b.members)-len(b.members) < n {
+		b.members = make([]Member, 0, DOM_SLAB_VALUES)
+	}
+	start := len(b.members)
+	b.members = append(b.members, members...)
+	return b.members[start:len(b.members):len(b.members)]
+}
+
+func (b *DomBuilder) appendText(data []byte) {
+	if len(b.text)+len(data) > cap(b.text) {
+		pending := len(b.text) - b.start
+		slab := make([]byte, 0, max(DOM_SLAB_BYTES, 2*(pending+len(data))))
+		b.text = append(slab, b.text[b.start:]...)
+		b.start = 0
+	}
+	b.text = append(b.text, data...)
+}
+
+// takeText returns the assembled text as a string sharing the slab; slab
+// bytes are never written twice, which keeps the string immutable
+func (b *DomBuilder) takeText() string {
+	n := len(b.text) - b.start
+	if n == 0 {
+		return ""
+	}
+	text := unsafe.String(&b.text[b.start], n)
+	b.start = len(b.text)
+	return text
+}
+
+// pointer describes where the member being added would go
+func (b *DomBuilder) pointer() string {
+	var pointer []byte
+	for _, frame := range b.frames[1:] {
+		pointer = append(pointer, '/')
+		if frame.index < 0 {
+			pointer = append(pointer, escapePointerToken(frame.key)...)
+		} else {
+			pointer = strconv.AppendInt(pointer, int64(frame.index), 10)
+		}
+	}
+	pointer = append(pointer, '/')
+	pointer = append(pointer, escapePointerToken(b.memStack[len(b.memStack)-1].Key)...)
+	return string(pointer)
+}
+
+func (b *DomBuilder) fail(p *Parser, err error) {
+	if b.err == nil {
+		b.err = err
+	}
+	p.ParseStop()
+}
+
+func (b *DomBuilder) add(p *Parser, value *Value) {
+	if len(b.frames) == 0 {
+		b.result = value
+		return
+	}
+	frame := &b.frames[len(b.frames)-1]
+	if frame.value.Kind == VALUE_ARRAY {
+		b.itemStack = append(b.itemStack, value)
+		return
+	}
+	// the key was pushed with a nil value
+	last := len(b.memStack) - 1
+	b.memStack[last].Value = value
+	if b.Duplicates == DUPLICATE_KEYS_KEEP {
+		return
+	}
+	key := b.memStack[last].Key
+	existing := -1
+	if frame.keys != nil {
+		if i, exists := frame.keys[key]; exists {
+			existing = i
+		}
+	} else {
+		for i := frame.start; i < last; i++ {
+			if b.memStack[i].Key == key {
+				existing = i
+				break
+			}
+		}
+	}
+	if existing >= 0 {
+		if b.Duplicates == DUPLICATE_KEYS_REJECT {
+			b.fail(p, DecodeError{b.pointer(), b.keyOffset, DuplicateKeyError{key}})
+			return
+		}
+		b.memStack[existing].Value = value
+		b.memStack[last] = Member{}
+		b.memStack = b.memStack[:last]
+		return
+	}
+	if frame.keys == nil && last-frame.start >= domKeyIndexThreshold {
+		frame.keys = make(map[string]int, 2*domKeyIndexThreshold)
+		for i := frame.start; i < last; i++ {
+			frame.keys[b.memStack[i].Key] = i
+		}
+	}
+	if frame.keys != nil {
+		frame.keys[key] = last
+	}
+}
+
+func (b *DomBuilder) enter(p *Parser, kind valueKind_t) {
+	if len(b.frames) >= b.MaxDepth {
+		b.fail(p, DomLimitError{"MaxDepth", b.MaxDepth})
+		return
+	}
+	frame := domFrame{value: b.newValue(kind), start: len(b.itemStack)}
+	if kind == VALUE_OBJECT {
+		frame.start = len(b.memStack)
+	}
+	if len(b.frames) != 0 {
+		parent := &b.frames[len(b.frames)-1]
+		if parent.value.Kind == VALUE_OBJECT {
+			frame.key, frame.index = b.memStack[len(b.memStack)-1].Key, -1
+		} else {
+			frame.index = len(b.itemStack) - parent.start
+		}
+	}
+	b.frames = append(b.frames, frame)
+}
+
+func (b *DomBuilder) leave(p *Parser) {
+	last := len(b.frames) - 1
+	frame := b.frames[last]
+	b.frames = b.frames[:last]
+	if frame.value.Kind == VALUE_ARRAY {
+		frame.value.Items = b.allocItems(b.itemStack[frame.start:])
+		clear(b.itemStack[frame.start:])
+		b.itemStack = b.itemStack[:frame.start]
+	} else {
+		frame.value.Members = b.allocMembers(b.memStack[frame.start:])
+		clear(b.memStack[frame.start:])
+		b.memStack = b.memStack[:frame.start]
+	}
+	b.add(p, frame.value)
+}
+
+func domOnEvent(p *Parser, evt event_t) {
+	b := p.UserData.(*DomBuilder)
+	switch evt {
+	case EVT_NULL:
+		b.add(p, b.newValue(VALUE_NULL))
+	case EVT_TRUE, EVT_FALSE:
+		value := b.newValue(VALUE_BOOL)
+		value.Bool = evt == EVT_TRUE
+		b.add(p, value)
+	case EVT_ARRAY:
+		b.enter(p, VALUE_ARRAY)
+	case EVT_DICT:
+		b.enter(p, VALUE_OBJECT)
+	case EVT_STRING:
+		if p.IsDictKey() {
+			b.token = WRITER_TOKEN_KEY
+			b.keyOffset = b.reader.offset()
+		} else {
+			b.token = WRITER_TOKEN_STRING
+		}
+	case EVT_NUMBER:
+		b.token = WRITER_TOKEN_NUMBER
+	case EVT_LEAVE:
+		switch b.token {
+		case WRITER_TOKEN_KEY:
+			b.memStack = append(b.memStack, Member{Key: b.takeText()})
+		case WRITER_TOKEN_STRING:
+			value := b.newValue(VALUE_STRING)
+			value.Text = b.takeText()
+			b.add(p, value)
+		case WRITER_TOKEN_NUMBER:
+			value := b.newValue(VALUE_NUMBER)
+			value.Text = b.takeText()
+			b.add(p, value)
+		default:
+			b.leave(p)
+		}
+		b.token = WRITER_TOKEN_NONE
+	}
+}
+
+func domOnData(p *Parser, endOfData bool) {
+	b := p.UserData.(*DomBuilder)
+	b.size += len(p.DataBuffer)
+	if b.size > b.MaxSize {
+		b.fail(p, DomLimitError{"MaxSize", b.MaxSize})
+		return
+	}
+	b.appendText(p.DataBuffer)
+}
+
+// Parse reads one document from byteReader into a tree
+func (b *DomBuilder) Parse(byteReader io.ByteReader) (*Value, error) {
+	// drop the old slabs rather than reuse them, earlier trees point into them
+	b.values, b.itemBuf, b.members, b.text, b.start = nil, nil, nil, nil, 0
+	clear(b.itemStack)
+	clear(b.memStack)
+	b.frames, b.itemStack, b.memStack = b.frames[:0], b.itemStack[:0], b.memStack[:0]
+	b.token, b.size, b.result, b.err = WRITER_TOKEN_NONE, 0, nil, nil
+	parser := documentParsers.Get()
+	defer documentParsers.Put(parser)
+	parser.UserData = b
+	b.reader = countingByteReader{reader: byteReader}
+	err := parser.Parse(&b.reader, domOnEvent, domOnData)
+	b.reader = countingByteReader{}
+	result := b.result
+	b.result = nil
+	if b.err != nil {
+		return nil, b.err
+	}
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ParseValue reads one document into a tree with the default limits,
+// keeping duplicate keys
+func ParseValue(byteReader io.ByteReader) (*Value, error) {
+	builder := NewDomBuilder()
+	return builder.Parse(byteReader)
+}
@@ -0,0 +1,215 @@
+package EvLJson
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const TEST_DOM_DOC = `{"b": 1, "a": [true, null, "xé"], "n": 12345678901234567890.50, "o": {"a~b/c": {}, "e": []}}`
+
+func TestDomRoundTrip(t *testing.T) {
+	value, err := ParseValue(strings.NewReader(TEST_DOM_DOC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := value.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"b":1,"a":[true,null,"xé"],"n":12345678901234567890.50,"o":{"a~b/c":{},"e":[]}}`
+	if string(encoded) != expected {
+		t.Logf("%s", encoded)
+		t.Fatalf("%s", expected)
+	}
+	encoded, err = Marshal(map[string]*Value{"v": value.Get("a")})
+	if err != nil || string(encoded) != `{"v":[true,null,"xé"]}` {
+		t.Fatalf("%s %v", encoded, err)
+	}
+}
+
+func TestDomPointer(t *testing.T) {
+	value, err := ParseValue(strings.NewReader(TEST_DOM_DOC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pointer, expected := range map[string]string{
+		"":           "",
+		"/a/2":       "xé",
+		"/n":         "12345678901234567890.50",
+		"/o/a~0b~1c": "",
+	} {
+		found, err := value.Pointer(pointer)
+		if err != nil || found.Text != expected {
+			t.Fatalf("%q: %+v %v", pointer, found, err)
+		}
+	}
+	for _, pointer := range []string{"/a/3", "/a/01", "/a/-", "/b/0", "/missing"} {
+		if _, err := value.Pointer(pointer); !errors.As(err, &PointerNotFoundError{}) {
+			t.Fatalf("%q: %v", pointer, err)
+		}
+	}
+	if _, err := value.Pointer("a"); !errors.As(err, &InvalidPointerError{}) {
+		t.Fatal(err)
+	}
+	if n, err := value.Get("b").Int64(); err != nil || n != 1 {
+		t.Fatal(n, err)
+	}
+}
+
+func TestDomMutation(t *testing.T) {
+	value, err := ParseValue(strings.NewReader(TEST_DOM_DOC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	number, err := NumberValue("2.5e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NumberValue("01"); err == nil {
+		t.Fatal("01 accepted as a number")
+	}
+	for pointer, v := range map[string]*Value{
+		"/b":     StringValue("<b>"),
+		"/a/-":   number,
+		"/a/0":   BoolValue(false),
+		"/o/e/-": ObjectValue(Member{"k", NullValue()}),
+		"/new":   ArrayValue(IntValue(-3), FloatValue(0.5)),
+	} {
+		if err := value.SetPointer(pointer, v); err != nil {
+			t.Fatalf("%q: %v", pointer, err)
+		}
+	}
+	if err := value.SetPointer("/a/9", NullValue()); err == nil {
+		t.Fatal("set past the end of an array")
+	}
+	if err := value.DeletePointer("/a/1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := value.DeletePointer("/n"); err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []int{-1, 3} {
+		if value.Get("a").Remove(index) {
+			t.Fatal("removed item", index)
+		}
+	}
+	if value.Remove(0) || !ArrayValue(NullValue()).Remove(0) {
+		t.Fatal("Remove")
+	}
+	// items appended to one array must not overwrite its slab neighbours
+	value.Get("o").Get("a~b/c").Set("z", BoolValue(true))
+	encoded, err := value.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"b":"<b>","a":[false,"xé",2.5e1],"o":{"a~b/c":{"z":true},"e":[{"k":null}]},"new":[-3,0.5]}`
+	if string(encoded) != expected {
+		t.Logf("%s", encoded)
+		t.Fatalf("%s", expected)
+	}
+}
+
+func TestDomDuplicateKeys(t *testing.T) {
+	// enough members that duplicates are found through the key index
+	var long strings.Builder
+	long.WriteString(`{`)
+	for i := 0; i < 40; i++ {
+		long.WriteString(`"k` + strconv.Itoa(i) + `": 0, `)
+	}
+	long.WriteString(`"k1": 1}`)
+
+	tests := []struct {
+		doc      string
+		policy   duplicateKeys_t
+		expected string
+		pointer  string
+	}{
+		{`{"a": 1, "b": 2, "a": 3}`, DUPLICATE_KEYS_KEEP, `{"a":1,"b":2,"a":3}`, ""},
+		{`{"a": 1, "b": 2, "a": 3}`, DUPLICATE_KEYS_LAST_WINS, `{"a":3,"b":2}`, ""},
+		{`[0, {"x": {"a": 1, "b": 2, "a": 3}}]`, DUPLICATE_KEYS_REJECT, "", "/1/x/a"},
+		{long.String(), DUPLICATE_KEYS_REJECT, "", "/k1"},
+	}
+	for _, test := range tests {
+		builder := NewDomBuilder()
+		builder.Duplicates = test.policy
+		value, err := builder.Parse(strings.NewReader(test.doc))
+		if test.pointer != "" {
+			var decodeErr DecodeError
+			if !errors.As(err, &decodeErr) || decodeErr.Pointer != test.pointer || !errors.As(err, &DuplicateKeyError{}) {
+				t.Fatalf("%s: %v", test.doc, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value.Get("a").Text != "3" {
+			t.Fatalf("%s: %+v", test.doc, value.Get("a"))
+		}
+		encoded, _ := value.MarshalJSON()
+		if string(encoded) != test.expected {
+			t.Fatalf("%s: %s", test.doc, encoded)
+		}
+	}
+}
+
+func TestDomLimits(t *testing.T) {
+	builder := NewDomBuilder()
+	builder.MaxDepth = 3
+	if _, err := builder.Parse(strings.NewReader(`[[[]]]`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := builder.Parse(strings.NewReader(`[[[[]]]]`)); !errors.As(err, &DomLimitError{}) {
+		t.Fatal(err)
+	}
+	builder = NewDomBuilder()
+	builder.MaxSize = 10
+	if _, err := builder.Parse(strings.NewReader(`{"abcde": "fghij"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := builder.Parse(strings.NewReader(`{"abcde": "fghijk"}`)); !errors.As(err, &DomLimitError{}) {
+		t.Fatal(err)
+	}
+	if _, err := builder.Parse(strings.NewReader(`{"a": [1`)); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+}
+
+func TestDomTreesOutliveBuilder(t *testing.T) {
+	builder := NewDomBuilder()
+	first, err := builder.Parse(strings.NewReader(`["first", {"k": "v"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("y", 3*DOM_SLAB_BYTES)
+	if _, err := builder.Parse(strings.NewReader(`["second", "` + long + `", {"k": "w"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := first.MarshalJSON()
+	if string(encoded) != `["first",{"k":"v"}]` {
+		t.Fatalf("%s", encoded)
+	}
+}
+
+func BenchmarkDomParse(b *testing.B) {
+	var doc strings.Builder
+	doc.WriteString(`[`)
+	for i := 0; i < 1000; i++ {
+		if i != 0 {
+			doc.WriteString(`,`)
+		}
+		doc.WriteString(`{"id": 12345, "name": "benchmark item", "tags": ["a", "b"], "ok": true}`)
+	}
+	doc.WriteString(`]`)
+	text := doc.String()
+	b.ReportAllocs()
+	b.SetBytes(int64(len(text)))
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseValue(strings.NewReader(text)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
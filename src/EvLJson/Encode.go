@@ -0,0 +1,480 @@
+package EvLJson
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// values nested deeper than this are assumed to be cycles
+const MAX_ENCODE_DEPTH = 1000
+
+type EncodeTypeError struct {
+	Type reflect.Type
+}
+
+func (err EncodeTypeError) Error() string {
+	return "Cannot encode go value of type " + err.Type.String()
+}
+
+type UnsupportedValueError struct {
+	Value string
+}
+
+func (err UnsupportedValueError) Error() string {
+	return "Cannot encode unsupported value: " + err.Value
+}
+
+// MarshalerError wraps an error returned by a MarshalJSON or MarshalText
+// method, or invalid json returned by MarshalJSON
+type MarshalerError struct {
+	Type reflect.Type
+	Err  error
+}
+
+func (err MarshalerError) Error() string {
+	return "Marshaler for " + err.Type.String() + " failed: " + err.Err.Error()
+}
+
+func (err MarshalerError) Unwrap() error {
+	return err.Err
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+type encoder struct {
+	w       *Writer
+	depth   int
+	scratch []byte
+}
+
+// appendFloat formats like encoding/json: plain decimals, except exponent
+// notation below 1e-6 and from 1e21 on
+func appendFloat(dst []byte, f float64, bits int) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, bits)
+	if n := len(dst); format == 'e' && n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+		// e-09 to e-9
+		dst[n-2] = dst[n-1]
+		dst = dst[:n-1]
+	}
+	return dst
+}
+
+// string writes s with invalid utf-8 replaced by U+FFFD, byte by byte as
+// encoding/json does
+func (e *encoder) string(s string) {
+	e.w.BeginString()
+	e.stringData(s)
+	e.w.EndString()
+}
+
+func (e *encoder) stringData(s string) {
+	if utf8.ValidString(s) {
+		e.w.StringData([]byte(s))
+		return
+	}
+	e.scratch = e.scratch[:0]
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		e.scratch = utf8.AppendRune(e.scratch, r)
+		i += size
+	}
+	e.w.StringData(e.scratch)
+}
+
+func (e *encoder) key(key string) {
+	e.w.BeginKey()
+	e.stringData(key)
+	e.w.EndString()
+}
+
+func (e *encoder) number(text []byte) {
+	e.w.BeginNumber()
+	e.w.NumberData(text)
+	e.w.EndNumber()
+}
+
+// marshaler returns the marshaler v or its address implements, if any;
+// an interface has none of its own, encode looks through it to the value it
+// holds, which may be a nil pointer
+func marshaler(v reflect.Value) (json.Marshaler, encoding.TextMarshaler) {
+	if v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, nil
+	}
+	if !v.Type().Implements(jsonMarshalerType) && !v.Type().Implements(textMarshalerType) && v.CanAddr() {
+		v = v.Addr()
+	}
+	if !v.CanInterface() {
+		return nil, nil
+	}
+	if m, ok := v.Interface().(json.Marshaler); ok {
+		return m, nil
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		return nil, m
+	}
+	return nil, nil
+}
+
+func (e *encoder) encodeMarshaler(v reflect.Value, m json.Marshaler, tm encoding.TextMarshaler) error {
+	if m != nil {
+		raw, err := m.MarshalJSON()
+		if err == nil {
+			// replayed through the Writer so it is indented like the rest
+			err = decodeValue(raw, WriterSink(e.w))
+		}
+		if err != nil {
+			return MarshalerError{v.Type(), err}
+		}
+		return nil
+	}
+	text, err := tm.MarshalText()
+	if err != nil {
+		return MarshalerError{v.Type(), err}
+	}
+	e.string(string(text))
+	return nil
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// isSeq matches iter.Seq[T] and any other func(yield func(T) bool)
+func isSeq(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return false
+	}
+	yield := t.In(0)
+	return yield.Kind() == reflect.Func && yield.NumIn() == 1 && yield.NumOut() == 1 && yield.Out(0).Kind() == reflect.Bool
+}
+
+func (e *encoder) encode(v reflect.Value, quoted bool) error {
+	if !v.IsValid() {
+		e.w.Null()
+		return e.w.Err()
+	}
+	if m, tm := marshaler(v); m != nil || tm != nil {
+		return e.encodeMarshaler(v, m, tm)
+	}
+	if quoted {
+		return e.encodeQuoted(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		e.w.Bool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.scratch = strconv.AppendInt(e.scratch[:0], v.Int(), 10)
+		e.number(e.scratch)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.scratch = strconv.AppendUint(e.scratch[:0], v.Uint(), 10)
+		e.number(e.scratch)
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return UnsupportedValueError{strconv.FormatFloat(f, 'g', -1, 64)}
+		}
+		e.scratch = appendFloat(e.scratch[:0], f, v.Type().Bits())
+		e.number(e.scratch)
+	case reflect.String:
+		if v.Type() == jsonNumberType {
+			number := v.String()
+			if number == "" {
+				number = "0"
+			}
+			if !isJsonNumber(number) {
+				return UnsupportedValueError{"invalid number literal " + strconv.Quote(number)}
+			}
+			e.number([]byte(number))
+			break
+		}
+		e.string(v.String())
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	case reflect.Map:
+		if v.IsNil() {
+			e.w.Null()
+			break
+		}
+		return e.nested(v, e.encodeMap)
+	case reflect.Slice:
+		if v.IsNil() {
+			e.w.Null()
+			break
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 && !reflect.PointerTo(v.Type().Elem()).Implements(jsonMarshalerType) &&
+			!reflect.PointerTo(v.Type().Elem()).Implements(textMarshalerType) {
+			e.string(base64.StdEncoding.EncodeToString(v.Bytes()))
+			break
+		}
+		return e.nested(v, e.encodeArray)
+	case reflect.Array:
+		return e.encodeArray(v)
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			e.w.Null()
+			break
+		}
+		return e.nested(v, func(v reflect.Value) error {
+			return e.encode(v.Elem(), false)
+		})
+	case reflect.Chan:
+		if v.IsNil() {
+			e.w.Null()
+			break
+		}
+		return e.encodeChan(v)
+	case reflect.Func:
+		if v.IsNil() {
+			e.w.Null()
+			break
+		}
+		if isSeq(v.Type()) {
+			return e.encodeSeq(v)
+		}
+		return EncodeTypeError{v.Type()}
+	default:
+		return EncodeTypeError{v.Type()}
+	}
+	return e.w.Err()
+}
+
+type numberCheckSink struct {
+	BaseSink
+}
+
+func (s numberCheckSink) Null() error {
+	return SinkTypeError{"null", s.GoType}
+}
+
+func (s numberCheckSink) Number(text []byte) error {
+	return nil
+}
+
+func isJsonNumber(s string) bool {
+	return decodeValue([]byte(s), numberCheckSink{BaseSink{"json.Number"}}) == nil
+}
+
+// nested guards the kinds that can form cycles
+func (e *encoder) nested(v reflect.Value, encode func(reflect.Value) error) error {
+	e.depth++
+	defer func() { e.depth-- }()
+	if e.depth > MAX_ENCODE_DEPTH {
+		return UnsupportedValueError{"nested too deeply, possibly a cycle, at " + v.Type().String()}
+	}
+	return encode(v)
+}
+
+// encodeQuoted writes the ",string" option: the json text of the value
+// inside a string
+func (e *encoder) encodeQuoted(v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			e.w.Null()
+			return e.w.Err()
+		}
+		v = v.Elem()
+	}
+	var text bytes.Buffer
+	inner := NewWriter(&text)
+	inner.EscapeHTML = e.w.EscapeHTML
+	if err := (&encoder{w: &inner}).encode(v, false); err != nil {
+		return err
+	}
+	if err := inner.Flush(); err != nil {
+		return err
+	}
+	if v.Kind() == reflect.String {
+		e.string(text.String())
+	} else {
+		e.w.BeginString()
+		e.w.StringData(text.Bytes())
+		e.w.EndString()
+	}
+	return e.w.Err()
+}
+
+func (e *encoder) encodeStruct(v reflect.Value) error {
+	e.w.BeginDict()
+	fields := cachedStructFields(v.Type())
+	for _, field := range fields.list {
+		fieldValue, ok := encodeFieldValue(v, field.index)
+		if !ok || (field.omitEmpty && isEmptyValue(fieldValue)) {
+			continue
+		}
+		e.key(field.name)
+		if err := e.encode(fieldValue, field.quoted); err != nil {
+			return err
+		}
+	}
+	e.w.EndDict()
+	return e.w.Err()
+}
+
+// encodeFieldValue walks the index path; fields behind nil embedded
+// pointers are left out
+func encodeFieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, fieldIndex := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return v, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(fieldIndex)
+	}
+	return v, true
+}
+
+type encodeMapMember struct {
+	key   string
+	value reflect.Value
+}
+
+func mapKeyString(key reflect.Value) (string, error) {
+	if key.Kind() == reflect.String {
+		return key.String(), nil
+	}
+	if m, ok := key.Interface().(encoding.TextMarshaler); ok {
+		if key.Kind() == reflect.Pointer && key.IsNil() {
+			return "", nil
+		}
+		text, err := m.MarshalText()
+		if err != nil {
+			return "", MarshalerError{key.Type(), err}
+		}
+		return string(text), nil
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10), nil
+	}
+	return "", EncodeTypeError{key.Type()}
+}
+
+// maps are written with their keys sorted, as encoding/json does
+func (e *encoder) encodeMap(v reflect.Value) error {
+	members := make([]encodeMapMember, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		key, err := mapKeyString(iter.Key())
+		if err != nil {
+			return err
+		}
+		members = append(members, encodeMapMember{key, iter.Value()})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].key < members[j].key
+	})
+	e.w.BeginDict()
+	for _, member := range members {
+		e.key(member.key)
+		if err := e.encode(member.value, false); err != nil {
+			return err
+		}
+	}
+	e.w.EndDict()
+	return e.w.Err()
+}
+
+func (e *encoder) encodeArray(v reflect.Value) error {
+	e.w.BeginArray()
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i), false); err != nil {
+			return err
+		}
+	}
+	e.w.EndArray()
+	return e.w.Err()
+}
+
+// encodeChan writes everything received until the channel is closed as an
+// array, holding one item at a time
+func (e *encoder) encodeChan(v reflect.Value) error {
+	if v.Type().ChanDir()&reflect.RecvDir == 0 {
+		return EncodeTypeError{v.Type()}
+	}
+	e.w.BeginArray()
+	for {
+		item, ok := v.Recv()
+		if !ok {
+			break
+		}
+		if err := e.encode(item, false); err != nil {
+			return err
+		}
+	}
+	e.w.EndArray()
+	return e.w.Err()
+}
+
+// encodeSeq writes every item the sequence yields as an array, holding one
+// item at a time; the sequence is stopped early on error
+func (e *encoder) encodeSeq(v reflect.Value) error {
+	var err error
+	yield := reflect.MakeFunc(v.Type().In(0), func(args []reflect.Value) []reflect.Value {
+		err = e.encode(args[0], false)
+		return []reflect.Value{reflect.ValueOf(err == nil)}
+	})
+	e.w.BeginArray()
+	v.Call([]reflect.Value{yield})
+	if err != nil {
+		return err
+	}
+	e.w.EndArray()
+	return e.w.Err()
+}
+
+// Encode writes v through w following encoding/json's rules for struct
+// tags, embedded structs, marshalers, map key order and number formatting,
+// then flushes w
+//
+// Channels and iter.Seq values are streamed as arrays until the channel
+// is closed or the sequence ends, so they are never held in memory whole
+func Encode(w *Writer, v interface{}) error {
+	e := encoder{w: w}
+	if err := e.encode(reflect.ValueOf(v), false); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// Marshal returns the minified encoding of v with html escaping, which is
+// what json.Marshal returns for the values both support
+func Marshal(v interface{}) ([]byte, error) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	w.EscapeHTML = true
+	if err := Encode(&w, v); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,246 @@
+package EvLJson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"iter"
+	"math"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type encodeMarshalerValue struct {
+	n int
+}
+
+func (m encodeMarshalerValue) MarshalJSON() ([]byte, error) {
+	return []byte(` { "n" : [ ` + strings.Repeat("1,", m.n) + `"<&>"] } `), nil
+}
+
+type encodeMarshalerPointer struct {
+	s string
+}
+
+func (m *encodeMarshalerPointer) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + m.s + `"`), nil
+}
+
+type encodeTextKey struct {
+	a, b int
+}
+
+func (k encodeTextKey) MarshalText() ([]byte, error) {
+	return []byte(string(rune('a'+k.a)) + string(rune('a'+k.b))), nil
+}
+
+type encodeEmbedded struct {
+	Inner  string `json:"inner"`
+	Shadow int
+}
+
+type EncodeEmbeddedPointer struct {
+	Deep []int `json:"deep,omitempty"`
+}
+
+type encodeSample struct {
+	encodeEmbedded
+	*EncodeEmbeddedPointer
+	Shadow    string                  `json:"Shadow"`
+	Name      string                  `json:"name"`
+	Omitted   string                  `json:"omitted,omitempty"`
+	Zero      int                     `json:"zero"`
+	OmitZero  int                     `json:",omitempty"`
+	Quoted    int                     `json:"quoted,string"`
+	QuotedStr string                  `json:"quoted_str,string"`
+	QuotedPtr *float64                `json:"quoted_ptr,string"`
+	Floats    []float64               `json:"floats"`
+	Float32   float32                 `json:"float32"`
+	Bytes     []byte                  `json:"bytes"`
+	NilSlice  []string                `json:"nil_slice"`
+	Empty     []string                `json:"empty"`
+	Array     [3]uint8                `json:"array"`
+	Map       map[string]interface{}  `json:"map"`
+	IntMap    map[int64]string        `json:"int_map"`
+	TextMap   map[encodeTextKey]bool  `json:"text_map"`
+	Any       interface{}             `json:"any"`
+	Ptr       *encodeSample           `json:"ptr"`
+	Value     encodeMarshalerValue    `json:"value"`
+	Pointer   encodeMarshalerPointer  `json:"pointer"`
+	PtrMarsh  *encodeMarshalerPointer `json:"ptr_marsh"`
+	When      time.Time               `json:"when"`
+	Addr      netip.Addr              `json:"addr"`
+	Number    json.Number             `json:"number"`
+	Html      string                  `json:"html"`
+	Invalid   string                  `json:"invalid"`
+	Skipped   string                  `json:"-"`
+	Dash      string                  `json:"-,"`
+	private   int
+}
+
+func encodeSampleValue() encodeSample {
+	half := 0.5
+	return encodeSample{
+		encodeEmbedded:        encodeEmbedded{"in", 3},
+		EncodeEmbeddedPointer: &EncodeEmbeddedPointer{[]int{1}},
+		Shadow:                "outer",
+		Name:                  "name",
+		Quoted:                -12,
+		QuotedStr:             `say "hi"`,
+		QuotedPtr:             &half,
+		Floats:                []float64{0, -0.0, 1, 1.5, 1e20, 1e21, 1e-6, 1e-7, 123456789.125, -3.0e-300, math.MaxFloat64, math.SmallestNonzeroFloat64},
+		Float32:               3.14,
+		Bytes:                 []byte("\x00\x01binary"),
+		Empty:                 []string{},
+		Array:                 [3]uint8{1, 2, 3},
+		Map:                   map[string]interface{}{"b": 1, "a": []interface{}{nil, true}, "c": map[string]interface{}{}},
+		IntMap:                map[int64]string{10: "ten", -1: "minus", 2: "two"},
+		TextMap:               map[encodeTextKey]bool{{1, 0}: true, {0, 1}: false},
+		Any:                   &encodeSample{Name: "nested"},
+		Value:                 encodeMarshalerValue{2},
+		Pointer:               encodeMarshalerPointer{"by pointer"},
+		PtrMarsh:              &encodeMarshalerPointer{"ptr"},
+		When:                  time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC),
+		Addr:                  netip.MustParseAddr("::1"),
+		Number:                "1.50",
+		Html:                  "<script>a && b</script>   é 😀 \t\"\\",
+		Invalid:               "bad \xff\xfe utf-8 \xe2\x80",
+		Skipped:               "skipped",
+		Dash:                  "dash",
+	}
+}
+
+func TestMarshalMatchesEncodingJson(t *testing.T) {
+	sample := encodeSampleValue()
+	values := []interface{}{
+		sample,
+		&sample,
+		[]interface{}{nil, 1, "s", true, []int(nil), map[string]int(nil)},
+		map[string]encodeSample{"x": {}},
+		[]encodeMarshalerPointer{{"addressable"}},
+		struct{ A, B int }{1, 2},
+		// marshalers held by interfaces, a nil pointer being null
+		struct{ I interface{} }{I: (*encodeMarshalerPointer)(nil)},
+		[]interface{}{(*encodeMarshalerPointer)(nil), &encodeMarshalerPointer{"p"}, encodeMarshalerValue{1}},
+	}
+	for _, value := range values {
+		encoded, err := Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(encoded, expected) {
+			t.Logf(LOG_STMT_FMT, string(encoded))
+			t.Logf(LOG_STMT_FMT, string(expected))
+			t.FailNow()
+		}
+	}
+}
+
+func TestEncodeIndented(t *testing.T) {
+	sample := encodeSampleValue()
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	w.Indent = "\t"
+	w.EscapeHTML = true
+	if err := Encode(&w, sample); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := json.MarshalIndent(sample, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(expected) {
+		t.Logf(LOG_STMT_FMT, out.String())
+		t.Logf(LOG_STMT_FMT, string(expected))
+		t.FailNow()
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	sample := encodeSampleValue()
+	encoded, err := Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded, expected map[string]interface{}
+	if err := Decode(bytes.NewReader(encoded), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(encoded, &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !genericEqual(decoded, expected) {
+		t.FailNow()
+	}
+}
+
+func TestEncodeStreams(t *testing.T) {
+	items := make(chan encodeEmbedded)
+	go func() {
+		for i := 0; i < 3; i++ {
+			items <- encodeEmbedded{strings.Repeat("x", i), i}
+		}
+		close(items)
+	}()
+	var seq iter.Seq[int] = slices.Values([]int{1, 2, 3})
+	value := map[string]interface{}{"chan": items, "seq": seq, "empty": slices.Values([]string(nil))}
+	encoded, err := Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"chan":[{"inner":"","Shadow":0},{"inner":"x","Shadow":1},{"inner":"xx","Shadow":2}],"empty":[],"seq":[1,2,3]}`
+	if string(encoded) != expected {
+		t.Fatal(string(encoded))
+	}
+}
+
+func TestEncodeSeqStopsOnError(t *testing.T) {
+	yielded := 0
+	seq := func(yield func(float64) bool) {
+		for _, f := range []float64{1, math.NaN(), 3} {
+			yielded++
+			if !yield(f) {
+				return
+			}
+		}
+	}
+	var unsupported UnsupportedValueError
+	if _, err := Marshal(seq); !errors.As(err, &unsupported) || yielded != 2 {
+		t.Fatal(err, yielded)
+	}
+}
+
+type encodeBadMarshaler struct{}
+
+func (encodeBadMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a":`), nil
+}
+
+func TestEncodeErrors(t *testing.T) {
+	type cycle struct {
+		Next *cycle
+	}
+	loop := &cycle{}
+	loop.Next = loop
+	values := []interface{}{
+		math.Inf(1),
+		json.Number("1x"),
+		func() {},
+		complex(1, 2),
+		map[float64]int{1: 1},
+		encodeBadMarshaler{},
+		loop,
+	}
+	for _, value := range values {
+		if _, err := Marshal(value); err == nil {
+			t.Fatalf("%#v", value)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package EvLJson
+
+import (
+	"io"
+)
+
+// options the document level helpers (Format, Canonicalize, ...) parse with
+const DOCUMENT_PARSER_OPTIONS = OPT_ALLOW_EXTRA_WHITESPACE | OPT_PARSE_UNTIL_EOF | OPT_DECODE_UNICODE_ESCAPES
+
+// DOCUMENT_DATA_BUFFER_SIZE is the DataBuffer the document level helpers
+// that keep their parser start it with
+const DOCUMENT_DATA_BUFFER_SIZE = POOL_DATA_BUFFER_SIZE
+
+// documentParsers are the parsers of the document level helpers, so values
+// reach OnData in large chunks without allocating a buffer per document
+var documentParsers = Pool{Options: DOCUMENT_PARSER_OPTIONS}
+
+func formatOnEvent(p *Parser, evt event_t) {
+	w := p.UserData.(*Writer)
+	switch evt {
+	case EVT_NULL:
+		w.Null()
+	case EVT_TRUE:
+		w.Bool(true)
+	case EVT_FALSE:
+		w.Bool(false)
+	case EVT_ARRAY:
+		w.BeginArray()
+	case EVT_DICT:
+		w.BeginDict()
+	case EVT_LEAVE:
+		w.Leave()
+	case EVT_STRING:
+		if p.IsDictKey() {
+			w.BeginKey()
+		} else {
+			w.BeginString()
+		}
+	case EVT_NUMBER:
+		w.BeginNumber()
+	}
+	if w.err != nil {
+		p.ParseStop()
+	}
+}
+
+func formatOnData(p *Parser, endOfData bool) {
+	w := p.UserData.(*Writer)
+	if p.DataIsJsonNum {
+		w.NumberData(p.DataBuffer)
+	} else {
+		w.StringData(p.DataBuffer)
+	}
+	if w.err != nil {
+		p.ParseStop()
+	}
+}
+
+// Format re-serialises the json document read from byteReader through w,
+// which decides indentation, colour and so on; memory use is bounded by the
+// document depth and the size of dataBuffer, never the document size
+//
+// \uXXXX escapes are decoded, so strings are written back out as utf-8
+// with only the escapes json requires
+func Format(byteReader io.ByteReader, w *Writer, dataBuffer []byte) error {
+	parser := NewParser(dataBuffer, nil, DOCUMENT_PARSER_OPTIONS)
+	parser.UserData = w
+	if err := parser.Parse(byteReader, formatOnEvent, formatOnData); err != nil {
+		if err == io.EOF {
+			// the document was cut short
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,136 @@
+package EvLJson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func formatString(jsonString string, w *Writer, out *bytes.Buffer) (string, error) {
+	err := Format(bytes.NewReader([]byte(jsonString)), w, nil)
+	return out.String(), err
+}
+
+func TestFormatMinify(t *testing.T) {
+	testCases := [][2]string{
+		{" [ 1 , 2.5e+3 , -0 ] ", `[1,2.5e+3,-0]`},
+		{"{ \"a\" : [ ] , \"b\" : { } , \"c\" : null }", `{"a":[],"b":{},"c":null}`},
+		{`["é\t\"\\\/\u0001"]`, "[\"é\\t\\\"\\\\/\\u0001\"]"},
+		{`[true,false,{"":""}]`, `[true,false,{"":""}]`},
+	}
+	for _, testCase := range testCases {
+		t.Logf(LOG_STMT_FMT, testCase[0])
+		var out bytes.Buffer
+		w := NewWriter(&out)
+		formatted, err := formatString(testCase[0], &w, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if formatted != testCase[1] {
+			t.Fatalf("%q != %q", formatted, testCase[1])
+		}
+	}
+}
+
+func TestFormatIndent(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	w.Indent = "  "
+	formatted, err := formatString(`{"a":[1,[]],"b":{"c":"d"},"e":{}}`, &w, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		`{`,
+		`  "a": [`,
+		`    1,`,
+		`    []`,
+		`  ],`,
+		`  "b": {`,
+		`    "c": "d"`,
+		`  },`,
+		`  "e": {}`,
+		`}`,
+	}, "\n")
+	if formatted != expected {
+		t.Fatalf("%s\n!=\n%s", formatted, expected)
+	}
+}
+
+func TestFormatArrayWidth(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	w.Indent = "\t"
+	w.ArrayWidth = 20
+	formatted, err := formatString(`{"a":[1,2,"x"],"b":[1,[2]],"c":[123456789,123456789]}`, &w, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		`{`,
+		"\t\"a\": [1, 2, \"x\"],",
+		"\t\"b\": [",
+		"\t\t1,",
+		"\t\t[2]",
+		"\t],",
+		"\t\"c\": [",
+		"\t\t123456789,",
+		"\t\t123456789",
+		"\t]",
+		`}`,
+	}, "\n")
+	if formatted != expected {
+		t.Fatalf("%s\n!=\n%s", formatted, expected)
+	}
+}
+
+func TestFormatColor(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	w.Color = true
+	formatted, err := formatString(`{"a":[1,null]}`, &w, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{" + COLOR_KEY + `"a"` + COLOR_RESET + ":[" + COLOR_NUMBER + "1" + COLOR_RESET + "," + COLOR_NULL + "null" + COLOR_RESET + "]}"
+	if formatted != expected {
+		t.Fatalf("%q != %q", formatted, expected)
+	}
+}
+
+func TestFormatSmallDataBuffer(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	jsonString := `{"a long key":"a long value é\n","n":-12345.6789e-10}`
+	err := Format(bytes.NewReader([]byte(jsonString)), &w, make([]byte, 0, MIN_UTF8_DATA_BUFFER_SIZE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\"a long key\":\"a long value é\\n\",\"n\":-12345.6789e-10}"
+	if out.String() != expected {
+		t.Fatalf("%q != %q", out.String(), expected)
+	}
+}
+
+func TestFormatBadJson(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	if _, err := formatString(`{"a":[1,]}`, &w, &out); err == nil {
+		t.FailNow()
+	}
+}
+
+func TestDocumentParsers(t *testing.T) {
+	value := strings.Repeat("x", DOCUMENT_DATA_BUFFER_SIZE/2)
+	for i := 0; i < 2; i++ {
+		parser := documentParsers.Get()
+		calls := 0
+		err := parser.Parse(bytes.NewReader([]byte(`["`+value+`"]`)), nil, func(p *Parser, endOfData bool) {
+			calls++
+		})
+		if err != nil || calls != 1 {
+			t.Fatal(err, calls)
+		}
+		documentParsers.Put(parser)
+	}
+}
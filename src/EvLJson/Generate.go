@@ -0,0 +1,685 @@
+package EvLJson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenerateOptions controls the code GenerateDecoders emits
+type GenerateOptions struct {
+	Package       string // package of the generated file, taken from the sources when empty
+	RuntimeImport string // import path of this package, empty when generating into it
+	Command       string // how the file was generated, for the header comment
+}
+
+type UnsupportedTypeError struct {
+	Type   string
+	Reason string
+}
+
+func (err UnsupportedTypeError) Error() string {
+	return "Cannot generate a decoder for " + err.Type + ": " + err.Reason
+}
+
+const ( // unmarshaler kinds
+	UNMARSHALER_NONE = iota
+	UNMARSHALER_JSON
+	UNMARSHALER_TEXT
+)
+
+var builtinSinks = map[string]string{
+	"string":  "StringSink",
+	"bool":    "BoolSink",
+	"int":     "IntSink",
+	"int8":    "IntSink",
+	"int16":   "IntSink",
+	"int32":   "IntSink",
+	"int64":   "IntSink",
+	"rune":    "IntSink",
+	"uint":    "UintSink",
+	"uint8":   "UintSink",
+	"uint16":  "UintSink",
+	"uint32":  "UintSink",
+	"uint64":  "UintSink",
+	"byte":    "UintSink",
+	"uintptr": "UintSink",
+	"float32": "FloatSink",
+	"float64": "FloatSink",
+	"any":     "AnySink",
+}
+
+// generatedField is a json member of a struct, possibly promoted from an
+// embedded struct
+type generatedField struct {
+	name   string
+	target string // go expression for a pointer to the field
+	typ    ast.Expr
+	depth  int
+	tagged bool
+	quoted bool // tagged ",string"
+}
+
+// embedAccessor allocates a nil embedded struct pointer on first use
+type embedAccessor struct {
+	method string
+	base   string
+	field  string
+	typ    string
+}
+
+type decoderGenerator struct {
+	runtime      string // qualifier for this package, "" or "EvLJson."
+	typeSpecs    map[string]*ast.TypeSpec
+	imports      map[string]string // package name to import path over all sources
+	unmarshalers map[string]int
+	queued       map[string]bool
+	queue        []string
+	usedImports  map[string]bool
+	body         bytes.Buffer
+	embeds       []embedAccessor
+}
+
+func exportName(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func isExported(name string) bool {
+	return name != "" && unicode.IsUpper([]rune(name)[0])
+}
+
+// the constructor and decode function take the exportedness of the type
+func (g *decoderGenerator) funcName(prefix, typeName, suffix string) string {
+	if isExported(typeName) {
+		return exportName(prefix) + exportName(typeName) + suffix
+	}
+	return prefix + exportName(typeName) + suffix
+}
+
+func sinkTypeName(typeName string) string {
+	return strings.ToLower(typeName[:1]) + typeName[1:] + "Sink"
+}
+
+func (g *decoderGenerator) enqueue(typeName string) {
+	if !g.queued[typeName] {
+		g.queued[typeName] = true
+		g.queue = append(g.queue, typeName)
+	}
+}
+
+// structType is the struct a type declared in the sources is, directly or
+// by being defined from another such type, unless it unmarshals itself
+func (g *decoderGenerator) structType(typeName string) *ast.StructType {
+	if g.unmarshalers[typeName] != UNMARSHALER_NONE {
+		return nil
+	}
+	for range len(g.typeSpecs) {
+		spec, exists := g.typeSpecs[typeName]
+		if !exists {
+			return nil
+		}
+		switch underlying := spec.Type.(type) {
+		case *ast.StructType:
+			return underlying
+		case *ast.Ident:
+			// methods are not inherited, so the unmarshalers of the
+			// underlying type do not matter
+			typeName = underlying.Name
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// quotable reports whether a field type takes the ",string" option, the
+// way quotable does for the reflection based Decode: a bool, number or
+// string kind, directly or through an unnamed pointer
+func (g *decoderGenerator) quotable(expr ast.Expr) (bool, error) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	for range len(g.typeSpecs) + 1 {
+		switch typeExpr := expr.(type) {
+		case *ast.Ident:
+			if sink, builtin := builtinSinks[typeExpr.Name]; builtin {
+				return sink != "AnySink", nil
+			}
+			spec, exists := g.typeSpecs[typeExpr.Name]
+			if !exists {
+				return false, nil
+			}
+			expr = spec.Type
+		case *ast.SelectorExpr:
+			return false, UnsupportedTypeError{types.ExprString(typeExpr), "the \",string\" option needs the kind of the type, which is only known at run time"}
+		default:
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+// markImports records the packages a type expression written into the
+// generated code refers to
+func (g *decoderGenerator) markImports(expr ast.Expr) {
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if selector, ok := node.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok {
+				g.usedImports[ident.Name] = true
+			}
+		}
+		return true
+	})
+}
+
+// sinkExpr returns go code for a Sink storing into the value ptr points at
+func (g *decoderGenerator) sinkExpr(expr ast.Expr, ptr string, depth int) (string, error) {
+	typeString := types.ExprString(expr)
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if sink, exists := builtinSinks[expr.Name]; exists {
+			return g.runtime + sink + "(" + ptr + ")", nil
+		}
+		spec, exists := g.typeSpecs[expr.Name]
+		if !exists {
+			return "", UnsupportedTypeError{typeString, "not a json type or a type declared in the sources"}
+		}
+		switch g.unmarshalers[expr.Name] {
+		case UNMARSHALER_JSON:
+			return g.runtime + "UnmarshalerSink(" + ptr + ")", nil
+		case UNMARSHALER_TEXT:
+			return g.runtime + "TextUnmarshalerSink(" + ptr + ")", nil
+		}
+		if g.structType(expr.Name) != nil {
+			g.enqueue(expr.Name)
+			return g.funcName("new", expr.Name, "Sink") + "(" + ptr + ")", nil
+		}
+		for range len(g.typeSpecs) {
+			// a type defined from another declared type has its underlying
+			// type but none of its methods
+			underlying, isIdent := spec.Type.(*ast.Ident)
+			if !isIdent || g.typeSpecs[underlying.Name] == nil {
+				break
+			}
+			spec = g.typeSpecs[underlying.Name]
+		}
+		if underlying, isIdent := spec.Type.(*ast.Ident); isIdent {
+			// the builtin sinks are generic over ~T so they take the named type
+			if sink, exists := builtinSinks[underlying.Name]; exists {
+				return g.runtime + sink + "(" + ptr + ")", nil
+			}
+			return "", UnsupportedTypeError{typeString, "not a json type or a type declared in the sources"}
+		}
+		g.markImports(spec.Type)
+		return g.sinkExpr(spec.Type, "(*"+types.ExprString(spec.Type)+")("+ptr+")", depth)
+	case *ast.StarExpr:
+		return g.elemSinkExpr("PointerSink", ptr, expr.X, depth)
+	case *ast.ArrayType:
+		if expr.Len != nil {
+			return "", UnsupportedTypeError{typeString, "fixed size arrays are not supported"}
+		}
+		if elem, isIdent := expr.Elt.(*ast.Ident); isIdent && (elem.Name == "byte" || elem.Name == "uint8") {
+			return g.runtime + "BytesSink(" + ptr + ")", nil
+		}
+		return g.elemSinkExpr("SliceSink", ptr, expr.Elt, depth)
+	case *ast.MapType:
+		if key, isIdent := expr.Key.(*ast.Ident); !isIdent || key.Name != "string" {
+			return "", UnsupportedTypeError{typeString, "map keys must be strings"}
+		}
+		return g.elemSinkExpr("MapSink", ptr, expr.Value, depth)
+	case *ast.InterfaceType:
+		if len(expr.Methods.List) != 0 {
+			return "", UnsupportedTypeError{typeString, "only the empty interface is supported"}
+		}
+		return g.runtime + "AnySink(" + ptr + ")", nil
+	case *ast.SelectorExpr:
+		// a type from another package, which is only known at run time
+		return g.runtime + "AutoSink(" + ptr + ")", nil
+	}
+	return "", UnsupportedTypeError{typeString, "unsupported kind of type"}
+}
+
+func (g *decoderGenerator) elemSinkExpr(sink string, ptr string, elem ast.Expr, depth int) (string, error) {
+	param := "p" + strconv.Itoa(depth)
+	inner, err := g.sinkExpr(elem, param, depth+1)
+	if err != nil {
+		return "", err
+	}
+	g.markImports(elem)
+	return fmt.Sprintf("%s%s(%s, func(%s *%s) %sSink {\n\treturn %s\n})",
+		g.runtime, sink, ptr, param, types.ExprString(elem), g.runtime, inner), nil
+}
+
+func jsonTag(field *ast.Field) (name string, options string, skip bool) {
+	if field.Tag == nil {
+		return "", "", false
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return "", "", false
+	}
+	value, exists := reflect.StructTag(tag).Lookup("json")
+	if !exists {
+		return "", "", false
+	}
+	if value == "-" {
+		return "", "", true
+	}
+	name, options, _ = strings.Cut(value, ",")
+	return name, options, false
+}
+
+// collectFields lists the json members of a struct the way encoding/json
+// sees them, following embedded structs; base is a go expression for the
+// struct value
+func (g *decoderGenerator) collectFields(structType *ast.StructType, base string, depth int, visiting map[string]bool) []generatedField {
+	var fields []generatedField
+	for _, field := range structType.Fields.List {
+		name, options, skip := jsonTag(field)
+		if skip {
+			continue
+		}
+		quoted := strings.Contains(","+options+",", ",string,")
+		if len(field.Names) == 0 {
+			typeExpr, isPointer := field.Type, false
+			if star, ok := typeExpr.(*ast.StarExpr); ok {
+				typeExpr, isPointer = star.X, true
+			}
+			var embedded *ast.StructType
+			typeName := types.ExprString(typeExpr)
+			if selector, ok := typeExpr.(*ast.SelectorExpr); ok {
+				typeName = selector.Sel.Name
+			} else {
+				embedded = g.structType(typeName)
+			}
+			if name == "" && embedded != nil && !visiting[typeName] {
+				embedBase := base + "." + typeName
+				if isPointer {
+					method := "embed" + strconv.Itoa(len(g.embeds))
+					g.embeds = append(g.embeds, embedAccessor{method, base, typeName, typeName})
+					embedBase = "s." + method + "()"
+				}
+				visiting[typeName] = true
+				fields = append(fields, g.collectFields(embedded, embedBase, depth+1, visiting)...)
+				delete(visiting, typeName)
+				continue
+			}
+			if !isExported(typeName) {
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = typeName
+			}
+			fields = append(fields, generatedField{name, "&" + base + "." + typeName, field.Type, depth, tagged, quoted})
+			continue
+		}
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			fieldName := name
+			if fieldName == "" {
+				fieldName = ident.Name
+			}
+			fields = append(fields, generatedField{fieldName, "&" + base + "." + ident.Name, field.Type, depth, name != "", quoted})
+		}
+	}
+	return fields
+}
+
+// dominantFields applies encoding/json's rules for members of the same
+// name: the shallowest wins, then a tagged one, and ties are dropped
+func dominantFields(fields []generatedField) []generatedField {
+	byName := map[string][]generatedField{}
+	var order []string
+	for _, field := range fields {
+		if _, exists := byName[field.name]; !exists {
+			order = append(order, field.name)
+		}
+		byName[field.name] = append(byName[field.name], field)
+	}
+	var result []generatedField
+	for _, name := range order {
+		candidates := byName[name]
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].depth != candidates[j].depth {
+				return candidates[i].depth < candidates[j].depth
+			}
+			return candidates[i].tagged && !candidates[j].tagged
+		})
+		if len(candidates) > 1 && candidates[0].depth == candidates[1].depth && candidates[0].tagged == candidates[1].tagged {
+			continue
+		}
+		result = append(result, candidates[0])
+	}
+	return result
+}
+
+func (g *decoderGenerator) writeStruct(typeName string, structType *ast.StructType) error {
+	g.embeds = g.embeds[:0]
+	fields := dominantFields(g.collectFields(structType, "s.v", 0, map[string]bool{typeName: true}))
+	sinkType := sinkTypeName(typeName)
+	b := &g.body
+
+	fmt.Fprintf(b, "type %s struct {\n\t%sBaseSink\n\tv *%s\n}\n\n", sinkType, g.runtime, typeName)
+	fmt.Fprintf(b, "func %s(v *%s) %sSink {\n\treturn &%s{%sBaseSink{GoType: %q}, v}\n}\n\n",
+		g.funcName("new", typeName, "Sink"), typeName, g.runtime, sinkType, g.runtime, typeName)
+	for _, embed := range g.embeds {
+		fmt.Fprintf(b, "func (s *%s) %s() *%s {\n\tif %s.%s == nil {\n\t\t%s.%s = new(%s)\n\t}\n\treturn %s.%s\n}\n\n",
+			sinkType, embed.method, embed.typ, embed.base, embed.field, embed.base, embed.field, embed.typ, embed.base, embed.field)
+	}
+	fmt.Fprintf(b, "func (s *%s) BeginDict() error {\n\treturn nil\n}\n\n", sinkType)
+
+	fmt.Fprintf(b, "func (s *%s) Member(key []byte) (%sSink, error) {\n", sinkType, g.runtime)
+	if len(fields) != 0 {
+		b.WriteString("switch string(key) {\n")
+		for _, field := range fields {
+			sink, err := g.sinkExpr(field.typ, field.target, 0)
+			quoted := false
+			if err == nil && field.quoted {
+				quoted, err = g.quotable(field.typ)
+			}
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", typeName, field.name, err)
+			}
+			if quoted {
+				sink = g.runtime + "QuotedSink(" + sink + ")"
+			}
+			fmt.Fprintf(b, "case %q:\n\treturn %s, nil\n", field.name, sink)
+		}
+		b.WriteString("}\n")
+		// encoding/json falls back to the first case insensitive match
+		fmt.Fprintf(b, "switch %sFoldKey(key) {\n", g.runtime)
+		folded := map[string]bool{}
+		for _, field := range fields {
+			lower := strings.ToLower(field.name)
+			if !folded[lower] {
+				folded[lower] = true
+				fmt.Fprintf(b, "case %q:\n\treturn s.Member([]byte(%q))\n", lower, field.name)
+			}
+		}
+		b.WriteString("}\n")
+	}
+	fmt.Fprintf(b, "return %sSkipSink(), nil\n}\n\n", g.runtime)
+	return nil
+}
+
+// GenerateDecoders parses the go source files and returns a go file with a
+// Sink for each named struct type and every struct type they reach, plus a
+// Decode function for each named type; the generated code uses no
+// reflection
+//
+// Types from other packages are decoded through AutoSink, which handles
+// json.Unmarshaler, encoding.TextUnmarshaler and builtin types
+func GenerateDecoders(filenames []string, typeNames []string, opts GenerateOptions) ([]byte, error) {
+	g := decoderGenerator{
+		typeSpecs:    map[string]*ast.TypeSpec{},
+		imports:      map[string]string{},
+		unmarshalers: map[string]int{},
+		queued:       map[string]bool{},
+		usedImports:  map[string]bool{},
+	}
+	if opts.RuntimeImport != "" {
+		g.runtime = "EvLJson."
+	}
+	fileSet := token.NewFileSet()
+	packageName := opts.Package
+	for _, filename := range filenames {
+		file, err := parser.ParseFile(fileSet, filename, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		if packageName == "" {
+			packageName = file.Name.Name
+		}
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			name := path[strings.LastIndex(path, "/")+1:]
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			g.imports[name] = path
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if typeSpec, ok := spec.(*ast.TypeSpec); ok && typeSpec.TypeParams == nil {
+						g.typeSpecs[typeSpec.Name.Name] = typeSpec
+					}
+				}
+			case *ast.FuncDecl:
+				if decl.Recv == nil || len(decl.Recv.List) != 1 {
+					continue
+				}
+				receiver := decl.Recv.List[0].Type
+				if star, ok := receiver.(*ast.StarExpr); ok {
+					receiver = star.X
+				}
+				ident, ok := receiver.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				if decl.Name.Name == "UnmarshalJSON" {
+					g.unmarshalers[ident.Name] = UNMARSHALER_JSON
+				} else if decl.Name.Name == "UnmarshalText" && g.unmarshalers[ident.Name] == UNMARSHALER_NONE {
+					g.unmarshalers[ident.Name] = UNMARSHALER_TEXT
+				}
+			}
+		}
+	}
+	if len(typeNames) == 0 {
+		return nil, errors.New("No types to generate decoders for")
+	}
+	for _, typeName := range typeNames {
+		if g.structType(typeName) == nil {
+			return nil, UnsupportedTypeError{typeName, "not a struct type declared in the sources without its own UnmarshalJSON"}
+		}
+		g.enqueue(typeName)
+	}
+
+	for i := 0; i < len(g.queue); i++ {
+		if err := g.writeStruct(g.queue[i], g.structType(g.queue[i])); err != nil {
+			return nil, err
+		}
+	}
+	for _, typeName := range typeNames {
+		fmt.Fprintf(&g.body, "// %s decodes one %s document read from byteReader\n", g.funcName("decode", typeName, ""), typeName)
+		fmt.Fprintf(&g.body, "func %s(byteReader io.ByteReader, v *%s) error {\n\treturn %sDecodeSink(byteReader, %s(v))\n}\n\n",
+			g.funcName("decode", typeName, ""), typeName, g.runtime, g.funcName("new", typeName, "Sink"))
+	}
+
+	var out bytes.Buffer
+	command := opts.Command
+	if command == "" {
+		command = "evljson gen"
+	}
+	fmt.Fprintf(&out, "// Code generated by %s; DO NOT EDIT.\n\npackage %s\n\nimport (\n\t\"io\"\n", command, packageName)
+	if opts.RuntimeImport != "" {
+		fmt.Fprintf(&out, "\t%q\n", opts.RuntimeImport)
+	}
+	var used []string
+	for name := range g.usedImports {
+		path, exists := g.imports[name]
+		if !exists {
+			return nil, UnsupportedTypeError{name, "package is not imported by the sources"}
+		}
+		if path[strings.LastIndex(path, "/")+1:] != name {
+			path = name + " " + strconv.Quote(path)
+		} else {
+			path = strconv.Quote(path)
+		}
+		used = append(used, path)
+	}
+	sort.Strings(used)
+	for _, path := range used {
+		out.WriteString("\t" + path + "\n")
+	}
+	out.WriteString(")\n\n")
+	out.Write(g.body.Bytes())
+	return format.Source(out.Bytes())
+}
+
+var goInitialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true,
+	"GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "OS": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true,
+	"TTL": true, "UDP": true, "UI": true, "URI": true, "URL": true, "UTF8": true,
+	"UUID": true, "XML": true,
+}
+
+// goFieldName makes an exported go identifier out of a json key, splitting
+// on anything that is not a letter or digit and on lower to upper changes
+func goFieldName(key string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) != 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	for _, r := range key {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && len(word) != 0 && unicode.IsLower(word[len(word)-1]):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+	var name strings.Builder
+	for _, word := range words {
+		if upper := strings.ToUpper(word); goInitialisms[upper] {
+			name.WriteString(upper)
+		} else {
+			runes := []rune(word)
+			name.WriteString(string(unicode.ToUpper(runes[0])) + string(runes[1:]))
+		}
+	}
+	if name.Len() == 0 || !unicode.IsLetter([]rune(name.String())[0]) {
+		return "X" + name.String()
+	}
+	return name.String()
+}
+
+type pendingStruct struct {
+	name string
+	node *inferredNode
+}
+
+type structGenerator struct {
+	names   map[string]bool
+	pending []pendingStruct
+	useTime bool
+}
+
+func (g *structGenerator) uniqueName(name string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.names[unique] = true
+	return unique
+}
+
+// goType picks a go type for the values seen at a node; struct types are
+// queued under a name derived from name
+func (g *structGenerator) goType(n *inferredNode, name string) string {
+	if n == nil {
+		return "interface{}"
+	}
+	kinds := n.types &^ SCHEMA_TYPE_NULL
+	if kinds == SCHEMA_TYPE_INTEGER|SCHEMA_TYPE_NUMBER {
+		kinds = SCHEMA_TYPE_NUMBER
+	}
+	nullable := n.types&SCHEMA_TYPE_NULL != 0
+	var goType string
+	switch kinds {
+	case SCHEMA_TYPE_INTEGER:
+		goType = "int64"
+	case SCHEMA_TYPE_NUMBER:
+		goType = "float64"
+	case SCHEMA_TYPE_BOOLEAN:
+		goType = "bool"
+	case SCHEMA_TYPE_STRING:
+		goType = "string"
+		if n.formats&FORMAT_DATE_TIME != 0 {
+			goType = "time.Time"
+			g.useTime = true
+		}
+	case SCHEMA_TYPE_ARRAY:
+		return "[]" + g.goType(n.items, name+"Item")
+	case SCHEMA_TYPE_OBJECT:
+		if n.additional != nil {
+			return "map[string]" + g.goType(n.additional, name+"Value")
+		}
+		goType = g.uniqueName(name)
+		g.pending = append(g.pending, pendingStruct{goType, n})
+	default:
+		return "interface{}"
+	}
+	if nullable {
+		return "*" + goType
+	}
+	return goType
+}
+
+func (g *structGenerator) writeStruct(out *bytes.Buffer, name string, n *inferredNode) {
+	fmt.Fprintf(out, "type %s struct {\n", name)
+	fieldNames := map[string]bool{}
+	for _, key := range n.keys {
+		child := n.properties[key]
+		fieldName := goFieldName(key)
+		for i := 2; fieldNames[fieldName]; i++ {
+			fieldName = goFieldName(key) + strconv.Itoa(i)
+		}
+		fieldNames[fieldName] = true
+		tag := key
+		if child.count < n.dicts {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(out, "\t%s %s `json:%s`\n", fieldName, g.goType(child, name+fieldName), strconv.Quote(tag))
+	}
+	out.WriteString("}\n\n")
+}
+
+// GenerateStructs returns a go file declaring typeName, and the types it
+// needs, to hold documents shaped like those observed: members missing
+// from some dicts get omitempty, nullable values become pointers, dicts
+// treated as maps become maps and strings that were all RFC 3339 times
+// become time.Time
+func (s *InferredSchema) GenerateStructs(packageName, typeName string) ([]byte, error) {
+	g := structGenerator{names: map[string]bool{}}
+	var body bytes.Buffer
+	rootType := g.goType(s.root, typeName)
+	if rootType != typeName {
+		g.uniqueName(typeName)
+		fmt.Fprintf(&body, "type %s %s\n\n", typeName, rootType)
+	}
+	for i := 0; i < len(g.pending); i++ {
+		g.writeStruct(&body, g.pending[i].name, g.pending[i].node)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "package %s\n\n", packageName)
+	if g.useTime {
+		out.WriteString("import \"time\"\n\n")
+	}
+	out.Write(body.Bytes())
+	return format.Source(out.Bytes())
+}
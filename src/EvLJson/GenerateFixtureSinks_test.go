@@ -0,0 +1,220 @@
+// Code generated by evljson gen -type genFixtureDoc GenerateFixture_test.go; DO NOT EDIT.
+
+package EvLJson
+
+import (
+	"io"
+)
+
+type genFixtureDocSink struct {
+	BaseSink
+	v *genFixtureDoc
+}
+
+func newGenFixtureDocSink(v *genFixtureDoc) Sink {
+	return &genFixtureDocSink{BaseSink{GoType: "genFixtureDoc"}, v}
+}
+
+func (s *genFixtureDocSink) embed0() *GenFixtureExtra {
+	if s.v.GenFixtureExtra == nil {
+		s.v.GenFixtureExtra = new(GenFixtureExtra)
+	}
+	return s.v.GenFixtureExtra
+}
+
+func (s *genFixtureDocSink) BeginDict() error {
+	return nil
+}
+
+func (s *genFixtureDocSink) Member(key []byte) (Sink, error) {
+	switch string(key) {
+	case "id":
+		return IntSink(&s.v.genFixtureBase.ID), nil
+	case "note":
+		return StringSink(&s.v.genFixtureBase.Note), nil
+	case "extra":
+		return BoolSink(&s.embed0().Extra), nil
+	case "title":
+		return StringSink(&s.v.Title), nil
+	case "count":
+		return UintSink(&s.v.Count), nil
+	case "Ratio":
+		return FloatSink(&s.v.Ratio), nil
+	case "level":
+		return IntSink(&s.v.Level), nil
+	case "tags":
+		return SliceSink((*[]string)(&s.v.Tags), func(p0 *string) Sink {
+			return StringSink(p0)
+		}), nil
+	case "child":
+		return newGenFixtureChildSink(&s.v.Child), nil
+	case "optional":
+		return PointerSink(&s.v.Optional, func(p0 *genFixtureChild) Sink {
+			return newGenFixtureChildSink(p0)
+		}), nil
+	case "by_name":
+		return MapSink(&s.v.ByName, func(p0 *genFixtureChild) Sink {
+			return newGenFixtureChildSink(p0)
+		}), nil
+	case "matrix":
+		return SliceSink(&s.v.Matrix, func(p0 *[]int) Sink {
+			return SliceSink(p0, func(p1 *int) Sink {
+				return IntSink(p1)
+			})
+		}), nil
+	case "data":
+		return BytesSink(&s.v.Data), nil
+	case "any":
+		return AnySink(&s.v.Any), nil
+	case "stamp":
+		return UnmarshalerSink(&s.v.Stamp), nil
+	case "code":
+		return TextUnmarshalerSink(&s.v.Code), nil
+	case "when":
+		return AutoSink(&s.v.When), nil
+	case "alias":
+		return newGenFixtureAliasSink(&s.v.Alias), nil
+	case "quoted":
+		return QuotedSink(IntSink(&s.v.Quoted)), nil
+	case "quoted_p":
+		return QuotedSink(PointerSink(&s.v.QuotedP, func(p0 *float64) Sink {
+			return FloatSink(p0)
+		})), nil
+	case "quoted_l":
+		return QuotedSink(IntSink(&s.v.QuotedL)), nil
+	case "quoted_b":
+		return QuotedSink(BoolSink(&s.v.QuotedB)), nil
+	case "quoted_s":
+		return SliceSink(&s.v.QuotedS, func(p0 *string) Sink {
+			return StringSink(p0)
+		}), nil
+	}
+	switch FoldKey(key) {
+	case "id":
+		return s.Member([]byte("id"))
+	case "note":
+		return s.Member([]byte("note"))
+	case "extra":
+		return s.Member([]byte("extra"))
+	case "title":
+		return s.Member([]byte("title"))
+	case "count":
+		return s.Member([]byte("count"))
+	case "ratio":
+		return s.Member([]byte("Ratio"))
+	case "level":
+		return s.Member([]byte("level"))
+	case "tags":
+		return s.Member([]byte("tags"))
+	case "child":
+		return s.Member([]byte("child"))
+	case "optional":
+		return s.Member([]byte("optional"))
+	case "by_name":
+		return s.Member([]byte("by_name"))
+	case "matrix":
+		return s.Member([]byte("matrix"))
+	case "data":
+		return s.Member([]byte("data"))
+	case "any":
+		return s.Member([]byte("any"))
+	case "stamp":
+		return s.Member([]byte("stamp"))
+	case "code":
+		return s.Member([]byte("code"))
+	case "when":
+		return s.Member([]byte("when"))
+	case "alias":
+		return s.Member([]byte("alias"))
+	case "quoted":
+		return s.Member([]byte("quoted"))
+	case "quoted_p":
+		return s.Member([]byte("quoted_p"))
+	case "quoted_l":
+		return s.Member([]byte("quoted_l"))
+	case "quoted_b":
+		return s.Member([]byte("quoted_b"))
+	case "quoted_s":
+		return s.Member([]byte("quoted_s"))
+	}
+	return SkipSink(), nil
+}
+
+type genFixtureChildSink struct {
+	BaseSink
+	v *genFixtureChild
+}
+
+func newGenFixtureChildSink(v *genFixtureChild) Sink {
+	return &genFixtureChildSink{BaseSink{GoType: "genFixtureChild"}, v}
+}
+
+func (s *genFixtureChildSink) BeginDict() error {
+	return nil
+}
+
+func (s *genFixtureChildSink) Member(key []byte) (Sink, error) {
+	switch string(key) {
+	case "name":
+		return StringSink(&s.v.Name), nil
+	case "score":
+		return FloatSink(&s.v.Score), nil
+	case "kids":
+		return SliceSink(&s.v.Kids, func(p0 **genFixtureChild) Sink {
+			return PointerSink(p0, func(p1 *genFixtureChild) Sink {
+				return newGenFixtureChildSink(p1)
+			})
+		}), nil
+	}
+	switch FoldKey(key) {
+	case "name":
+		return s.Member([]byte("name"))
+	case "score":
+		return s.Member([]byte("score"))
+	case "kids":
+		return s.Member([]byte("kids"))
+	}
+	return SkipSink(), nil
+}
+
+type genFixtureAliasSink struct {
+	BaseSink
+	v *genFixtureAlias
+}
+
+func newGenFixtureAliasSink(v *genFixtureAlias) Sink {
+	return &genFixtureAliasSink{BaseSink{GoType: "genFixtureAlias"}, v}
+}
+
+func (s *genFixtureAliasSink) BeginDict() error {
+	return nil
+}
+
+func (s *genFixtureAliasSink) Member(key []byte) (Sink, error) {
+	switch string(key) {
+	case "name":
+		return StringSink(&s.v.Name), nil
+	case "score":
+		return FloatSink(&s.v.Score), nil
+	case "kids":
+		return SliceSink(&s.v.Kids, func(p0 **genFixtureChild) Sink {
+			return PointerSink(p0, func(p1 *genFixtureChild) Sink {
+				return newGenFixtureChildSink(p1)
+			})
+		}), nil
+	}
+	switch FoldKey(key) {
+	case "name":
+		return s.Member([]byte("name"))
+	case "score":
+		return s.Member([]byte("score"))
+	case "kids":
+		return s.Member([]byte("kids"))
+	}
+	return SkipSink(), nil
+}
+
+// decodeGenFixtureDoc decodes one genFixtureDoc document read from byteReader
+func decodeGenFixtureDoc(byteReader io.ByteReader, v *genFixtureDoc) error {
+	return DecodeSink(byteReader, newGenFixtureDocSink(v))
+}
@@ -0,0 +1,76 @@
+package EvLJson
+
+import (
+	"strings"
+	"time"
+)
+
+// types for Generate_test.go; the sinks for them in
+// GenerateFixtureSinks_test.go are generated from this file
+
+type genFixtureLevel int
+
+type genFixtureTags []string
+
+type genFixtureStamp struct {
+	raw string
+}
+
+func (s *genFixtureStamp) UnmarshalJSON(raw []byte) error {
+	s.raw = string(raw)
+	return nil
+}
+
+type genFixtureCode string
+
+func (c *genFixtureCode) UnmarshalText(text []byte) error {
+	*c = genFixtureCode(strings.ToUpper(string(text)))
+	return nil
+}
+
+type genFixtureBase struct {
+	ID   int64  `json:"id"`
+	Note string `json:"note,omitempty"`
+}
+
+type GenFixtureExtra struct {
+	Extra bool `json:"extra"`
+}
+
+type genFixtureChild struct {
+	Name  string             `json:"name"`
+	Score float64            `json:"score"`
+	Kids  []*genFixtureChild `json:"kids,omitempty"`
+}
+
+// genFixtureAlias has the fields of genFixtureChild but none of its methods
+type genFixtureAlias genFixtureChild
+
+type genFixtureLevelAlias genFixtureLevel
+
+type genFixtureDoc struct {
+	genFixtureBase
+	*GenFixtureExtra
+	Title    string `json:"title"`
+	Count    uint16 `json:"count,omitempty"`
+	Ratio    float32
+	Level    genFixtureLevel            `json:"level"`
+	Tags     genFixtureTags             `json:"tags"`
+	Child    genFixtureChild            `json:"child"`
+	Optional *genFixtureChild           `json:"optional"`
+	ByName   map[string]genFixtureChild `json:"by_name"`
+	Matrix   [][]int                    `json:"matrix"`
+	Data     []byte                     `json:"data"`
+	Any      interface{}                `json:"any"`
+	Stamp    genFixtureStamp            `json:"stamp"`
+	Code     genFixtureCode             `json:"code"`
+	When     time.Time                  `json:"when"`
+	Alias    genFixtureAlias            `json:"alias"`
+	Quoted   int                        `json:"quoted,string"`
+	QuotedP  *float64                   `json:"quoted_p,string"`
+	QuotedL  genFixtureLevelAlias       `json:"quoted_l,string"`
+	QuotedB  bool                       `json:"quoted_b,omitempty,string"`
+	QuotedS  []string                   `json:"quoted_s,string"`
+	Ignored  string                     `json:"-"`
+	hidden   int
+}
@@ -0,0 +1,154 @@
+package EvLJson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const TEST_GENERATE_COMMAND = "evljson gen -type genFixtureDoc GenerateFixture_test.go"
+
+const TEST_GENERATE_DOC = `{
+	"id": 7, "note": "n", "extra": true, "title": "Té", "count": 65535, "RATIO": 0.5,
+	"level": -3, "tags": ["a", "b"],
+	"child": {"name": "c", "score": 1.5, "kids": [{"name": "k", "score": 2}, null]},
+	"optional": {"name": "o", "score": 0},
+	"by_name": {"x": {"name": "x", "score": 1}, "y": {"name": "y", "score": 2}},
+	"matrix": [[1, 2], [], [3]],
+	"data": "aGVsbG8=",
+	"any": {"list": [1, "two", null, true], "nested": {}},
+	"stamp": {"b": [1, 2.50], "a": "A"},
+	"code": "abc",
+	"when": "2024-01-02T03:04:05Z",
+	"alias": {"name": "al", "score": 3, "kids": [{"name": "ak"}]},
+	"quoted": "-12", "quoted_p": "2.5", "quoted_l": "4", "quoted_b": "true", "quoted_s": ["not", "quoted"],
+	"unknown": {"deep": [1, {"x": null}]},
+	"Ignored": "no"
+}`
+
+func TestGeneratedSinksUpToDate(t *testing.T) {
+	generated, err := GenerateDecoders([]string{"GenerateFixture_test.go"}, []string{"genFixtureDoc"}, GenerateOptions{Command: TEST_GENERATE_COMMAND})
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing, err := os.ReadFile("GenerateFixtureSinks_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generated, existing) {
+		t.Logf(LOG_STMT_FMT, string(generated))
+		t.Fatal("GenerateFixtureSinks_test.go is stale, regenerate it with: " + TEST_GENERATE_COMMAND)
+	}
+}
+
+func TestGeneratedDecoder(t *testing.T) {
+	var decoded genFixtureDoc
+	if err := decodeGenFixtureDoc(strings.NewReader(TEST_GENERATE_DOC), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	var expected genFixtureDoc
+	if err := json.Unmarshal([]byte(TEST_GENERATE_DOC), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Stamp.raw != `{"b":[1,2.50],"a":"A"}` {
+		t.Fatal(decoded.Stamp.raw)
+	}
+	decoded.Stamp, expected.Stamp = genFixtureStamp{}, genFixtureStamp{}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Logf("%+v", decoded)
+		t.Fatalf("%+v", expected)
+	}
+}
+
+func TestGeneratedDecoderErrors(t *testing.T) {
+	tests := []struct {
+		doc     string
+		pointer string
+		offset  int64
+	}{
+		{`{"title": 1}`, "/title", 10},
+		{`{"count": 65536}`, "/count", 10},
+		{`{"child": {"kids": [{"score": "x"}]}}`, "/child/kids/0/score", 30},
+		{`{"by_name": {"a~b": []}}`, "/by_name/a~0b", 20},
+		{`{"matrix": [[1.5]]}`, "/matrix/0/0", 13},
+		{`{"quoted": 5}`, "/quoted", 11},
+		{`{"quoted": "x"}`, "/quoted", 11},
+	}
+	for _, test := range tests {
+		var decoded genFixtureDoc
+		err := decodeGenFixtureDoc(strings.NewReader(test.doc), &decoded)
+		decodeErr, ok := err.(DecodeError)
+		if !ok || decodeErr.Pointer != test.pointer || decodeErr.Offset != test.offset {
+			t.Logf(LOG_STMT_FMT, test.doc)
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	if _, err := GenerateDecoders([]string{"GenerateFixture_test.go"}, []string{"genFixtureLevel"}, GenerateOptions{}); err == nil {
+		t.Fatal("generated a decoder for a non-struct type")
+	}
+	// the kind of a type from another package is unknown to ",string"
+	source := filepath.Join(t.TempDir(), "quoted.go")
+	if err := os.WriteFile(source, []byte("package quoted\n\nimport \"time\"\n\ntype Quoted struct {\n\tD time.Duration `json:\"d,string\"`\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenerateDecoders([]string{source}, []string{"Quoted"}, GenerateOptions{}); !errors.As(err, new(UnsupportedTypeError)) {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateStructs(t *testing.T) {
+	schema := inferString(t, `{"id":1,"user_name":"a","createdAt":"2024-01-02T03:04:05Z","tags":["x"],"owner":{"url":"u"},"score":1.5,"extra":null}
+{"id":2,"user_name":"b","createdAt":"2024-01-02T03:04:05Z","tags":[],"owner":null,"score":2}
+`)
+	generated, err := schema.GenerateStructs("sample", "Event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "package sample\n\nimport \"time\"\n\n" +
+		"type Event struct {\n" +
+		"\tID        int64       `json:\"id\"`\n" +
+		"\tUserName  string      `json:\"user_name\"`\n" +
+		"\tCreatedAt time.Time   `json:\"createdAt\"`\n" +
+		"\tTags      []string    `json:\"tags\"`\n" +
+		"\tOwner     *EventOwner `json:\"owner\"`\n" +
+		"\tScore     float64     `json:\"score\"`\n" +
+		"\tExtra     interface{} `json:\"extra,omitempty\"`\n" +
+		"}\n\n" +
+		"type EventOwner struct {\n" +
+		"\tURL string `json:\"url\"`\n" +
+		"}\n"
+	if string(generated) != expected {
+		t.Logf(LOG_STMT_FMT, string(generated))
+		t.FailNow()
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "sample.go", generated, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateStructsMaps(t *testing.T) {
+	schema := NewInferredSchema()
+	schema.MaxProperties = 2
+	if err := schema.ObserveStream(strings.NewReader(`[{"a":1,"b":{"k1":true,"k2":false}},{"b":{"k3":true}}]`)); err != nil {
+		t.Fatal(err)
+	}
+	generated, err := schema.GenerateStructs("sample", "Rows")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(generated), "type Rows []RowsItem\n") ||
+		!strings.Contains(string(generated), "B map[string]bool `json:\"b\"`") {
+		t.Logf(LOG_STMT_FMT, string(generated))
+		t.FailNow()
+	}
+}
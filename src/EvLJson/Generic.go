@@ -0,0 +1,148 @@
+package EvLJson
+
+import (
+	"io"
+	"strconv"
+)
+
+// genericBuilder assembles parser events into plain go values: nil, bool,
+// float64, string, []interface{} and map[string]interface{}
+type genericBuilder struct {
+	frames []genericFrame
+	token  writerToken_t
+	text   []byte
+	result interface{}
+}
+
+type genericFrame struct {
+	array  []interface{}
+	dict   map[string]interface{}
+	key    string
+	isDict bool
+}
+
+func (g *genericBuilder) add(value interface{}) {
+	if len(g.frames) == 0 {
+		g.result = value
+		return
+	}
+	frame := &g.frames[len(g.frames)-1]
+	if frame.isDict {
+		frame.dict[frame.key] = value
+	} else {
+		frame.array = append(frame.array, value)
+	}
+}
+
+func (g *genericBuilder) onEvent(evt event_t, isKey bool) {
+	switch evt {
+	case EVT_NULL:
+		g.add(nil)
+	case EVT_TRUE:
+		g.add(true)
+	case EVT_FALSE:
+		g.add(false)
+	case EVT_ARRAY:
+		g.frames = append(g.frames, genericFrame{array: []interface{}{}})
+	case EVT_DICT:
+		g.frames = append(g.frames, genericFrame{dict: map[string]interface{}{}, isDict: true})
+	case EVT_STRING:
+		if isKey {
+			g.token = WRITER_TOKEN_KEY
+		} else {
+			g.token = WRITER_TOKEN_STRING
+		}
+	case EVT_NUMBER:
+		g.token = WRITER_TOKEN_NUMBER
+	case EVT_LEAVE:
+		switch g.token {
+		case WRITER_TOKEN_KEY:
+			g.frames[len(g.frames)-1].key = string(g.text)
+		case WRITER_TOKEN_STRING:
+			g.add(string(g.text))
+		case WRITER_TOKEN_NUMBER:
+			number, _ := strconv.ParseFloat(string(g.text), 64)
+			g.add(number)
+		default:
+			last := len(g.frames) - 1
+			frame := g.frames[last]
+			g.frames = g.frames[:last]
+			if frame.isDict {
+				g.add(frame.dict)
+			} else {
+				g.add(frame.array)
+			}
+		}
+		g.token = WRITER_TOKEN_NONE
+		g.text = g.text[:0]
+	}
+}
+
+func (g *genericBuilder) onData(data []byte) {
+	g.text = append(g.text, data...)
+}
+
+func genericOnEvent(p *Parser, evt event_t) {
+	p.UserData.(*genericBuilder).onEvent(evt, evt == EVT_STRING && p.IsDictKey())
+}
+
+func genericOnData(p *Parser, endOfData bool) {
+	p.UserData.(*genericBuilder).onData(p.DataBuffer)
+}
+
+// parseGeneric reads a whole document into plain go values
+func parseGeneric(byteReader io.ByteReader) (interface{}, error) {
+	builder := genericBuilder{}
+	parser := documentParsers.Get()
+	defer documentParsers.Put(parser)
+	parser.UserData = &builder
+	if err := parser.Parse(byteReader, genericOnEvent, genericOnData); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return builder.result, nil
+}
+
+// genericEqual compares values built by genericBuilder the way json schema
+// compares instances: numbers by value, dicts regardless of member order
+func genericEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		other, ok := b.(bool)
+		return ok && a == other
+	case float64:
+		other, ok := b.(float64)
+		return ok && a == other
+	case string:
+		other, ok := b.(string)
+		return ok && a == other
+	case []interface{}:
+		other, ok := b.([]interface{})
+		if !ok || len(a) != len(other) {
+			return false
+		}
+		for i := range a {
+			if !genericEqual(a[i], other[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		other, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(other) {
+			return false
+		}
+		for key, value := range a {
+			otherValue, exists := other[key]
+			if !exists || !genericEqual(value, otherValue) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,420 @@
+package EvLJson
+
+import (
+	"bufio"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// INDEX_VERSION is the version of the index format WriteTo writes and
+// ReadIndex accepts
+const INDEX_VERSION = 1
+
+// indexMagic starts every index
+const indexMagic = "EvLJsonIndex"
+
+var indexCrcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// IndexEntry is the byte range of a value of the indexed document, from
+// its first byte up to the byte after its last
+type IndexEntry struct {
+	Pointer string
+	Start   int64
+	End     int64
+}
+
+// Index maps json pointers of a document to the byte ranges of their
+// values: every value down to Depth tokens below the root and every item
+// of the arrays among them, in document order
+//
+// Size and Checksum, the CRC-32C of the whole document, tie the index to
+// the document it was built from
+type Index struct {
+	Depth    int
+	Size     int64
+	Checksum uint32
+	Entries  []IndexEntry
+}
+
+// InvalidIndexError is an index that cannot be read, being damaged or of
+// another version
+type InvalidIndexError struct {
+	Reason string
+}
+
+func (err InvalidIndexError) Error() string {
+	return "Invalid index: " + err.Reason
+}
+
+// IndexMismatchError is an index used with a document it was not built
+// from, told apart by What: the size, the checksum or a value's range
+type IndexMismatchError struct {
+	What string
+}
+
+func (err IndexMismatchError) Error() string {
+	return "Index does not match the document: " + err.What + " differs"
+}
+
+// indexChecksum sums and counts what is written to it
+type indexChecksum struct {
+	crc  uint32
+	size int64
+}
+
+func (sum *indexChecksum) Write(data []byte) (int, error) {
+	sum.crc = crc32.Update(sum.crc, indexCrcTable, data)
+	sum.size += int64(len(data))
+	return len(data), nil
+}
+
+// indexFrame is an array or dict being indexed
+type indexFrame struct {
+	pointer string
+	array   bool
+	items   int
+	entry   int // in Index.Entries, -1 when not indexed
+}
+
+// indexBuilder is the parseHandler of BuildIndex, reading the ranges of values
+// off the tracked positions
+type indexBuilder struct {
+	index  *Index
+	frames []indexFrame
+	token  writerToken_t
+	entry  int // of the string or number being parsed
+	key    []byte
+}
+
+// startValue adds an entry for a value starting if it is to be indexed,
+// returning its index or -1
+func (b *indexBuilder) startValue(p *Parser) int {
+	depth := len(b.frames)
+	var pointer string
+	if depth != 0 {
+		parent := &b.frames[depth-1]
+		if depth > b.index.Depth && !(parent.array && depth == b.index.Depth+1) {
+			if parent.array {
+				parent.items++
+			}
+			return -1
+		}
+		if parent.array {
+			pointer = parent.pointer + "/" + strconv.Itoa(parent.items)
+			parent.items++
+		} else {
+			pointer = parent.pointer + "/" + escapePointerToken(string(b.key))
+		}
+	}
+	b.index.Entries = append(b.index.Entries, IndexEntry{Pointer: pointer, Start: p.ValueStart().Offset})
+	return len(b.index.Entries) - 1
+}
+
+func (b *indexBuilder) endValue(p *Parser, entry int) {
+	if entry >= 0 {
+		end, _ := p.ValueEnd()
+		b.index.Entries[entry].End = end.Offset
+	}
+}
+
+func (b *indexBuilder) OnEvent(p *Parser, evt event_t) {
+	switch evt {
+	case EVT_NULL, EVT_TRUE, EVT_FALSE:
+		b.endValue(p, b.startValue(p))
+	case EVT_ARRAY, EVT_DICT:
+		entry := b.startValue(p)
+		var pointer string
+		if entry >= 0 {
+			pointer = b.index.Entries[entry].Pointer
+		}
+		b.frames = append(b.frames, indexFrame{pointer: pointer, array: evt == EVT_ARRAY, entry: entry})
+	case EVT_STRING:
+		if p.IsDictKey() {
+			b.token = WRITER_TOKEN_KEY
+			b.key = b.key[:0]
+		} else {
+			b.token = WRITER_TOKEN_STRING
+			b.entry = b.startValue(p)
+		}
+	case EVT_NUMBER:
+		b.token = WRITER_TOKEN_NUMBER
+		b.entry = b.startValue(p)
+	case EVT_LEAVE:
+		switch b.token {
+		case WRITER_TOKEN_KEY:
+		case WRITER_TOKEN_STRING, WRITER_TOKEN_NUMBER:
+			b.endValue(p, b.entry)
+		default:
+			last := len(b.frames) - 1
+			b.endValue(p, b.frames[last].entry)
+			b.frames = b.frames[:last]
+		}
+		b.token = WRITER_TOKEN_NONE
+	}
+}
+
+func (b *indexBuilder) OnData(p *Parser, endOfData bool) {
+	if b.token == WRITER_TOKEN_KEY {
+		b.key = append(b.key, p.DataBuffer...)
+	}
+}
+
+// BuildIndex parses the document read from reader once, indexing the
+// values down to depth tokens below the root and the items of the arrays
+// among them; the document must be read in full for its checksum
+func BuildIndex(reader io.Reader, depth int) (*Index, error) {
+	if depth < 0 {
+		depth = 0
+	}
+	var sum indexChecksum
+	input := bufio.NewReader(io.TeeReader(reader, &sum))
+	builder := indexBuilder{index: &Index{Depth: depth}}
+	p := NewParser(make([]byte, 0, DOCUMENT_DATA_BUFFER_SIZE), nil, DOCUMENT_PARSER_OPTIONS|OPT_TRACK_POSITIONS)
+	err := parseWith(&p, input, &builder)
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(io.Discard, input); err != nil {
+		return nil, err
+	}
+	builder.index.Size, builder.index.Checksum = sum.size, sum.crc
+	return builder.index, nil
+}
+
+// WriteTo writes the index in the format of INDEX_VERSION: a header with
+// the depth, size and checksum, the entries, their starts as deltas, and a
+// CRC-32C of it all
+func (index *Index) WriteTo(w io.Writer) (int64, error) {
+	buffer := append([]byte(indexMagic), 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(buffer[len(indexMagic):], index.Checksum)
+	for _, value := range []uint64{INDEX_VERSION, uint64(index.Depth), uint64(index.Size), uint64(len(index.Entries))} {
+		buffer = binary.AppendUvarint(buffer, value)
+	}
+	var start int64
+	for _, entry := range index.Entries {
+		buffer = binary.AppendUvarint(buffer, uint64(len(entry.Pointer)))
+		buffer = append(buffer, entry.Pointer...)
+		buffer = binary.AppendUvarint(buffer, uint64(entry.Start-start))
+		buffer = binary.AppendUvarint(buffer, uint64(entry.End-entry.Start))
+		start = entry.Start
+	}
+	buffer = binary.LittleEndian.AppendUint32(buffer, crc32.Checksum(buffer, indexCrcTable))
+	n, err := w.Write(buffer)
+	return int64(n), err
+}
+
+// indexDecoder reads the fields of an index
+type indexDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *indexDecoder) uvarint() uint64 {
+	value, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.err, d.data = InvalidIndexError{"truncated"}, nil
+		return 0
+	}
+	d.data = d.data[n:]
+	return value
+}
+
+// ReadIndex reads an index written by WriteTo
+func ReadIndex(reader io.Reader) (*Index, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	header := len(indexMagic) + 4
+	if len(data) < header+4 || string(data[:len(indexMagic)]) != indexMagic {
+		return nil, InvalidIndexError{"not an index"}
+	}
+	content := data[:len(data)-4]
+	if crc32.Checksum(content, indexCrcTable) != binary.LittleEndian.Uint32(data[len(content):]) {
+		return nil, InvalidIndexError{"checksum mismatch"}
+	}
+	d := indexDecoder{data: content[header:]}
+	if version := d.uvarint(); version != INDEX_VERSION && d.err == nil {
+		return nil, InvalidIndexError{"version " + strconv.FormatUint(version, 10)}
+	}
+	index := &Index{Checksum: binary.LittleEndian.Uint32(content[len(indexMagic):])}
+	index.Depth, index.Size = int(d.uvarint()), int64(d.uvarint())
+	count := d.uvarint()
+	if count > uint64(len(d.data)) {
+		// every entry takes at least three bytes
+		return nil, InvalidIndexError{"truncated"}
+	}
+	index.Entries = make([]IndexEntry, 0, count)
+	var start int64
+	for ; count != 0 && d.err == nil; count-- {
+		length := d.uvarint()
+		if length > uint64(len(d.data)) {
+			return nil, InvalidIndexError{"truncated"}
+		}
+		pointer := string(d.data[:length])
+		d.data = d.data[length:]
+		start += int64(d.uvarint())
+		index.Entries = append(index.Entries, IndexEntry{pointer, start, start + int64(d.uvarint())})
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(d.data) != 0 {
+		return nil, InvalidIndexError{"trailing data"}
+	}
+	return index, nil
+}
+
+// Verify checks that the size bytes of file are the document the index was
+// built from, reading all of them for the checksum
+func (index *Index) Verify(file io.ReaderAt, size int64) error {
+	if size != index.Size {
+		return IndexMismatchError{"size"}
+	}
+	var sum indexChecksum
+	if _, err := io.Copy(&sum, io.NewSectionReader(file, 0, size)); err != nil {
+		return err
+	}
+	if sum.crc != index.Checksum {
+		return IndexMismatchError{"checksum"}
+	}
+	return nil
+}
+
+// IndexedReader decodes values of an indexed document reading only the
+// byte range of the nearest indexed value
+type IndexedReader struct {
+	file    io.ReaderAt
+	index   *Index
+	entries map[string]int
+}
+
+// NewIndexedReader reads the document of size bytes in file with index,
+// which is only checked against the size; see Index.Verify
+func NewIndexedReader(file io.ReaderAt, size int64, index *Index) (*IndexedReader, error) {
+	if size != index.Size {
+		return nil, IndexMismatchError{"size"}
+	}
+	entries := make(map[string]int, len(index.Entries))
+	for i, entry := range index.Entries {
+		entries[entry.Pointer] = i
+	}
+	return &IndexedReader{file: file, index: index, entries: entries}, nil
+}
+
+// Section is the json text of the value at pointer, which must be indexed
+func (r *IndexedReader) Section(pointer string) (*io.SectionReader, error) {
+	i, ok := r.entries[pointer]
+	if !ok {
+		return nil, PointerNotFoundError{pointer}
+	}
+	entry := r.index.Entries[i]
+	return io.NewSectionReader(r.file, entry.Start, entry.End-entry.Start), nil
+}
+
+// indexTargetSink notes whether the value it passes on was reached
+type indexTargetSink struct {
+	Sink
+	found bool
+}
+
+func (s *indexTargetSink) Null() error {
+	s.found = true
+	return s.Sink.Null()
+}
+
+func (s *indexTargetSink) Bool(value bool) error {
+	s.found = true
+	return s.Sink.Bool(value)
+}
+
+func (s *indexTargetSink) String(value []byte) error {
+	s.found = true
+	return s.Sink.String(value)
+}
+
+func (s *indexTargetSink) Number(text []byte) error {
+	s.found = true
+	return s.Sink.Number(text)
+}
+
+func (s *indexTargetSink) BeginArray() error {
+	s.found = true
+	return s.Sink.BeginArray()
+}
+
+func (s *indexTargetSink) BeginDict() error {
+	s.found = true
+	return s.Sink.BeginDict()
+}
+
+func (s *indexTargetSink) ChildEnd() error {
+	if ender, ok := s.Sink.(ChildEnder); ok {
+		return ender.ChildEnd()
+	}
+	return nil
+}
+
+// DecodeSink feeds the value at pointer into sink, parsing the range of
+// its nearest indexed ancestor, or its own; DecodeErrors are placed in the
+// whole document
+func (r *IndexedReader) DecodeSink(pointer string, sink Sink) error {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return err
+	}
+	requested, ancestor := pointer, len(tokens)
+	i, ok := r.entries[pointer]
+	for !ok && ancestor > 0 {
+		ancestor--
+		pointer = pointer[:strings.LastIndexByte(pointer, '/')]
+		i, ok = r.entries[pointer]
+	}
+	if !ok {
+		return PointerNotFoundError{requested}
+	}
+	target := &indexTargetSink{Sink: sink}
+	var root Sink = target
+	if ancestor != len(tokens) {
+		root = newStreamPathSink(tokens[ancestor:], target)
+	}
+	items := 0
+	wrapper := SliceSink(new([]struct{}), func(*struct{}) Sink {
+		items++
+		return root
+	})
+	entry := r.index.Entries[i]
+	section := io.NewSectionReader(r.file, entry.Start, entry.End-entry.Start)
+	document := bufio.NewReader(io.MultiReader(strings.NewReader("["), section, strings.NewReader("]")))
+	err = DecodeSink(document, wrapper)
+	if decodeErr, ok := err.(DecodeError); ok {
+		// from within the array the range is wrapped in
+		decodeErr.Pointer = entry.Pointer + strings.TrimPrefix(decodeErr.Pointer, "/0")
+		decodeErr.Offset += entry.Start - 1
+		return decodeErr
+	}
+	switch {
+	case err != nil:
+		return err
+	case items != 1:
+		return IndexMismatchError{"range of " + strconv.Quote(entry.Pointer)}
+	case !target.found:
+		return PointerNotFoundError{requested}
+	}
+	return nil
+}
+
+// Decode is DecodeSink into v, see Decode
+func (r *IndexedReader) Decode(pointer string, v interface{}) error {
+	sink, err := ValueSink(v, 0)
+	if err != nil {
+		return err
+	}
+	return r.DecodeSink(pointer, sink)
+}
@@ -0,0 +1,229 @@
+package EvLJson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const TEST_INDEX_DOC = " {\"users\": [{\"name\": \"ann\", \"tags\": [\"a\", \"b\"]}, {\"name\": \"b\\u00e9n\", \"age\": 42}, null, 7.5e1],\n" +
+	"\t\"a/b~c\": {\"x\": true, \"y\": {\"z\": [[1], {\"w\": false}]}}, \"s\": \"str\\\"ing\", \"\": {}}\n"
+
+// indexTestText is the json text of the value at pointer in doc as the dom
+// writes it
+func indexTestText(t *testing.T, text string, pointer string) string {
+	dom, err := ParseValue(bytes.NewReader([]byte(text)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := dom.Pointer(pointer)
+	if err != nil {
+		t.Fatal(pointer, err)
+	}
+	out, _ := value.MarshalJSON()
+	return string(out)
+}
+
+func TestBuildIndex(t *testing.T) {
+	doc := TEST_INDEX_DOC
+	for depth, expected := range [][]string{
+		{""},
+		{"", "/users", "/users/0", "/users/1", "/users/2", "/users/3", "/a~1b~0c", "/s", "/"},
+		{"", "/users", "/users/0", "/users/1", "/users/2", "/users/3", "/a~1b~0c", "/a~1b~0c/x", "/a~1b~0c/y", "/s", "/"},
+		{"", "/users", "/users/0", "/users/0/name", "/users/0/tags", "/users/0/tags/0", "/users/0/tags/1", "/users/1", "/users/1/name", "/users/1/age",
+			"/users/2", "/users/3", "/a~1b~0c", "/a~1b~0c/x", "/a~1b~0c/y", "/a~1b~0c/y/z", "/a~1b~0c/y/z/0", "/a~1b~0c/y/z/1", "/s", "/"},
+	} {
+		index, err := BuildIndex(strings.NewReader(doc), depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pointers []string
+		for _, entry := range index.Entries {
+			pointers = append(pointers, entry.Pointer)
+			// the range is exactly the value
+			text := doc[entry.Start:entry.End]
+			if strings.TrimSpace(text) != text || indexTestText(t, "["+text+"]", "/0") != indexTestText(t, doc, entry.Pointer) {
+				t.Fatalf("%q: %q", entry.Pointer, text)
+			}
+		}
+		if strings.Join(pointers, " ") != strings.Join(expected, " ") {
+			t.Fatalf("depth %d: %q", depth, pointers)
+		}
+		if index.Size != int64(len(doc)) || index.Depth != depth {
+			t.Fatal(index.Size, index.Depth)
+		}
+	}
+	array, err := BuildIndex(strings.NewReader(`[1, [2], {"a": 3}]`), 0)
+	if err != nil || len(array.Entries) != 4 || array.Entries[3].Pointer != "/2" {
+		t.Fatal(array, err)
+	}
+	for _, bad := range []string{`{"a": [1, 2}`, `{"a": 1`, ``} {
+		if _, err := BuildIndex(strings.NewReader(bad), 2); err == nil {
+			t.Fatalf("%q", bad)
+		}
+	}
+}
+
+func TestIndexFormat(t *testing.T) {
+	index, err := BuildIndex(strings.NewReader(TEST_INDEX_DOC), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if n, err := index.WriteTo(&out); err != nil || n != int64(out.Len()) {
+		t.Fatal(n, err)
+	}
+	read, err := ReadIndex(bytes.NewReader(out.Bytes()))
+	if err != nil || !reflect.DeepEqual(read, index) {
+		t.Fatalf("%v %+v", err, read)
+	}
+	written := out.Bytes()
+	for i := range written {
+		damaged := append([]byte(nil), written...)
+		damaged[i] ^= 0x20
+		if _, err := ReadIndex(bytes.NewReader(damaged)); !errors.As(err, new(InvalidIndexError)) {
+			t.Fatalf("byte %d: %v", i, err)
+		}
+		if _, err := ReadIndex(bytes.NewReader(written[:i])); !errors.As(err, new(InvalidIndexError)) {
+			t.Fatalf("%d bytes: %v", i, err)
+		}
+	}
+	// a later version with a valid checksum
+	future := append([]byte(nil), written[:len(written)-4]...)
+	future[len(indexMagic)+4] = INDEX_VERSION + 1
+	if _, err := ReadIndex(bytes.NewReader(indexWithTrailer(future))); err != (InvalidIndexError{"version 2"}) {
+		t.Fatal(err)
+	}
+}
+
+// indexWithTrailer appends the checksum of an index to content
+func indexWithTrailer(content []byte) []byte {
+	var sum indexChecksum
+	sum.Write(content)
+	return append(content, byte(sum.crc), byte(sum.crc>>8), byte(sum.crc>>16), byte(sum.crc>>24))
+}
+
+// rangeReaderAt records the bytes read from it
+type rangeReaderAt struct {
+	data     []byte
+	min, max int64
+}
+
+func (r *rangeReaderAt) ReadAt(b []byte, offset int64) (int, error) {
+	r.min, r.max = min(r.min, offset), max(r.max, offset)
+	if offset >= int64(len(r.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, r.data[offset:])
+	r.max = max(r.max, offset+int64(n))
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func TestIndexedReader(t *testing.T) {
+	doc := TEST_INDEX_DOC
+	index, err := BuildIndex(strings.NewReader(doc), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := &rangeReaderAt{data: []byte(doc)}
+	reader, err := NewIndexedReader(file, int64(len(doc)), index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pointer := range []string{"/users/1", "/users/0/tags/1", "/a~1b~0c/y/z/1/w", "/s", "/users/3", ""} {
+		entry := index.Entries[0]
+		for _, candidate := range index.Entries {
+			if strings.HasPrefix(pointer+"/", candidate.Pointer+"/") && len(candidate.Pointer) >= len(entry.Pointer) {
+				entry = candidate
+			}
+		}
+		file.min, file.max = int64(len(doc)), 0
+		var raw json.RawMessage
+		if err := reader.Decode(pointer, &raw); err != nil {
+			t.Fatal(pointer, err)
+		}
+		if indexTestText(t, "["+string(raw)+"]", "/0") != indexTestText(t, doc, pointer) {
+			t.Fatalf("%q: %s", pointer, raw)
+		}
+		// only the range of the nearest indexed value is read
+		if file.min < entry.Start || file.max > entry.End {
+			t.Fatalf("%q: read %d to %d of %+v", pointer, file.min, file.max, entry)
+		}
+	}
+	if section, err := reader.Section("/a~1b~0c"); err != nil || section.Size() != int64(strings.Index(doc, `}}, "s"`)+2-strings.Index(doc, `{"x"`)) {
+		t.Fatal(err)
+	}
+	for _, missing := range []string{"/users/4", "/nope", "/users/0/tags/2", "/s/0"} {
+		var v interface{}
+		if err := reader.Decode(missing, &v); err != (PointerNotFoundError{missing}) {
+			t.Fatalf("%q: %v", missing, err)
+		}
+	}
+	if _, err := reader.Section("/users/0/name"); err != (PointerNotFoundError{"/users/0/name"}) {
+		t.Fatal(err)
+	}
+
+	// errors are placed in the whole document
+	var age string
+	var decodeErr DecodeError
+	if err := reader.Decode("/users/1/age", &age); !errors.As(err, &decodeErr) || decodeErr.Pointer != "/users/1/age" || decodeErr.Offset != int64(strings.Index(doc, "42")) {
+		t.Fatalf("%v", err)
+	}
+	var user struct{ Age string }
+	if err := reader.Decode("/users/1", &user); !errors.As(err, &decodeErr) || decodeErr.Pointer != "/users/1/age" || decodeErr.Offset != int64(strings.Index(doc, "42")) {
+		t.Fatalf("%v", err)
+	}
+}
+
+func TestIndexMismatch(t *testing.T) {
+	doc := TEST_INDEX_DOC
+	index, err := BuildIndex(strings.NewReader(doc), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Verify(strings.NewReader(doc), int64(len(doc))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewIndexedReader(strings.NewReader(doc), int64(len(doc))+1, index); err != (IndexMismatchError{"size"}) {
+		t.Fatal(err)
+	}
+	if err := index.Verify(strings.NewReader(doc+" "), int64(len(doc))+1); err != (IndexMismatchError{"size"}) {
+		t.Fatal(err)
+	}
+	// an edit keeping the size slips past the size but not the checksum
+	edited := strings.Replace(doc, `"ann", "tags"`, `"annie",  "x"`, 1)
+	if err := index.Verify(strings.NewReader(edited), int64(len(edited))); err != (IndexMismatchError{"checksum"}) {
+		t.Fatal(err)
+	}
+	reader, err := NewIndexedReader(strings.NewReader(edited), int64(len(edited)), index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v interface{}
+	if err := reader.Decode("/users/0", &v); err != nil {
+		t.Fatal(err)
+	}
+	shifted := strings.Replace(doc, `null, 7.5e1`, `null,7.5e1 `, 1)
+	reader, _ = NewIndexedReader(strings.NewReader(shifted), int64(len(shifted)), index)
+	if err := reader.Decode("/users/3", &v); err == nil {
+		t.Fatal(v)
+	}
+}
+
+func BenchmarkBuildIndex(b *testing.B) {
+	doc := []byte("[" + strings.Repeat(STR_OBSFUCATED_BENCHMARK_BASIS+",", 15) + STR_OBSFUCATED_BENCHMARK_BASIS + "]")
+	b.SetBytes(int64(len(doc)))
+	for i := 0; i < b.N; i++ {
+		if _, err := BuildIndex(bytes.NewReader(doc), 3); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
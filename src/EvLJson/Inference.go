@@ -0,0 +1,605 @@
+package EvLJson
+
+import (
+	"io"
+	"math"
+	"net"
+	"net/mail"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const ( // stringFormat_t
+	FORMAT_DATE = 1 << iota
+	FORMAT_DATE_TIME
+	FORMAT_TIME
+	FORMAT_UUID
+	FORMAT_EMAIL
+	FORMAT_IPV4
+	FORMAT_ALL = FORMAT_DATE | FORMAT_DATE_TIME | FORMAT_TIME | FORMAT_UUID | FORMAT_EMAIL | FORMAT_IPV4
+)
+
+// strings longer than this are not checked against formats, which keeps
+// the memory each string needs bounded
+const MAX_FORMAT_LENGTH = 64
+
+// a dict with more distinct keys than this is treated as a map: its members
+// are merged into one additionalProperties schema
+const DEFAULT_MAX_INFERRED_PROPERTIES = 1000
+
+const SCHEMA_DIALECT = "https://json-schema.org/draft/2020-12/schema"
+
+// INFERRED_DOCUMENTS_KEYWORD annotates a written InferredSchema with the
+// number of documents observed, so loading it back restores Documents
+const INFERRED_DOCUMENTS_KEYWORD = "x-documents"
+
+type stringFormat_t uint8
+
+var stringFormatNames = []struct {
+	format stringFormat_t
+	name   string
+}{
+	{FORMAT_DATE, "date"},
+	{FORMAT_DATE_TIME, "date-time"},
+	{FORMAT_TIME, "time"},
+	{FORMAT_UUID, "uuid"},
+	{FORMAT_EMAIL, "email"},
+	{FORMAT_IPV4, "ipv4"},
+}
+
+func isUuid(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if s[i] != '-' {
+				return false
+			}
+		default:
+			if _, ok := hexCharValue(s[i]); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func detectFormats(s string) stringFormat_t {
+	var formats stringFormat_t
+	if _, err := time.Parse("2006-01-02", s); err == nil {
+		formats |= FORMAT_DATE
+	}
+	if _, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		formats |= FORMAT_DATE_TIME
+	}
+	if _, err := time.Parse("15:04:05Z07:00", s); err == nil {
+		formats |= FORMAT_TIME
+	} else if _, err := time.Parse("15:04:05.999999999Z07:00", s); err == nil {
+		formats |= FORMAT_TIME
+	}
+	if isUuid(s) {
+		formats |= FORMAT_UUID
+	}
+	if address, err := mail.ParseAddress(s); err == nil && address.Name == "" && address.Address == s {
+		formats |= FORMAT_EMAIL
+	}
+	if ip := net.ParseIP(s); ip != nil && ip.To4() != nil && len(s) <= len("255.255.255.255") {
+		formats |= FORMAT_IPV4
+	}
+	return formats
+}
+
+// inferredNode accumulates what has been seen at one path; dict members
+// are required when they were present in every dict seen at the path
+type inferredNode struct {
+	count   int64
+	types   schemaType_t // SCHEMA_TYPE_INTEGER for whole numbers, SCHEMA_TYPE_NUMBER for the rest
+	minimum float64
+	maximum float64
+
+	formats   stringFormat_t // formats every string so far matched
+	minLength int
+	maxLength int
+
+	dicts      int64
+	keys       []string // first seen order
+	properties map[string]*inferredNode
+	additional *inferredNode // every member once the dict is treated as a map
+
+	items    *inferredNode
+	minItems int
+	maxItems int
+}
+
+func newInferredNode() *inferredNode {
+	return &inferredNode{formats: FORMAT_ALL, minLength: -1, minItems: -1, minimum: math.Inf(1), maximum: math.Inf(-1)}
+}
+
+func (n *inferredNode) property(key string, maxProperties int) *inferredNode {
+	if n.additional != nil {
+		return n.additional
+	}
+	if child, exists := n.properties[key]; exists {
+		return child
+	}
+	if len(n.keys) >= maxProperties {
+		return n.collapse(maxProperties)
+	}
+	if n.properties == nil {
+		n.properties = map[string]*inferredNode{}
+	}
+	child := newInferredNode()
+	n.keys = append(n.keys, key)
+	n.properties[key] = child
+	return child
+}
+
+// collapse starts treating the dict as a map of one value schema
+func (n *inferredNode) collapse(maxProperties int) *inferredNode {
+	if n.additional == nil {
+		n.additional = newInferredNode()
+		for _, key := range n.keys {
+			n.additional.merge(n.properties[key], maxProperties)
+		}
+		n.keys = nil
+		n.properties = nil
+	}
+	return n.additional
+}
+
+func (n *inferredNode) observeLength(length int) {
+	if n.minLength < 0 || length < n.minLength {
+		n.minLength = length
+	}
+	if length > n.maxLength {
+		n.maxLength = length
+	}
+}
+
+func (n *inferredNode) observeItems(items int) {
+	if n.minItems < 0 || items < n.minItems {
+		n.minItems = items
+	}
+	if items > n.maxItems {
+		n.maxItems = items
+	}
+}
+
+func (n *inferredNode) observeNumber(number float64) {
+	if number == math.Trunc(number) {
+		n.types |= SCHEMA_TYPE_INTEGER
+	} else {
+		n.types |= SCHEMA_TYPE_NUMBER
+	}
+	n.minimum = math.Min(n.minimum, number)
+	n.maximum = math.Max(n.maximum, number)
+}
+
+func (n *inferredNode) merge(other *inferredNode, maxProperties int) {
+	if other == nil {
+		return
+	}
+	n.count += other.count
+	if other.types&SCHEMA_TYPE_STRING != 0 {
+		if n.types&SCHEMA_TYPE_STRING != 0 {
+			n.formats &= other.formats
+		} else {
+			n.formats = other.formats
+		}
+	}
+	n.types |= other.types
+	n.minimum = math.Min(n.minimum, other.minimum)
+	n.maximum = math.Max(n.maximum, other.maximum)
+	if other.minLength >= 0 {
+		n.observeLength(other.minLength)
+		n.observeLength(other.maxLength)
+	}
+	if other.minItems >= 0 {
+		n.observeItems(other.minItems)
+		n.observeItems(other.maxItems)
+	}
+	if other.items != nil {
+		if n.items == nil {
+			n.items = newInferredNode()
+		}
+		n.items.merge(other.items, maxProperties)
+	}
+	n.dicts += other.dicts
+	if other.additional != nil {
+		n.collapse(maxProperties).merge(other.additional, maxProperties)
+	}
+	for _, key := range other.keys {
+		n.property(key, maxProperties).merge(other.properties[key], maxProperties)
+	}
+}
+
+// InferredSchema describes the shape of every document observed so far and
+// can be written out as a draft 2020-12 json schema at any point
+//
+// Memory use grows with the number of distinct paths, never with the
+// number or size of the documents observed
+//
+// The zero InferredSchema is empty and ready to use, a zero MaxProperties
+// standing for DEFAULT_MAX_INFERRED_PROPERTIES
+type InferredSchema struct {
+	MaxProperties int
+	Documents     int64
+	root          *inferredNode
+}
+
+// rootNode is the node of the documents, made on first use
+func (s *InferredSchema) rootNode() *inferredNode {
+	if s.root == nil {
+		s.root = newInferredNode()
+	}
+	return s.root
+}
+
+func (s *InferredSchema) maxProperties() int {
+	if s.MaxProperties == 0 {
+		return DEFAULT_MAX_INFERRED_PROPERTIES
+	}
+	return s.MaxProperties
+}
+
+func NewInferredSchema() InferredSchema {
+	return InferredSchema{MaxProperties: DEFAULT_MAX_INFERRED_PROPERTIES, root: newInferredNode()}
+}
+
+type inferenceFrame struct {
+	node  *inferredNode
+	items int
+}
+
+type inferenceHandler struct {
+	schema  *InferredSchema
+	frames  []inferenceFrame
+	node    *inferredNode // the string or number being read
+	token   writerToken_t
+	text    []byte
+	length  int
+	lastKey string
+}
+
+func (h *inferenceHandler) startValue(types schemaType_t) *inferredNode {
+	var node *inferredNode
+	if len(h.frames) == 0 {
+		node = h.schema.rootNode()
+	} else if parent := &h.frames[len(h.frames)-1]; parent.items < 0 {
+		node = parent.node.property(h.lastKey, h.schema.maxProperties())
+	} else {
+		parent.items++
+		if parent.node.items == nil {
+			parent.node.items = newInferredNode()
+		}
+		node = parent.node.items
+	}
+	node.count++
+	node.types |= types
+	return node
+}
+
+func inferenceOnEvent(p *Parser, evt event_t) {
+	h := p.UserData.(*inferenceHandler)
+	switch evt {
+	case EVT_NULL:
+		h.startValue(SCHEMA_TYPE_NULL)
+	case EVT_TRUE, EVT_FALSE:
+		h.startValue(SCHEMA_TYPE_BOOLEAN)
+	case EVT_ARRAY:
+		h.frames = append(h.frames, inferenceFrame{h.startValue(SCHEMA_TYPE_ARRAY), 0})
+	case EVT_DICT:
+		node := h.startValue(SCHEMA_TYPE_OBJECT)
+		node.dicts++
+		// items of -1 marks the frame as a dict
+		h.frames = append(h.frames, inferenceFrame{node, -1})
+	case EVT_STRING:
+		if p.IsDictKey() {
+			h.token = WRITER_TOKEN_KEY
+		} else {
+			h.token = WRITER_TOKEN_STRING
+			h.node = h.startValue(SCHEMA_TYPE_STRING)
+		}
+	case EVT_NUMBER:
+		h.token = WRITER_TOKEN_NUMBER
+		h.node = h.startValue(0)
+	case EVT_LEAVE:
+		switch h.token {
+		case WRITER_TOKEN_KEY:
+			h.lastKey = string(h.text)
+		case WRITER_TOKEN_STRING:
+			h.node.observeLength(h.length)
+			if h.length > MAX_FORMAT_LENGTH {
+				h.node.formats = 0
+			} else if h.node.formats != 0 {
+				h.node.formats &= detectFormats(string(h.text))
+			}
+		case WRITER_TOKEN_NUMBER:
+			number, _ := strconv.ParseFloat(string(h.text), 64)
+			h.node.observeNumber(number)
+		default:
+			last := len(h.frames) - 1
+			if frame := h.frames[last]; frame.items >= 0 {
+				frame.node.observeItems(frame.items)
+			}
+			h.frames = h.frames[:last]
+		}
+		h.token = WRITER_TOKEN_NONE
+		h.text = h.text[:0]
+		h.length = 0
+	}
+}
+
+func inferenceOnData(p *Parser, endOfData bool) {
+	h := p.UserData.(*inferenceHandler)
+	h.length += countColumns(p.DataBuffer)
+	// a format candidate is at most MAX_FORMAT_LENGTH characters long, so
+	// longer strings need not be kept
+	if h.token != WRITER_TOKEN_STRING || len(h.text) <= MAX_FORMAT_LENGTH*utf8.UTFMax {
+		h.text = append(h.text, p.DataBuffer...)
+	}
+}
+
+// documentsByteReader reads a stream of documents, handing the byte a
+// parse reads past the end of its document on to the next parse
+type documentsByteReader struct {
+	reader io.ByteReader
+	last   byte
+	read   bool // last was read, the parse did not end on an error
+	unread bool
+}
+
+func (r *documentsByteReader) ReadByte() (byte, error) {
+	if r.unread {
+		r.unread = false
+		return r.last, nil
+	}
+	b, err := r.reader.ReadByte()
+	r.last, r.read = b, err == nil
+	return b, err
+}
+
+// nextDocument gives back the byte read after the document just parsed
+func (r *documentsByteReader) nextDocument() {
+	r.unread = r.read
+}
+
+// ObserveStream adds every document in byteReader to the schema; documents
+// may follow each other directly or be separated by any whitespace, so
+// ndjson works as is
+func (s *InferredSchema) ObserveStream(byteReader io.ByteReader) error {
+	h := inferenceHandler{schema: s}
+	parser := NewParser(make([]byte, 0, DOCUMENT_DATA_BUFFER_SIZE), nil, OPT_ALLOW_EXTRA_WHITESPACE|OPT_DECODE_UNICODE_ESCAPES)
+	parser.UserData = &h
+	reader := documentsByteReader{reader: byteReader}
+	for {
+		err := parser.Parse(&reader, inferenceOnEvent, inferenceOnData)
+		if err == nil {
+			s.Documents++
+			reader.nextDocument()
+			continue
+		}
+		if err == io.EOF && len(parser.ContextStack) == 0 && len(h.frames) == 0 {
+			return nil
+		}
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+}
+
+// Merge folds in a schema inferred from another batch of documents, as if
+// those documents had been observed here too
+func (s *InferredSchema) Merge(other *InferredSchema) {
+	s.Documents += other.Documents
+	s.rootNode().merge(other.root, s.maxProperties())
+}
+
+func (n *inferredNode) write(w *Writer) {
+	w.BeginDict()
+	n.writeKeywords(w)
+	w.EndDict()
+}
+
+func (n *inferredNode) writeKeywords(w *Writer) {
+	var types []string
+	for _, name := range []string{"null", "boolean", "object", "array", "number", "integer", "string"} {
+		bit := schemaTypeNames[name]
+		if n.types&bit == 0 || (bit == SCHEMA_TYPE_INTEGER && n.types&SCHEMA_TYPE_NUMBER != 0) {
+			continue
+		}
+		types = append(types, name)
+	}
+	if len(types) == 1 {
+		w.Key("type")
+		w.String(types[0])
+	} else if len(types) > 1 {
+		w.Key("type")
+		w.BeginArray()
+		for _, name := range types {
+			w.String(name)
+		}
+		w.EndArray()
+	}
+	// the bounds stay infinite until a number is seen, as for a loaded
+	// schema without them
+	if n.types&(SCHEMA_TYPE_NUMBER|SCHEMA_TYPE_INTEGER) != 0 && !math.IsInf(n.minimum, 0) {
+		w.Key("minimum")
+		w.Number(strconv.FormatFloat(n.minimum, 'g', -1, 64))
+	}
+	if n.types&(SCHEMA_TYPE_NUMBER|SCHEMA_TYPE_INTEGER) != 0 && !math.IsInf(n.maximum, 0) {
+		w.Key("maximum")
+		w.Number(strconv.FormatFloat(n.maximum, 'g', -1, 64))
+	}
+	if n.types&SCHEMA_TYPE_STRING != 0 {
+		for _, format := range stringFormatNames {
+			// date-time strings never parse as dates, so at most one is left
+			if n.formats&format.format != 0 {
+				w.Key("format")
+				w.String(format.name)
+				break
+			}
+		}
+		w.Key("minLength")
+		w.Number(strconv.Itoa(n.minLength))
+		w.Key("maxLength")
+		w.Number(strconv.Itoa(n.maxLength))
+	}
+	if n.types&SCHEMA_TYPE_OBJECT != 0 {
+		if n.additional != nil {
+			w.Key("additionalProperties")
+			n.additional.write(w)
+		} else {
+			w.Key("properties")
+			w.BeginDict()
+			var required []string
+			for _, key := range n.keys {
+				child := n.properties[key]
+				w.Key(key)
+				child.write(w)
+				if child.count >= n.dicts {
+					required = append(required, key)
+				}
+			}
+			w.EndDict()
+			if required != nil {
+				w.Key("required")
+				w.BeginArray()
+				for _, key := range required {
+					w.String(key)
+				}
+				w.EndArray()
+			}
+		}
+	}
+	if n.types&SCHEMA_TYPE_ARRAY != 0 {
+		if n.items != nil {
+			w.Key("items")
+			n.items.write(w)
+		}
+		w.Key("minItems")
+		w.Number(strconv.Itoa(n.minItems))
+		w.Key("maxItems")
+		w.Number(strconv.Itoa(n.maxItems))
+	}
+}
+
+// Write emits the inferred json schema through w and flushes it
+func (s *InferredSchema) Write(w *Writer) error {
+	w.BeginDict()
+	w.Key("$schema")
+	w.String(SCHEMA_DIALECT)
+	w.Key(INFERRED_DOCUMENTS_KEYWORD)
+	w.Number(strconv.FormatInt(s.Documents, 10))
+	s.rootNode().writeKeywords(w)
+	w.EndDict()
+	return w.Flush()
+}
+
+func schemaNumber(schema map[string]interface{}, keyword string) (float64, bool) {
+	number, ok := schema[keyword].(float64)
+	return number, ok
+}
+
+// inferredFromSchema rebuilds inference state from a schema Write produced;
+// counts are not part of the schema, so every path counts as seen once and
+// optional members as never, which is all merging needs to keep required
+// members required only when they are required on both sides
+func inferredFromSchema(value interface{}) *inferredNode {
+	n := newInferredNode()
+	n.count = 1
+	schema, _ := value.(map[string]interface{})
+	switch types := schema["type"].(type) {
+	case string:
+		n.types = schemaTypeNames[types]
+	case []interface{}:
+		for _, name := range types {
+			name, _ := name.(string)
+			n.types |= schemaTypeNames[name]
+		}
+	}
+	if minimum, ok := schemaNumber(schema, "minimum"); ok {
+		n.minimum = minimum
+	}
+	if maximum, ok := schemaNumber(schema, "maximum"); ok {
+		n.maximum = maximum
+	}
+	if n.types&SCHEMA_TYPE_STRING != 0 {
+		n.formats = 0
+		if name, ok := schema["format"].(string); ok {
+			for _, format := range stringFormatNames {
+				if format.name == name {
+					n.formats = format.format
+				}
+			}
+		}
+		if minLength, ok := schemaNumber(schema, "minLength"); ok {
+			n.minLength = int(minLength)
+		}
+		if maxLength, ok := schemaNumber(schema, "maxLength"); ok {
+			n.maxLength = int(maxLength)
+		}
+	}
+	if n.types&SCHEMA_TYPE_OBJECT != 0 {
+		n.dicts = 1
+		required := map[string]bool{}
+		if list, ok := schema["required"].([]interface{}); ok {
+			for _, key := range list {
+				if key, ok := key.(string); ok {
+					required[key] = true
+				}
+			}
+		}
+		if properties, ok := schema["properties"].(map[string]interface{}); ok {
+			n.properties = map[string]*inferredNode{}
+			for key := range properties {
+				n.keys = append(n.keys, key)
+			}
+			sort.Strings(n.keys)
+			for _, key := range n.keys {
+				child := inferredFromSchema(properties[key])
+				if !required[key] {
+					child.count = 0
+				}
+				n.properties[key] = child
+			}
+		}
+		if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			n.additional = inferredFromSchema(additional)
+		}
+	}
+	if n.types&SCHEMA_TYPE_ARRAY != 0 {
+		if items, ok := schema["items"]; ok {
+			n.items = inferredFromSchema(items)
+		}
+		if minItems, ok := schemaNumber(schema, "minItems"); ok {
+			n.minItems = int(minItems)
+		}
+		if maxItems, ok := schemaNumber(schema, "maxItems"); ok {
+			n.maxItems = int(maxItems)
+		}
+	}
+	return n
+}
+
+// LoadInferredSchema reads back a schema Write produced so it can be merged
+// with schemas inferred from later batches
+func LoadInferredSchema(byteReader io.ByteReader) (InferredSchema, error) {
+	s := NewInferredSchema()
+	document, err := parseGeneric(byteReader)
+	if err != nil {
+		return s, err
+	}
+	s.root = inferredFromSchema(document)
+	if schema, ok := document.(map[string]interface{}); ok {
+		if documents, ok := schemaNumber(schema, INFERRED_DOCUMENTS_KEYWORD); ok {
+			s.Documents = int64(documents)
+		}
+	}
+	return s, nil
+}
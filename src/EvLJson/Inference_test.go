@@ -0,0 +1,133 @@
+package EvLJson
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func inferString(t *testing.T, ndjson string) InferredSchema {
+	schema := NewInferredSchema()
+	if err := schema.ObserveStream(bytes.NewReader([]byte(ndjson))); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+func writeInferred(t *testing.T, schema *InferredSchema) string {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	if err := schema.Write(&w); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+const TEST_INFERENCE_BATCH = `{"id":"9b2e7f1c-3a4d-4e5f-8a9b-0c1d2e3f4a5b","at":"2024-01-02T03:04:05Z","n":1,"tags":["a",1],"owner":null}
+{"id":"0b2e7f1c-3a4d-4e5f-8a9b-0c1d2e3f4a5b","at":"2024-02-02T03:04:05.5+01:00","n":2.5,"tags":[],"owner":{"name":"x"}}
+`
+
+func TestInferSchema(t *testing.T) {
+	schema := inferString(t, TEST_INFERENCE_BATCH)
+	if schema.Documents != 2 {
+		t.Fatal(schema.Documents)
+	}
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","x-documents":2,"type":"object",` +
+		`"properties":{` +
+		`"id":{"type":"string","format":"uuid","minLength":36,"maxLength":36},` +
+		`"at":{"type":"string","format":"date-time","minLength":20,"maxLength":27},` +
+		`"n":{"type":"number","minimum":1,"maximum":2.5},` +
+		`"tags":{"type":"array","items":{"type":["integer","string"],"minimum":1,"maximum":1,"minLength":1,"maxLength":1},"minItems":0,"maxItems":2},` +
+		`"owner":{"type":["null","object"],"properties":{"name":{"type":"string","minLength":1,"maxLength":1}},"required":["name"]}` +
+		`},"required":["id","at","n","tags","owner"]}`
+	if inferred := writeInferred(t, &schema); inferred != expected {
+		t.Fatalf("%s\n!=\n%s", inferred, expected)
+	}
+}
+
+func TestInferSchemaOptionalMembers(t *testing.T) {
+	schema := inferString(t, "{\"a\":\"2024-01-01\",\"b\":1}\n{\"a\":\"2024-01-02\"}\n")
+	inferred := writeInferred(t, &schema)
+	if !strings.Contains(inferred, `"format":"date"`) || !strings.Contains(inferred, `"required":["a"]`) {
+		t.Fatal(inferred)
+	}
+}
+
+func TestInferSchemaMapsCollapse(t *testing.T) {
+	schema := NewInferredSchema()
+	schema.MaxProperties = 2
+	if err := schema.ObserveStream(bytes.NewReader([]byte(`{"k1":1,"k2":2,"k3":3}`))); err != nil {
+		t.Fatal(err)
+	}
+	inferred := writeInferred(t, &schema)
+	if !strings.Contains(inferred, `"additionalProperties":{"type":"integer","minimum":1,"maximum":3}`) {
+		t.Fatal(inferred)
+	}
+}
+
+func TestInferSchemaMerge(t *testing.T) {
+	first := inferString(t, `{"a":1,"b":"x"}`)
+	second := inferString(t, `{"a":5,"c":true}`)
+	firstWritten := writeInferred(t, &first)
+	first.Merge(&second)
+	merged := writeInferred(t, &first)
+
+	// merging the written out schemas must give the same result
+	loadedFirst, err := LoadInferredSchema(bytes.NewReader([]byte(firstWritten)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadedSecond, err := LoadInferredSchema(bytes.NewReader([]byte(writeInferred(t, &second))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadedFirst.Merge(&loadedSecond)
+	reloaded := writeInferred(t, &loadedFirst)
+
+	for _, inferred := range []string{merged, reloaded} {
+		if !strings.Contains(inferred, `"a":{"type":"integer","minimum":1,"maximum":5}`) || !strings.Contains(inferred, `"required":["a"]`) {
+			t.Fatal(inferred)
+		}
+	}
+	if loadedFirst.Documents != 2 || first.Documents != 2 {
+		t.Fatal(loadedFirst.Documents, first.Documents)
+	}
+}
+
+func TestInferSchemaAdjacentDocuments(t *testing.T) {
+	separated := inferString(t, "{\"a\":1}\n{\"b\":2} [3]\t[4]\n")
+	for _, stream := range []string{`{"a":1}{"b":2}[3][4]`, `{"a":1}{"b":2}[3][4] `} {
+		adjacent := inferString(t, stream)
+		if adjacent.Documents != 4 || writeInferred(t, &adjacent) != writeInferred(t, &separated) {
+			t.Fatalf("%q: %d %s", stream, adjacent.Documents, writeInferred(t, &adjacent))
+		}
+	}
+	var schema InferredSchema
+	if err := schema.ObserveStream(strings.NewReader(`[1][2]`)); err != nil || schema.Documents != 2 {
+		t.Fatal(err, schema.Documents)
+	}
+	var empty, merged InferredSchema
+	merged.Merge(&empty)
+	if inferred := writeInferred(t, &empty); inferred != `{"$schema":"https://json-schema.org/draft/2020-12/schema","x-documents":0}` {
+		t.Fatal(inferred)
+	}
+}
+
+func TestInferSchemaWithoutBounds(t *testing.T) {
+	written := `{"$schema":"https://json-schema.org/draft/2020-12/schema","x-documents":1,"type":"object",` +
+		`"properties":{"a":{"type":"number"},"b":{"type":"integer","minimum":2}},"required":["a","b"]}`
+	loaded, err := LoadInferredSchema(strings.NewReader(written))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inferred := writeInferred(t, &loaded); inferred != written {
+		t.Fatalf("%s\n!=\n%s", inferred, written)
+	}
+}
+
+func TestInferSchemaTruncated(t *testing.T) {
+	schema := NewInferredSchema()
+	if err := schema.ObserveStream(bytes.NewReader([]byte("{\"a\":1}\n{\"a\""))); err == nil {
+		t.FailNow()
+	}
+}
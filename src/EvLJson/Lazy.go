@@ -0,0 +1,368 @@
+package EvLJson
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Result is a value found by Get; Raw is its json text, a slice of the
+// input, and is nil when nothing was found
+type Result struct {
+	Kind   valueKind_t
+	Raw    []byte
+	Offset int // where Raw starts in the input
+}
+
+func (r Result) Exists() bool {
+	return r.Raw != nil
+}
+
+// String decodes a string; other values give their json text and missing
+// ones ""
+func (r Result) String() string {
+	if r.Kind != VALUE_STRING {
+		return string(r.Raw)
+	}
+	text := r.Raw[1 : len(r.Raw)-1]
+	if bytes.IndexByte(text, '\\') < 0 {
+		return string(text)
+	}
+	var decoded string
+	if err := decodeValue(r.Raw, StringSink(&decoded)); err != nil {
+		return ""
+	}
+	return decoded
+}
+
+func (r Result) Bool() bool {
+	return r.Kind == VALUE_BOOL && r.Raw[0] == 't'
+}
+
+func (r Result) Float64() (float64, error) {
+	return strconv.ParseFloat(string(r.Raw), 64)
+}
+
+func (r Result) Int64() (int64, error) {
+	return strconv.ParseInt(string(r.Raw), 10, 64)
+}
+
+// Get looks path up below the value, which must be an array or object
+func (r Result) Get(path string) Result {
+	found := Get(r.Raw, path)
+	if found.Exists() {
+		found.Offset += r.Offset
+	}
+	return found
+}
+
+// lazyPath is a trie of the requested paths, so a single pass over the
+// document serves all of them
+type lazyPath struct {
+	segment  string
+	children []*lazyPath
+	results  []int // indices of the paths ending here
+}
+
+func (node *lazyPath) child(segment string) *lazyPath {
+	for _, child := range node.children {
+		if child.segment == segment {
+			return child
+		}
+	}
+	return nil
+}
+
+// splitLazyPath splits on dots; a backslash makes the next byte literal
+func splitLazyPath(path string) []string {
+	var segments []string
+	var segment strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch {
+		case path[i] == '\\' && i+1 < len(path):
+			i++
+			segment.WriteByte(path[i])
+		case path[i] == '.':
+			segments = append(segments, segment.String())
+			segment.Reset()
+		default:
+			segment.WriteByte(path[i])
+		}
+	}
+	return append(segments, segment.String())
+}
+
+type lazyScanner struct {
+	data    []byte
+	results []Result
+}
+
+// forget drops the results of the paths through node, as a repeated key
+// replaces whatever the earlier one led to
+func (s *lazyScanner) forget(node *lazyPath) {
+	for _, result := range node.results {
+		s.results[result] = Result{}
+	}
+	for _, child := range node.children {
+		s.forget(child)
+	}
+}
+
+func (s *lazyScanner) skipWhitespace(i int) int {
+	for i < len(s.data) && isCharWhitespace(s.data[i]) {
+		i++
+	}
+	return i
+}
+
+// skipString returns the index after the closing quote of the string
+// starting at i, or -1
+func (s *lazyScanner) skipString(i int) int {
+	for i++; i < len(s.data); i++ {
+		switch s.data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// skipValue returns the index after the value starting at i, or -1;
+// literals and numbers are checked against the json grammar, containers
+// are skipped by counting brackets outside strings
+func (s *lazyScanner) skipValue(i int) int {
+	if i >= len(s.data) {
+		return -1
+	}
+	switch s.data[i] {
+	case '"':
+		return s.skipString(i)
+	case '[', '{':
+		depth := 0
+		for ; i < len(s.data); i++ {
+			switch s.data[i] {
+			case '"':
+				if i = s.skipString(i); i < 0 {
+					return -1
+				}
+				i--
+			case '[', '{':
+				depth++
+			case ']', '}':
+				if depth--; depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return -1
+	}
+	var end int
+	switch s.data[i] {
+	case 't':
+		end = s.skipLiteral(i, "true")
+	case 'f':
+		end = s.skipLiteral(i, "false")
+	case 'n':
+		end = s.skipLiteral(i, "null")
+	default:
+		end = s.skipNumber(i)
+	}
+	if end < 0 || end < len(s.data) && !isLazyDelimiter(s.data[end]) {
+		return -1
+	}
+	return end
+}
+
+// isLazyDelimiter reports whether b can follow a scalar
+func isLazyDelimiter(b byte) bool {
+	return b == ',' || b == ']' || b == '}' || isCharWhitespace(b)
+}
+
+// skipLiteral returns the index after literal starting at i, or -1
+func (s *lazyScanner) skipLiteral(i int, literal string) int {
+	if !bytes.HasPrefix(s.data[i:], []byte(literal)) {
+		return -1
+	}
+	return i + len(literal)
+}
+
+// skipNumber returns the index after the number starting at i, or -1 when
+// it does not follow the json grammar
+func (s *lazyScanner) skipNumber(i int) int {
+	if i < len(s.data) && s.data[i] == '-' {
+		i++
+	}
+	switch {
+	case i < len(s.data) && s.data[i] == '0':
+		i++
+	case i < len(s.data) && s.data[i] >= '1' && s.data[i] <= '9':
+		i += scanDigitRun(s.data, i)
+	default:
+		return -1
+	}
+	if i < len(s.data) && s.data[i] == '.' {
+		digits := scanDigitRun(s.data, i+1)
+		if digits == 0 {
+			return -1
+		}
+		i += 1 + digits
+	}
+	if i < len(s.data) && (s.data[i] == 'e' || s.data[i] == 'E') {
+		i++
+		if i < len(s.data) && (s.data[i] == '+' || s.data[i] == '-') {
+			i++
+		}
+		digits := scanDigitRun(s.data, i)
+		if digits == 0 {
+			return -1
+		}
+		i += digits
+	}
+	return i
+}
+
+func lazyKind(b byte) valueKind_t {
+	switch b {
+	case '"':
+		return VALUE_STRING
+	case '[':
+		return VALUE_ARRAY
+	case '{':
+		return VALUE_OBJECT
+	case 't', 'f':
+		return VALUE_BOOL
+	case 'n':
+		return VALUE_NULL
+	}
+	return VALUE_NUMBER
+}
+
+// walk visits the value starting at i for the paths through node and
+// returns the index after it, or -1 when the input is malformed
+func (s *lazyScanner) walk(i int, node *lazyPath) int {
+	end := i
+	switch {
+	case len(node.children) == 0:
+		end = s.skipValue(i)
+	case s.data[i] == '{':
+		end = s.walkObject(i, node)
+	case s.data[i] == '[':
+		end = s.walkArray(i, node)
+	default:
+		end = s.skipValue(i)
+	}
+	if end < 0 {
+		return -1
+	}
+	for _, result := range node.results {
+		s.results[result] = Result{lazyKind(s.data[i]), s.data[i:end:end], i}
+	}
+	return end
+}
+
+func (s *lazyScanner) walkObject(i int, node *lazyPath) int {
+	for i = s.skipWhitespace(i + 1); i < len(s.data) && s.data[i] != '}'; {
+		if s.data[i] != '"' {
+			return -1
+		}
+		keyStart, keyEnd := i, s.skipString(i)
+		if keyEnd < 0 {
+			return -1
+		}
+		key := s.data[keyStart+1 : keyEnd-1]
+		i = s.skipWhitespace(keyEnd)
+		if i >= len(s.data) || s.data[i] != ':' {
+			return -1
+		}
+		i = s.skipWhitespace(i + 1)
+		if i >= len(s.data) {
+			return -1
+		}
+		var child *lazyPath
+		if bytes.IndexByte(key, '\\') < 0 {
+			child = node.child(string(key))
+		} else {
+			child = node.child(Result{VALUE_STRING, s.data[keyStart:keyEnd], keyStart}.String())
+		}
+		if child != nil {
+			s.forget(child)
+			i = s.walk(i, child)
+		} else {
+			i = s.skipValue(i)
+		}
+		if i < 0 {
+			return -1
+		}
+		if i = s.skipWhitespace(i); i < len(s.data) && s.data[i] == ',' {
+			i = s.skipWhitespace(i + 1)
+		}
+	}
+	if i >= len(s.data) {
+		return -1
+	}
+	return i + 1
+}
+
+func (s *lazyScanner) walkArray(i int, node *lazyPath) int {
+	var index []byte
+	for i, n := s.skipWhitespace(i+1), 0; i < len(s.data); n++ {
+		if s.data[i] == ']' {
+			return i + 1
+		}
+		index = strconv.AppendInt(index[:0], int64(n), 10)
+		if child := node.child(string(index)); child != nil {
+			i = s.walk(i, child)
+		} else {
+			i = s.skipValue(i)
+		}
+		if i < 0 {
+			return -1
+		}
+		if i = s.skipWhitespace(i); i < len(s.data) && s.data[i] == ',' {
+			i = s.skipWhitespace(i + 1)
+		}
+	}
+	return -1
+}
+
+// GetMany looks up every path in a single pass over data, decoding nothing
+// and skipping the subtrees no path leads into
+//
+// Paths are member names and array indices separated by dots, with a
+// backslash escaping a literal dot or backslash; the last of repeated keys
+// is used, as everywhere else in the package, so the objects along the
+// paths are read to their end. A document that is cut short or whose
+// structure is malformed where it is read gives no results, as does a
+// malformed literal or number outside the skipped containers, which are
+// only checked as far as finding their end needs
+func GetMany(data []byte, paths ...string) []Result {
+	root := &lazyPath{}
+	for index, path := range paths {
+		node := root
+		if path != "" {
+			for _, segment := range splitLazyPath(path) {
+				child := node.child(segment)
+				if child == nil {
+					child = &lazyPath{segment: segment}
+					node.children = append(node.children, child)
+				}
+				node = child
+			}
+		}
+		node.results = append(node.results, index)
+	}
+	s := lazyScanner{data: data, results: make([]Result, len(paths))}
+	if start := s.skipWhitespace(0); start < len(data) && len(paths) != 0 {
+		if s.walk(start, root) < 0 {
+			clear(s.results)
+		}
+	}
+	return s.results
+}
+
+// Get looks up a single path, see GetMany
+func Get(data []byte, path string) Result {
+	return GetMany(data, path)[0]
+}
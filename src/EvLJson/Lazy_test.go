@@ -0,0 +1,131 @@
+package EvLJson
+
+import (
+	"strings"
+	"testing"
+)
+
+const TEST_LAZY_DOC = `{
+	"skip": {"deep": ["}", "\"]", {"user": "decoy"}]},
+	"user": {"name": "first", "age": 1},
+	"user": {
+		"name": "Jörg \"JJ\"",
+		"age": 37,
+		"score": -1.5e2,
+		"active": true,
+		"nick": null,
+		"tags": ["a", "b", ["c", "d"]],
+		"address": {"city": "Berlin", "zip.code": "10115"}
+	},
+	"a\"b": 1
+}`
+
+func TestLazyGet(t *testing.T) {
+	data := []byte(TEST_LAZY_DOC)
+	if city := Get(data, "user.address.city"); city.String() != "Berlin" || city.Kind != VALUE_STRING {
+		t.Fatalf("%+v", city)
+	}
+	if name := Get(data, "user.name"); name.String() != `Jörg "JJ"` {
+		t.Fatalf("%q", name.String())
+	}
+	if age, err := Get(data, "user.age").Int64(); err != nil || age != 37 {
+		t.Fatal(age, err)
+	}
+	if score, err := Get(data, "user.score").Float64(); err != nil || score != -150 {
+		t.Fatal(score, err)
+	}
+	if !Get(data, "user.active").Bool() || Get(data, "user.nick").Kind != VALUE_NULL {
+		t.Fatal("literals")
+	}
+	if tag := Get(data, "user.tags.2.1"); tag.String() != "d" {
+		t.Fatalf("%+v", tag)
+	}
+	if zip := Get(data, `user.address.zip\.code`); zip.String() != "10115" {
+		t.Fatalf("%+v", zip)
+	}
+	if quoted := Get(data, `a"b`); quoted.String() != "1" {
+		t.Fatalf("%+v", quoted)
+	}
+	address := Get(data, "user.address")
+	if address.Kind != VALUE_OBJECT || address.Get("city").String() != "Berlin" {
+		t.Fatalf("%s", address.Raw)
+	}
+	if city := address.Get("city"); string(data[city.Offset:city.Offset+len(city.Raw)]) != `"Berlin"` {
+		t.Fatal(city.Offset)
+	}
+	for _, path := range []string{"missing", "user.tags.3", "user.age.x", "user.tags.x", "skip.deep.2.user.x"} {
+		if found := Get(data, path); found.Exists() {
+			t.Fatalf("%q: %+v", path, found)
+		}
+	}
+}
+
+func TestLazyGetMany(t *testing.T) {
+	results := GetMany([]byte(TEST_LAZY_DOC), "user.age", "skip.deep.1", "user.address.city", "user.age", "nope", "")
+	expected := []string{"37", `"]`, "Berlin", "37", "", TEST_LAZY_DOC}
+	for i, result := range results {
+		if result.String() != expected[i] {
+			t.Fatalf("%d: %q", i, result.String())
+		}
+	}
+}
+
+func TestLazyDuplicateKeys(t *testing.T) {
+	data := []byte(`{"a": {"b": 1, "b": 2, "c": [0]}, "x": [], "a": {"c": [3, 4], "c": [5]}}`)
+	results := GetMany(data, "a.b", "a.c.0", "a.c.1", "a")
+	if results[0].Exists() || results[1].String() != "5" || results[2].Exists() || results[3].Get("c.0").String() != "5" {
+		t.Fatalf("%+v", results)
+	}
+	tape, err := ParseToTape(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := tape.Root().Get("a").Get("c").Index(0); value.Text() != results[1].String() {
+		t.Fatal(value.Text())
+	}
+}
+
+func TestLazyMalformed(t *testing.T) {
+	for _, doc := range []string{``, `{"a": `, `{"a" 1}`, `{"a": [`, `{"a": [,1]}`, `{"a": "x`, `{1: 2}`, `{"a": [1, 2]`, `{"a": [1]`, `{"a": [1], "b"`} {
+		if found := Get([]byte(doc), "a.0"); found.Exists() {
+			t.Fatalf("%q: %+v", doc, found)
+		}
+	}
+	if found := Get([]byte("[1,2"), "1"); found.Exists() {
+		t.Fatalf("%+v", found)
+	}
+}
+
+func TestLazyMalformedScalars(t *testing.T) {
+	for _, value := range []string{`tru`, `truex`, `nul`, `falsey`, `t`, `01`, `-`, `-01`, `1.`, `.5`, `+1`, `1e`, `1E+`, `1.5e+-2`, `0x10`, `1-2`, `NaN`} {
+		for _, doc := range []string{`{"a": ` + value + `}`, `{"b": ` + value + `, "a": 1}`, `[` + value + `, 1]`} {
+			if found := GetMany([]byte(doc), "a", "1"); found[0].Exists() || found[1].Exists() {
+				t.Fatalf("%q: %+v", doc, found)
+			}
+		}
+	}
+	for _, value := range []string{`true`, `false`, `null`, `0`, `-0`, `-0.5E+10`, `12e3`, `1.25`} {
+		doc := `{"a": ` + value + `, "b":` + value + `}`
+		if found := Get([]byte(doc), "a"); found.String() != value {
+			t.Fatalf("%q: %+v", doc, found)
+		}
+	}
+}
+
+func BenchmarkLazyGet(b *testing.B) {
+	var doc strings.Builder
+	doc.WriteString(`{"padding": [`)
+	for i := 0; i < 500; i++ {
+		doc.WriteString(`{"id": 1, "text": "some \"quoted\" text", "nested": [[1], {"k": "v"}]}, `)
+	}
+	doc.WriteString(`0], "user": {"address": {"city": "Berlin"}, "id": 7}}`)
+	data := []byte(doc.String())
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		results := GetMany(data, "user.address.city", "user.id", "padding.3.id")
+		if !results[0].Exists() || !results[1].Exists() || !results[2].Exists() {
+			b.Fatal("missing results")
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package EvLJson
+
+import (
+	"bytes"
+	"io"
+	"iter"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// NDJSON_CHUNK_SIZE is how much of the input NdjsonPool hands to a worker
+// at once, grown to hold a longer line
+const NDJSON_CHUNK_SIZE = 1 << 20
+
+// NDJSON_PARSER_OPTIONS are the options of the parsers records are given
+// with: one document per line, any whitespace around it
+const NDJSON_PARSER_OPTIONS = OPT_ALLOW_EXTRA_WHITESPACE | OPT_PARSE_UNTIL_EOF
+
+var ndjsonParsers = Pool{Options: NDJSON_PARSER_OPTIONS}
+
+// NdjsonRecord is the result of work on the record at Line, counted from 1
+type NdjsonRecord[R any] struct {
+	Line  int64
+	Value R
+}
+
+// NdjsonError is an error of the record at Line
+type NdjsonError struct {
+	Line int64
+	Err  error
+}
+
+func (err NdjsonError) Error() string {
+	return "line " + strconv.FormatInt(err.Line, 10) + ": " + err.Err.Error()
+}
+
+func (err NdjsonError) Unwrap() error {
+	return err.Err
+}
+
+type ndjsonChunk struct {
+	index  int
+	line   int64 // of the first byte
+	data   []byte
+	buffer []byte
+}
+
+type ndjsonResult[R any] struct {
+	record NdjsonRecord[R]
+	err    error
+}
+
+type ndjsonResults[R any] struct {
+	index   int
+	results []ndjsonResult[R]
+	err     error // reading failed, which ends the sequence
+}
+
+// ndjsonReader splits the input into chunks of whole lines, recycling the
+// buffers of chunks the workers are done with
+type ndjsonReader struct {
+	reader    io.Reader
+	chunkSize int
+	free      chan []byte
+	carry     []byte
+	line      int64
+	index     int
+	err       error
+}
+
+func (r *ndjsonReader) buffer(size int) []byte {
+	select {
+	case buffer := <-r.free:
+		if cap(buffer) >= size {
+			return buffer[:0]
+		}
+	default:
+	}
+	return make([]byte, 0, size)
+}
+
+// next is the next chunk, false once the input is exhausted or failed;
+// the lines read before a failure are still handed out
+func (r *ndjsonReader) next() (ndjsonChunk, bool) {
+	buffer := append(r.buffer(r.chunkSize), r.carry...)
+	for r.err == nil {
+		if len(buffer) == cap(buffer) {
+			if bytes.LastIndexByte(buffer, '\n') >= 0 {
+				break
+			}
+			// a line longer than the chunk
+			buffer = append(buffer, 0)[:len(buffer)]
+		}
+		var n int
+		n, r.err = r.reader.Read(buffer[len(buffer):cap(buffer)])
+		buffer = buffer[:len(buffer)+n]
+	}
+	split := bytes.LastIndexByte(buffer, '\n') + 1
+	if r.err == io.EOF {
+		// the last line needs no newline
+		split = len(buffer)
+	}
+	r.carry = append(r.carry[:0], buffer[split:]...)
+	if split == 0 {
+		return ndjsonChunk{}, false
+	}
+	chunk := ndjsonChunk{index: r.index, line: r.line, data: buffer[:split], buffer: buffer}
+	r.index++
+	r.line += int64(bytes.Count(chunk.data, []byte{'\n'}))
+	return chunk, true
+}
+
+// ndjsonWork runs work on the records of chunk with p
+func ndjsonWork[R any](p *Parser, chunk ndjsonChunk, work func(p *Parser, record []byte) (R, error)) []ndjsonResult[R] {
+	var results []ndjsonResult[R]
+	line := chunk.line
+	for data := chunk.data; len(data) != 0; line++ {
+		record := data
+		if end := bytes.IndexByte(data, '\n'); end >= 0 {
+			record, data = data[:end], data[end+1:]
+		} else {
+			data = nil
+		}
+		if scanWhitespaceRun(record, 0) == len(record) {
+			continue
+		}
+		value, err := work(p, record)
+		p.Reset()
+		if err != nil {
+			err = NdjsonError{line + 1, err}
+		}
+		results = append(results, ndjsonResult[R]{NdjsonRecord[R]{line + 1, value}, err})
+	}
+	return results
+}
+
+// NdjsonPool runs work on each record of newline delimited json with a
+// pool of workers goroutines, GOMAXPROCS for fewer than one, yielding the
+// results in input order when ordered is set and as chunks of the input
+// complete otherwise; blank lines are skipped
+//
+// Records are handed to work with a reset parser of NDJSON_PARSER_OPTIONS
+// owned by the worker, and are only valid during the call. The input is
+// read a chunk of NDJSON_CHUNK_SIZE at a time and at most 2*workers chunks
+// are in flight, read but not yet yielded, so memory stays bounded and a
+// slow consumer holds the reading back. An error from work is yielded as
+// an NdjsonError with the record and the sequence goes on, while a read
+// error is yielded after every earlier record and ends it
+func NdjsonPool[R any](reader io.Reader, workers int, ordered bool, work func(p *Parser, record []byte) (R, error)) iter.Seq2[NdjsonRecord[R], error] {
+	return ndjsonPool(reader, workers, ordered, NDJSON_CHUNK_SIZE, work)
+}
+
+func ndjsonPool[R any](reader io.Reader, workers int, ordered bool, chunkSize int, work func(p *Parser, record []byte) (R, error)) iter.Seq2[NdjsonRecord[R], error] {
+	return func(yield func(NdjsonRecord[R], error) bool) {
+		if workers < 1 {
+			workers = runtime.GOMAXPROCS(0)
+		}
+		chunks := make(chan ndjsonChunk)
+		results := make(chan ndjsonResults[R], workers)
+		window := make(chan struct{}, 2*workers)
+		done := make(chan struct{})
+		input := &ndjsonReader{reader: reader, chunkSize: chunkSize, free: make(chan []byte, 2*workers)}
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(chunks)
+			for {
+				select {
+				case window <- struct{}{}:
+				case <-done:
+					return
+				}
+				chunk, ok := input.next()
+				if !ok {
+					if input.err != io.EOF {
+						select {
+						case results <- ndjsonResults[R]{index: input.index, err: input.err}:
+						case <-done:
+						}
+					}
+					return
+				}
+				select {
+				case chunks <- chunk:
+				case <-done:
+					return
+				}
+			}
+		}()
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p := ndjsonParsers.Get()
+				defer ndjsonParsers.Put(p)
+				for chunk := range chunks {
+					chunkResults := ndjsonWork(p, chunk, work)
+					select {
+					case input.free <- chunk.buffer:
+					default:
+					}
+					select {
+					case results <- ndjsonResults[R]{index: chunk.index, results: chunkResults}:
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+		defer func() {
+			close(done)
+			for range results {
+			}
+		}()
+
+		next := 0 // chunks yielded so far, and in order mode the next index due
+		held := map[int]ndjsonResults[R]{}
+		emit := func(chunk ndjsonResults[R]) bool {
+			next++
+			<-window
+			for _, result := range chunk.results {
+				if !yield(result.record, result.err) {
+					return false
+				}
+			}
+			return true
+		}
+		var final *ndjsonResults[R]
+		for chunk := range results {
+			switch {
+			case chunk.err != nil:
+				final = &chunk
+			case ordered:
+				held[chunk.index] = chunk
+			default:
+				if !emit(chunk) {
+					return
+				}
+			}
+			for ordered {
+				due, exists := held[next]
+				if !exists {
+					break
+				}
+				delete(held, next)
+				if !emit(due) {
+					return
+				}
+			}
+			if final != nil && next == final.index {
+				yield(NdjsonRecord[R]{Line: input.line + 1}, final.err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package EvLJson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// ndjsonTestInput has records of growing length, blank lines, a record
+// ending in \r and invalid records; the last line has no newline
+func ndjsonTestInput(records int) string {
+	var lines []string
+	for i := 0; i < records; i++ {
+		switch i % 10 {
+		case 3:
+			lines = append(lines, "", "  \t")
+		case 5:
+			lines = append(lines, `{"id": `+strconv.Itoa(i)+`}`+"\r")
+		case 7:
+			lines = append(lines, `{"id": `+strconv.Itoa(i)+`, "bad"}`)
+		default:
+			lines = append(lines, `{"id": `+strconv.Itoa(i)+`, "s": "`+strings.Repeat("x", i%100)+`", "a": [1, 2.5, null]}`)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ndjsonTestWork counts the events of a record
+func ndjsonTestWork(p *Parser, record []byte) (int, error) {
+	events := 0
+	err := p.ParseBytes(record, func(*Parser, event_t) { events++ }, nil)
+	return events, err
+}
+
+// ndjsonExpected is what a sequential parse of input yields
+func ndjsonExpected(input string) []string {
+	var expected []string
+	for i, line := range strings.Split(input, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		p := NewParser(nil, nil, NDJSON_PARSER_OPTIONS)
+		events, err := ndjsonTestWork(&p, []byte(line))
+		expected = append(expected, ndjsonResultString(int64(i+1), events, err))
+	}
+	return expected
+}
+
+func ndjsonResultString(line int64, events int, err error) string {
+	return strconv.FormatInt(line, 10) + " " + strconv.Itoa(events) + " " + strconv.FormatBool(err != nil)
+}
+
+func collectNdjson(t *testing.T, reader io.Reader, workers int, ordered bool, chunkSize int) []string {
+	var results []string
+	for record, err := range ndjsonPool(reader, workers, ordered, chunkSize, ndjsonTestWork) {
+		var lineErr NdjsonError
+		if err != nil && (!errors.As(err, &lineErr) || lineErr.Line != record.Line) {
+			t.Fatalf("%d: %v", record.Line, err)
+		}
+		results = append(results, ndjsonResultString(record.Line, record.Value, err))
+	}
+	return results
+}
+
+func compareNdjson(t *testing.T, results []string, expected []string, ordered bool) {
+	if !ordered {
+		lineOf := func(s string) int {
+			line, _ := strconv.Atoi(strings.Fields(s)[0])
+			return line
+		}
+		sort.Slice(results, func(i, j int) bool { return lineOf(results[i]) < lineOf(results[j]) })
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("%d results != %d", len(results), len(expected))
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Fatalf("%s != %s", results[i], expected[i])
+		}
+	}
+}
+
+func TestNdjsonPool(t *testing.T) {
+	input := ndjsonTestInput(500)
+	expected := ndjsonExpected(input)
+	for _, chunkSize := range []int{1, 7, 256, NDJSON_CHUNK_SIZE} {
+		for _, workers := range []int{0, 1, 4} {
+			for _, ordered := range []bool{true, false} {
+				compareNdjson(t, collectNdjson(t, strings.NewReader(input), workers, ordered, chunkSize), expected, ordered)
+			}
+		}
+	}
+	for _, reader := range []io.Reader{iotest.OneByteReader(strings.NewReader(input)), iotest.HalfReader(strings.NewReader(input))} {
+		compareNdjson(t, collectNdjson(t, reader, 3, true, 64), expected, true)
+	}
+	if results := collectNdjson(t, strings.NewReader("\n \n"), 2, true, 64); len(results) != 0 {
+		t.Fatal(results)
+	}
+}
+
+func TestNdjsonPoolReadError(t *testing.T) {
+	input := ndjsonTestInput(100)
+	failure := errors.New("read failure")
+	cut := strings.LastIndexByte(input[:len(input)/2], '\n') + 5
+	expected := ndjsonExpected(input[:cut-5])
+	for _, ordered := range []bool{true, false} {
+		var results []string
+		var last error
+		for record, err := range ndjsonPool(io.MultiReader(strings.NewReader(input[:cut]), iotest.ErrReader(failure)), 4, ordered, 128, ndjsonTestWork) {
+			if last != nil {
+				t.Fatal("results after the read error")
+			}
+			if err == failure {
+				last = err
+				continue
+			}
+			results = append(results, ndjsonResultString(record.Line, record.Value, err))
+		}
+		if last != failure {
+			t.Fatal(last)
+		}
+		compareNdjson(t, results, expected, ordered)
+	}
+}
+
+// countingReader counts the bytes read from it
+type countingReader struct {
+	reader io.Reader
+	read   atomic.Int64
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	r.read.Add(int64(n))
+	return n, err
+}
+
+func TestNdjsonPoolBackpressure(t *testing.T) {
+	input := strings.Repeat(ndjsonTestInput(100)+"\n", 50)
+	const workers, chunkSize = 2, 512
+	reader := &countingReader{reader: strings.NewReader(input)}
+	goroutines := runtime.NumGoroutine()
+	results := 0
+	for range ndjsonPool(reader, workers, true, chunkSize, ndjsonTestWork) {
+		if results++; results == 1 {
+			time.Sleep(50 * time.Millisecond)
+			// the chunks in the window, one being read and the one the
+			// first result came from
+			if read := reader.read.Load(); read > (2*workers+2)*chunkSize {
+				t.Fatalf("%d bytes read ahead", read)
+			}
+		}
+		if results == 10 {
+			break
+		}
+	}
+	for i := 0; runtime.NumGoroutine() > goroutines; i++ {
+		if i == 100 {
+			t.Fatalf("%d goroutines left", runtime.NumGoroutine()-goroutines)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func BenchmarkNdjsonPool(b *testing.B) {
+	input := []byte(strings.Repeat(ndjsonTestInput(1000)+"\n", 100))
+	for _, workers := range []int{1, runtime.GOMAXPROCS(0)} {
+		b.Run("workers="+strconv.Itoa(workers), func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			for i := 0; i < b.N; i++ {
+				for range NdjsonPool(bytes.NewReader(input), workers, true, ndjsonTestWork) {
+				}
+			}
+		})
+	}
+}
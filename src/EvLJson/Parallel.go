@@ -0,0 +1,366 @@
+package EvLJson
+
+import (
+	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// PARALLEL_CHUNK_SIZE is the size of the ranges ParseBytesBatchedParallel
+// cuts its input into
+const PARALLEL_CHUNK_SIZE = 1 << 20
+
+// arrayScan is a pass over a range of the input counting nesting, from the
+// string state speculated for its start
+type arrayScan struct {
+	inString    bool
+	endInString bool
+	depth       int
+}
+
+// parallelStats counts how often speculation went wrong
+type parallelStats struct {
+	chunks       int
+	rescans      int // ranges whose string state was misspeculated
+	fallbackFrom int // chunk parsing went sequential from, -1 for none
+}
+
+// backslashesBefore is the length of the run of backslashes ending at i
+func backslashesBefore(data []byte, i int) int {
+	n := 0
+	for ; i-n > 0 && data[i-n-1] == '\\'; n++ {
+	}
+	return n
+}
+
+// speculateInString guesses whether data[start] is inside a string from
+// the first quote at or after it: an escaped quote or one followed by
+// what follows the end of a string closes one, other quotes open one
+func speculateInString(data []byte, start int, end int) bool {
+	for i := start; i < end; i++ {
+		if data[i] != '"' {
+			continue
+		}
+		if backslashesBefore(data, i)%2 == 1 {
+			return true
+		}
+		for i++; i < len(data) && isCharWhitespace(data[i]); i++ {
+		}
+		if i == len(data) {
+			return true
+		}
+		switch data[i] {
+		case ',', ':', ']', '}':
+			return true
+		}
+		return false
+	}
+	// no quote at all, most likely outside of any string
+	return false
+}
+
+// scanArrayRange counts the nesting over data[start:end] outside strings,
+// which data[start] is inside of when inString; escapes are known exactly
+// from the backslashes before start
+func scanArrayRange(data []byte, start int, end int, inString bool) arrayScan {
+	scan := arrayScan{inString: inString}
+	escaped := inString && backslashesBefore(data, start)%2 == 1
+	for i := start; i < end; i++ {
+		b := data[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+		switch b {
+		case '"':
+			inString = true
+		case '[', '{':
+			scan.depth++
+		case ']', '}':
+			scan.depth--
+		}
+	}
+	scan.endInString = inString
+	return scan
+}
+
+// findArraySplit is the index after the first comma of the top level
+// array in data[start:end], given the exact string state and nesting at
+// start, or -1
+func findArraySplit(data []byte, start int, end int, inString bool, depth int) int {
+	escaped := inString && backslashesBefore(data, start)%2 == 1
+	for i := start; i < end; i++ {
+		b := data[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+		switch b {
+		case '"':
+			inString = true
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ',':
+			if depth == 1 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// parallelFor runs work for 0 up to n on workers goroutines
+func parallelFor(n int, workers int, work func(i int)) {
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int(next.Add(1) - 1); i < n; i = int(next.Add(1) - 1) {
+				work(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// arraySplits cuts data holding a top level array into chunks of whole
+// items at about chunkSize, returning the start of each chunk
+func arraySplits(data []byte, workers int, chunkSize int, stats *parallelStats) []int {
+	ranges := (len(data) + chunkSize - 1) / chunkSize
+	rangeEnd := func(i int) int {
+		return min((i+1)*chunkSize, len(data))
+	}
+	scans := make([]arrayScan, ranges)
+	parallelFor(ranges, workers, func(i int) {
+		inString := i != 0 && speculateInString(data, i*chunkSize, rangeEnd(i))
+		scans[i] = scanArrayRange(data, i*chunkSize, rangeEnd(i), inString)
+	})
+	depths := make([]int, ranges)
+	inString, depth := false, 0
+	for i := range scans {
+		if scans[i].inString != inString {
+			scans[i] = scanArrayRange(data, i*chunkSize, rangeEnd(i), inString)
+			stats.rescans++
+		}
+		depths[i] = depth
+		inString, depth = scans[i].endInString, depth+scans[i].depth
+	}
+	splits := make([]int, ranges)
+	parallelFor(ranges-1, workers, func(i int) {
+		i++
+		splits[i] = findArraySplit(data, i*chunkSize, rangeEnd(i), scans[i].inString, depths[i])
+	})
+	starts := []int{0}
+	for _, split := range splits[1:] {
+		if split >= 0 {
+			starts = append(starts, split)
+		}
+	}
+	return starts
+}
+
+// parallelChunk is the parse of one chunk of the array, its events
+// recorded with their arena data
+type parallelChunk struct {
+	index  int
+	events []BatchEvent
+	arena  []byte
+	ok     bool // the chunk ended back in the top level array
+}
+
+// parseArrayChunk records the events of data[start:end] parsed with w in
+// batches of events, which has to end where the next chunk resumes
+func parseArrayChunk(w *Parser, data []byte, start int, end int, events []BatchEvent, chunk *parallelChunk) {
+	handle := handle_t(HANDLE_ARRAY_EXPECT_ENTRY)
+	if start == 0 {
+		handle = HANDLE_START
+	}
+	w.Reset()
+	chunk.events, chunk.arena = chunk.events[:0], chunk.arena[:0]
+	err := w.parseBytesBatchedFrom(data[:end], start, handle, events, func(w *Parser, events []BatchEvent) {
+		recorded := len(chunk.events)
+		chunk.events = append(chunk.events, events...)
+		for i := recorded; i < len(chunk.events); i++ {
+			if event := &chunk.events[i]; event.InArena {
+				span := w.BatchData[event.Start:event.End]
+				event.Start, event.End = len(chunk.arena), len(chunk.arena)+len(span)
+				chunk.arena = append(chunk.arena, span...)
+			}
+		}
+	})
+	if end == len(data) {
+		chunk.ok = err == nil
+	} else {
+		chunk.ok = err == io.EOF && len(w.ContextStack) == 1 && w.ContextStack[0] == HANDLE_END
+	}
+}
+
+// deliverChunk hands the events of chunk to onBatch a batch at a time;
+// chunks are not mixed in a batch as each has its own arena
+func (p *Parser) deliverChunk(chunk *parallelChunk, events []BatchEvent, onBatch batchReceiver_t) {
+	own := p.BatchData
+	p.BatchData = chunk.arena
+	for recorded := chunk.events; len(recorded) != 0 && p.userSignal != SIG_STOP; {
+		n := copy(events[:cap(events)], recorded)
+		recorded = recorded[n:]
+		onBatch(p, events[:n])
+	}
+	p.BatchData = own
+}
+
+// ParseBytesBatchedParallel is ParseBytesBatched for a document whose top
+// level is an array, parsing its items on workers goroutines, GOMAXPROCS
+// for fewer than one; any other document is parsed sequentially
+//
+// The input is cut into ranges of PARALLEL_CHUNK_SIZE whose string state
+// is speculated from the quotes near their start, which lets them all be
+// scanned for nesting at once; a range speculated wrong is scanned again
+// once the range before it is known. The chunks of whole items found this
+// way are parsed concurrently and their events handed to onBatch in order,
+// so onBatch sees what ParseBytesBatched would have recorded except that
+// batches end with each chunk. Should a chunk not parse into whole items,
+// as with a document in error, parsing goes on sequentially from it
+func (p *Parser) ParseBytesBatchedParallel(data []byte, workers int, events []BatchEvent, onBatch batchReceiver_t) error {
+	_, err := p.parseBytesBatchedParallel(data, workers, PARALLEL_CHUNK_SIZE, events, onBatch)
+	return err
+}
+
+func (p *Parser) parseBytesBatchedParallel(data []byte, workers int, chunkSize int, events []BatchEvent, onBatch batchReceiver_t) (parallelStats, error) {
+	stats := parallelStats{fallbackFrom: -1}
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	first := scanWhitespaceRun(data, 0)
+	if len(data) < 2*chunkSize || first == len(data) || data[first] != '[' {
+		return stats, p.ParseBytesBatched(data, events, onBatch)
+	}
+	if cap(events) == 0 {
+		events = make([]BatchEvent, 0, DEFAULT_BATCH_SIZE)
+	}
+	starts := arraySplits(data, workers, chunkSize, &stats)
+	stats.chunks = len(starts)
+	chunkEnd := func(i int) int {
+		if i+1 < len(starts) {
+			return starts[i+1]
+		}
+		return len(data)
+	}
+
+	indexes := make(chan int)
+	results := make(chan *parallelChunk, workers)
+	free := make(chan *parallelChunk, 2*workers)
+	window := make(chan struct{}, 2*workers)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(indexes)
+		for i := range starts {
+			select {
+			case window <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case indexes <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+	// the workers must not read p, which the fallback goes on parsing with
+	bufferSize, options := cap(p.DataBuffer), p.options
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker := NewParser(make([]byte, 0, bufferSize), nil, options)
+			batch := make([]BatchEvent, 0, 4*DEFAULT_BATCH_SIZE)
+			for i := range indexes {
+				var chunk *parallelChunk
+				select {
+				case chunk = <-free:
+				default:
+					chunk = &parallelChunk{}
+				}
+				chunk.index = i
+				parseArrayChunk(&worker, data, starts[i], chunkEnd(i), batch, chunk)
+				select {
+				case results <- chunk:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	stopped := false
+	stop := func() {
+		if !stopped {
+			stopped = true
+			close(done)
+		}
+	}
+	defer func() {
+		stop()
+		for range results {
+		}
+	}()
+
+	p.beginInput(data)
+	next := 0
+	held := map[int]*parallelChunk{}
+	for chunk := range results {
+		held[chunk.index] = chunk
+		for due := held[next]; due != nil; due = held[next] {
+			delete(held, next)
+			if !due.ok {
+				// the chunks still to come are of no use now
+				stop()
+				p.endInput()
+				stats.fallbackFrom = next
+				handle := handle_t(HANDLE_ARRAY_EXPECT_ENTRY)
+				if next == 0 {
+					handle = HANDLE_START
+				}
+				return stats, p.parseBytesBatchedFrom(data, starts[next], handle, events, onBatch)
+			}
+			p.deliverChunk(due, events, onBatch)
+			select {
+			case free <- due:
+			default:
+			}
+			if p.userSignal == SIG_STOP {
+				p.endInput()
+				return stats, nil
+			}
+			next++
+			<-window
+		}
+	}
+	p.endInput()
+	return stats, nil
+}
@@ -0,0 +1,158 @@
+package EvLJson
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+// parallelTestDoc is an array of items whose strings look like the
+// structure around them, to mislead the speculation
+func parallelTestDoc(items int) string {
+	tricky := []string{`, \", \"`, `\", [`, `\\`, `]}\\\"`, `{\"a\": [1, 2]}`, `,`, `: [{`, `x\\\", \"y`}
+	var b strings.Builder
+	b.WriteString(" [")
+	for i := 0; i < items; i++ {
+		if i != 0 {
+			b.WriteString(", ")
+		}
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&b, `{"id": %d, "s": "%s", "n": [%d.5e-3, -0, {"k": []}]}`, i, tricky[i%len(tricky)], i)
+		case 1:
+			fmt.Fprintf(&b, `"%s%s"`, strings.Repeat("x", i%13), tricky[i%len(tricky)])
+		case 2:
+			fmt.Fprintf(&b, `[[%d], {"%s": null}, true]`, i, tricky[i%len(tricky)])
+		default:
+			fmt.Fprintf(&b, `%d`, i*7919)
+		}
+	}
+	b.WriteString("]\n")
+	return b.String()
+}
+
+// collectBatchStream records events with their data as text
+func collectBatchStream(run func(onBatch batchReceiver_t) error) ([]string, error) {
+	var records []string
+	err := run(func(p *Parser, events []BatchEvent) {
+		for _, event := range events {
+			data := p.BatchBytes(event)
+			if !event.InArena {
+				records = append(records, fmt.Sprintf("%d %v %d %q @%d", event.Kind, event.Key, event.Offset, data, event.Start))
+			} else {
+				records = append(records, fmt.Sprintf("%d %v %d %q", event.Kind, event.Key, event.Offset, data))
+			}
+		}
+	})
+	return records, err
+}
+
+func compareParallel(t *testing.T, doc string, options uint8, workers int, chunkSize int) parallelStats {
+	p := NewParser(nil, nil, options)
+	expected, expectedErr := collectBatchStream(func(onBatch batchReceiver_t) error {
+		return p.ParseBytesBatched([]byte(doc), nil, onBatch)
+	})
+	var stats parallelStats
+	p = NewParser(nil, nil, options)
+	records, err := collectBatchStream(func(onBatch batchReceiver_t) error {
+		var err error
+		stats, err = p.parseBytesBatchedParallel([]byte(doc), workers, chunkSize, make([]BatchEvent, 5), onBatch)
+		return err
+	})
+	if err != expectedErr {
+		t.Fatalf("chunk size %d: %v != %v", chunkSize, err, expectedErr)
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("chunk size %d: %d records != %d, %+v", chunkSize, len(records), len(expected), stats)
+	}
+	for i := range expected {
+		if records[i] != expected[i] {
+			t.Fatalf("chunk size %d: %s != %s", chunkSize, records[i], expected[i])
+		}
+	}
+	return stats
+}
+
+func TestParallelMatchesSequential(t *testing.T) {
+	doc := parallelTestDoc(300)
+	rescans, chunks := 0, 0
+	for chunkSize := 8; chunkSize < 300; chunkSize += 7 {
+		for _, workers := range []int{1, 4} {
+			stats := compareParallel(t, doc, OPT_ALLOW_EXTRA_WHITESPACE, workers, chunkSize)
+			if stats.fallbackFrom != -1 {
+				t.Fatalf("chunk size %d: fell back from chunk %d", chunkSize, stats.fallbackFrom)
+			}
+			rescans, chunks = rescans+stats.rescans, chunks+stats.chunks
+		}
+	}
+	if rescans == 0 || chunks < 1000 {
+		t.Fatalf("%d rescans of %d chunks", rescans, chunks)
+	}
+	compareParallel(t, doc, OPT_ALLOW_EXTRA_WHITESPACE|OPT_DECODE_UNICODE_ESCAPES|OPT_PARSE_UNTIL_EOF, 3, 64)
+}
+
+func TestParallelFallback(t *testing.T) {
+	doc := parallelTestDoc(100)
+	broken := []string{
+		strings.Replace(doc, `, true]`, `, ture]`, 1),
+		strings.Replace(doc, `"k": []`, `"k": ]`, 1),
+		doc[:len(doc)*2/3],
+		strings.Replace(doc, `]`+"\n", `,]`, 1),
+	}
+	for _, bad := range broken {
+		stats := compareParallel(t, bad, OPT_ALLOW_EXTRA_WHITESPACE, 4, 64)
+		if stats.fallbackFrom < 0 {
+			t.Fatalf("%q: %+v", bad[len(bad)-20:], stats)
+		}
+	}
+	// what follows the array is only looked at until EOF
+	compareParallel(t, doc+"[]", OPT_ALLOW_EXTRA_WHITESPACE, 4, 64)
+	compareParallel(t, doc+"[]", OPT_ALLOW_EXTRA_WHITESPACE|OPT_PARSE_UNTIL_EOF, 4, 64)
+	// trailing commas are fine when allowed, and other documents are
+	// parsed sequentially
+	compareParallel(t, strings.Replace(doc, `]`+"\n", `,]`, 1), OPT_ALLOW_EXTRA_WHITESPACE|OPT_ALLOW_TRAILING_COMMAS, 4, 64)
+	for _, other := range []string{`{"a": ` + doc + `}`, "  ", doc[:10]} {
+		if stats := compareParallel(t, other, OPT_ALLOW_EXTRA_WHITESPACE, 4, 16); stats.chunks != 0 {
+			t.Fatalf("%q: %+v", other, stats)
+		}
+	}
+}
+
+func TestParallelStop(t *testing.T) {
+	doc := []byte(parallelTestDoc(200))
+	p := NewParser(nil, nil, OPT_ALLOW_EXTRA_WHITESPACE)
+	batches := 0
+	_, err := p.parseBytesBatchedParallel(doc, 4, 64, make([]BatchEvent, 4), func(p *Parser, events []BatchEvent) {
+		if batches++; batches == 10 {
+			p.ParseStop()
+		}
+	})
+	if err != nil || batches != 10 {
+		t.Fatal(err, batches)
+	}
+}
+
+func BenchmarkParallelArray(b *testing.B) {
+	doc := []byte("[" + strings.Repeat(STR_OBSFUCATED_BENCHMARK_BASIS+",", 63) + STR_OBSFUCATED_BENCHMARK_BASIS + "]")
+	onBatch := func(p *Parser, events []BatchEvent) {}
+	p := NewParser(nil, nil, OPT_ALLOW_EXTRA_WHITESPACE)
+	b.Run("sequential", func(b *testing.B) {
+		b.SetBytes(int64(len(doc)))
+		for i := 0; i < b.N; i++ {
+			p.Reset()
+			if err := p.ParseBytesBatched(doc, nil, onBatch); err != nil {
+				log.Fatal(err)
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.SetBytes(int64(len(doc)))
+		for i := 0; i < b.N; i++ {
+			p.Reset()
+			if _, err := p.parseBytesBatchedParallel(doc, 0, len(doc)/16, nil, onBatch); err != nil {
+				log.Fatal(err)
+			}
+		}
+	})
+}
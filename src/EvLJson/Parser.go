@@ -1,8 +1,8 @@
 package EvLJson
 
 import (
-	"encoding/hex"
 	"io"
+	"unicode/utf8"
 	//"fmt"  // DEBUG
 	//"log"  // DEBUG
 )
@@ -15,9 +15,12 @@ import (
 // handling does not need to signal more than once as the buffer is updated
 //
 // ^^^ this is very important ^^^
-//
 const MIN_DATA_BUFFER_SIZE = 3
 
+// OPT_DECODE_UNICODE_ESCAPES writes whole utf-8 sequences of up to
+// utf8.UTFMax bytes at once, so the buffer must be able to hold one
+const MIN_UTF8_DATA_BUFFER_SIZE = utf8.UTFMax
+
 // minimum nominal case will require 3 state levels
 const MIN_STACK_DEPTH = 3
 
@@ -56,7 +59,6 @@ type signal_t uint8
 type event_t uint8
 type eventReceiver_t func(parser *Parser, evt event_t)
 type dataReceiver_t func(parser *Parser, endOfData bool)
-type userSig_t func(normalSignal signal_t) signal_t
 type UnspecifiedJsonParserError struct{}
 
 func (err UnspecifiedJsonParserError) Error() string {
@@ -73,8 +75,17 @@ func (err InvalidStricterExponentFormat) Error() string {
 
 var invalidStricterExponentFormat = InvalidStricterExponentFormat{}
 
-func signalDataNextByte(p *Parser, b byte) signal_t {
-	if p.OnData == nil {
+func signalDataNextByte[H parseHandler](p *Parser, h H, b byte) signal_t {
+	if !p.wantsData {
+		return SIG_NEXT_BYTE
+	}
+	if p.input != nil && !p.copying {
+		// the bytes are already contiguous in the input, just extend the run
+		if !p.aliasing {
+			p.aliasing = true
+			p.aliasStart = p.input.pos - 1
+		}
+		p.aliasEnd = p.input.pos
 		return SIG_NEXT_BYTE
 	}
 	size := len(p.DataBuffer)
@@ -83,7 +94,8 @@ func signalDataNextByte(p *Parser, b byte) signal_t {
 		p.DataBuffer[size] = b
 		return SIG_NEXT_BYTE
 	}
-	p.OnData(p, DATA_CONTINUES)
+	p.spills++
+	h.OnData(p, DATA_CONTINUES)
 	if p.userSignal != SIG_STOP {
 		p.DataBuffer = p.DataBuffer[0:1]
 		p.DataBuffer[0] = b
@@ -92,16 +104,62 @@ func signalDataNextByte(p *Parser, b byte) signal_t {
 	return SIG_STOP
 }
 
-var whitespaces = map[byte]interface{}{
-	0x20: nil, // SPACE
-	0x09: nil, // TAB
-	0x0A: nil, // LF
-	0x0D: nil, // CR
+var whitespaces = [256]bool{
+	0x20: true, // SPACE
+	0x09: true, // TAB
+	0x0A: true, // LF
+	0x0D: true, // CR
+}
+
+// signalAliasedData hands over the run of input seen so far when an escape
+// means the rest of the value has to be rewritten into DataBuffer
+//
+// Note: user can signal within this function
+func signalAliasedData[H parseHandler](p *Parser, h H) signal_t {
+	p.copying = true
+	if !p.aliasing {
+		return SIG_NEXT_BYTE
+	}
+	p.aliasing = false
+	p.DataBuffer, p.DataAliasesInput = p.input.data[p.aliasStart:p.aliasEnd:p.aliasEnd], true
+	h.OnData(p, DATA_CONTINUES)
+	p.DataBuffer, p.DataAliasesInput = p.ownBuffer[:0], false
+	if p.userSignal == SIG_STOP {
+		return SIG_STOP
+	}
+	return SIG_NEXT_BYTE
+}
+
+// Note: user can signal within this function
+func signalDataRune[H parseHandler](p *Parser, h H, r rune) signal_t {
+	var encoded [utf8.UTFMax]byte
+	n := utf8.EncodeRune(encoded[:], r)
+	size := len(p.DataBuffer)
+	if size+n > cap(p.DataBuffer) {
+		p.spills++
+		h.OnData(p, DATA_CONTINUES)
+		if p.userSignal == SIG_STOP {
+			return SIG_STOP
+		}
+		size = 0
+	}
+	p.DataBuffer = append(p.DataBuffer[:size], encoded[:n]...)
+	return SIG_NEXT_BYTE
+}
+
+// hexNibbles holds the value of each hex digit and HEX_INVALID for the
+// other bytes
+var hexNibbles [256]byte
+
+const HEX_INVALID = 0xff
+
+func hexCharValue(b byte) (rune, bool) {
+	nibble := hexNibbles[b]
+	return rune(nibble), nibble != HEX_INVALID
 }
 
 func isCharWhitespace(b byte) bool {
-	_, exists := whitespaces[b]
-	return exists
+	return whitespaces[b]
 }
 
 func pushHandle(p *Parser, handle *handle_t, newHandle handle_t) {
@@ -110,232 +168,335 @@ func pushHandle(p *Parser, handle *handle_t, newHandle handle_t) {
 }
 
 // Note: user can signal within this function
-func pushEnterHandle(p *Parser, handle *handle_t, newHandle handle_t, evt event_t) {
-	p.onEvent(p, EVT_ENTER)
+func pushEnterHandle[H parseHandler](p *Parser, h H, handle *handle_t, newHandle handle_t, evt event_t) {
+	h.OnEvent(p, EVT_ENTER)
 	if p.userSignal != SIG_STOP {
 		pushHandle(p, handle, newHandle)
-		p.onEvent(p, evt)
+		h.OnEvent(p, evt)
 	}
 }
 
-func pushNewValueHandle(p *Parser, handle *handle_t, err *error, b byte) signal_t {
-	if b >= '1' && b <= '9' {
-		p.DataIsJsonNum = true
-		pushEnterHandle(p, handle, HANDLE_INT, EVT_NUMBER)
-		return p.yieldToUserSig(SIG_NEXT_BYTE)
-	}
-	switch b {
-	case '0':
-		p.DataIsJsonNum = true
-		pushEnterHandle(p, handle, HANDLE_ZD_EXP_START, EVT_NUMBER)
-	case '[':
-		pushEnterHandle(p, handle, p.handleArrayStart, EVT_ARRAY)
-	case '{':
-		pushEnterHandle(p, handle, p.handleDictStart, EVT_DICT)
-	case VALUE_STR_NULL[0]:
-		p.onEvent(p, EVT_NULL)
-		pushHandle(p, handle, HANDLE_NULL)
-	case VALUE_STR_FALSE[0]:
-		p.onEvent(p, EVT_FALSE)
-		pushHandle(p, handle, HANDLE_FALSE)
-	case VALUE_STR_TRUE[0]:
-		p.onEvent(p, EVT_TRUE)
-		pushHandle(p, handle, HANDLE_TRUE)
-	case '"':
-		p.DataIsJsonNum = false
-		pushEnterHandle(p, handle, HANDLE_STRING, EVT_STRING)
-	case '-':
-		p.DataIsJsonNum = true
-		pushEnterHandle(p, handle, HANDLE_ZD_EXPN_START, EVT_NUMBER)
-	default:
-		*err = unspecifiedParserError
-		return SIG_ERR
-	}
-	return p.yieldToUserSig(SIG_NEXT_BYTE)
-}
-
 func popHandle(p *Parser, handle *handle_t) {
 	newMaxIdx := len(p.ContextStack) - 1
 	*handle, p.ContextStack = p.ContextStack[newMaxIdx], p.ContextStack[:newMaxIdx]
 }
 
 // Note: user can signal within this function
-func popHandleEvent(p *Parser, handle *handle_t) {
+func popHandleEvent[H parseHandler](p *Parser, h H, handle *handle_t) {
 	popHandle(p, handle)
+	if p.aliasing {
+		p.aliasing = false
+		p.DataBuffer, p.DataAliasesInput = p.input.data[p.aliasStart:p.aliasEnd:p.aliasEnd], true
+	}
+	p.copying = false
 	if len(p.DataBuffer) == 0 {
-		p.onEvent(p, EVT_LEAVE)
+		h.OnEvent(p, EVT_LEAVE)
 		return
 	}
-	if p.OnData != nil {
-		p.OnData(p, DATA_END)
+	if p.wantsData {
+		h.OnData(p, DATA_END)
 		if p.userSignal != SIG_STOP {
 			goto FIRE_LEAVE_EVT
 		}
 		return
 	}
 FIRE_LEAVE_EVT:
-	p.DataBuffer = p.DataBuffer[:0]
-	p.onEvent(p, EVT_LEAVE)
+	if p.DataAliasesInput {
+		p.DataBuffer, p.DataAliasesInput = p.ownBuffer[:0], false
+	} else {
+		p.DataBuffer = p.DataBuffer[:0]
+	}
+	h.OnEvent(p, EVT_LEAVE)
 }
 
-const ( // handle_t
-	HANDLE_START_AEW = iota
-	HANDLE_START
-	HANDLE_NULL
-	HANDLE_TRUE
-	HANDLE_FALSE
-	HANDLE_ZD_EXP_START // handleZeroOrDecimalOrExponentStart
-	HANDLE_INT
-	HANDLE_ZD_EXPN_START         // handleZeroOrDecimalOrExponentNegativeStart
-	HANDLE_DEC_FRAC_START        // handleDecimalFractionalStart
-	HANDLE_DEC_FRAC_END          // handleDecimalFractionalEnd
-	HANDLE_EXP_COEF_START        // handleExponentCoefficientStart
-	HANDLE_EXP_COEF_NEG          // handleExponentCoefficientNegative
-	HANDLE_EXP_COEF_LZERO        // handleExponentCoefficientLeadingZero
-	HANDLE_EXP_COEF_STRICT_LZERO // handleExponentCoefficientStrictLeadingZero
-	HANDLE_EXP_COEF_END          // handleExponentCoefficientEnd
-	HANDLE_STRING
-	HANDLE_STRING_RSP // handleStringReverseSolidusPrefix
-	HANDLE_HEX_NR     // handleStringHexShortNoReporting
-	HANDLE_HEX_EVEN   // handleStringHexShortEven
-	HANDLE_HEX_ODD    // handleStringHexShortOdd
-	HANDLE_DICT_START_AEW
-	HANDLE_DICT_START
-	HANDLE_DICT_KV_DELIM_AEW
-	HANDLE_DICT_KV_DELIM
-	HANDLE_DICT_VALUE_AEW
-	HANDLE_DICT_VALUE
-	HANDLE_DICT_VALUE_END_AEW
-	HANDLE_DICT_VALUE_END
-	HANDLE_DICT_EXPECT_KEY_AEW
-	HANDLE_DICT_EXPECT_KEY
-	HANDLE_ARRAY_START_AEW
-	HANDLE_ARRAY_START
-	HANDLE_ARRAY_DELIM_AEW
-	HANDLE_ARRAY_DELIM
-	HANDLE_ARRAY_EXPECT_ENTRY_AEW
-	HANDLE_ARRAY_EXPECT_ENTRY
-	HANDLE_END_AEW
-	HANDLE_END
-	HANDLE_STOP
-)
+//go:generate go test -run TestParserTablesUpToDate -update-tables
+
+// the states, byte classes and actions of the tables are generated from
+// Parser.grammar into ParserTables.go
+
+type byteClass_t uint8
+type parserAction_t uint8
+
+// parserTransition packs the action, next state, pushed state and event of
+// a table entry into one word, a byte each
+type parserTransition uint32
 
-var allhexchars = map[byte]interface{}{
-	'0': nil,
-	'1': nil,
-	'2': nil,
-	'3': nil,
-	'4': nil,
-	'5': nil,
-	'6': nil,
-	'7': nil,
-	'8': nil,
-	'9': nil,
-	'a': nil,
-	'b': nil,
-	'c': nil,
-	'd': nil,
-	'e': nil,
-	'f': nil,
-	'A': nil,
-	'B': nil,
-	'C': nil,
-	'D': nil,
-	'E': nil,
-	'F': nil,
+func (t parserTransition) action() parserAction_t { return parserAction_t(t) }
+func (t parserTransition) next() handle_t         { return handle_t(t >> 8) }
+func (t parserTransition) push() handle_t         { return handle_t(t >> 16) }
+func (t parserTransition) evt() event_t           { return event_t(t >> 24) }
+
+// the rows of a table are padded to a power of two so indexing them with a
+// masked class needs no bounds check
+const parserTableRow = 32
+
+type parserTable_t [HANDLE_TABLE_STATES][parserTableRow]parserTransition
+
+type parserPatch struct {
+	state      handle_t
+	class      byteClass_t
+	transition parserTransition
 }
 
-var caphexchars = map[byte]interface{}{
-	'A': nil,
-	'B': nil,
-	'C': nil,
-	'D': nil,
-	'E': nil,
-	'F': nil,
+type parserOverlay struct {
+	options uint8
+	patches []parserPatch
 }
 
-var lowerhexchars = map[byte]interface{}{
-	'0': nil,
-	'1': nil,
-	'2': nil,
-	'3': nil,
-	'4': nil,
-	'5': nil,
-	'6': nil,
-	'7': nil,
-	'8': nil,
-	'9': nil,
-	'a': nil,
-	'b': nil,
-	'c': nil,
-	'd': nil,
-	'e': nil,
-	'f': nil,
+// parserOverlayOptions are the options some overlay depends on
+var parserOverlayOptions uint8
+
+// parserTables has the composed table of every combination of
+// parserOverlayOptions
+var parserTables [256]*parserTable_t
+
+func init() {
+	for b := range hexNibbles {
+		switch {
+		case b >= '0' && b <= '9':
+			hexNibbles[b] = byte(b - '0')
+		case b >= 'a' && b <= 'f':
+			hexNibbles[b] = byte(b-'a') + 10
+		case b >= 'A' && b <= 'F':
+			hexNibbles[b] = byte(b-'A') + 10
+		default:
+			hexNibbles[b] = HEX_INVALID
+		}
+	}
+	for _, overlay := range parserOverlays {
+		parserOverlayOptions |= overlay.options
+	}
+	for options := parserOverlayOptions; ; options = (options - 1) & parserOverlayOptions {
+		table := parserBaseTable
+		for _, overlay := range parserOverlays {
+			if options&overlay.options == overlay.options {
+				for _, patch := range overlay.patches {
+					table[patch.state][patch.class] = patch.transition
+				}
+			}
+		}
+		parserTables[options] = &table
+		if options == 0 {
+			break
+		}
+	}
 }
 
 func defaultOnEvent(parser *Parser, evt event_t) {
 	return
 }
 
-func userSigNone(normalSignal signal_t) signal_t {
+// yieldToUserSig lets a stop signalled by the user override the normal
+// signal
+func yieldToUserSig(p *Parser, normalSignal signal_t) signal_t {
+	if p.userSignal == SIG_STOP {
+		return SIG_STOP
+	}
 	return normalSignal
 }
 
-func userSigStop(normalSignal signal_t) signal_t {
-	return SIG_STOP
-}
-
 const (
 	OPT_ALLOW_EXTRA_WHITESPACE = 0x01
 	OPT_STRICTER_EXPONENTS     = 0x02
 	OPT_PARSE_UNTIL_EOF        = 0x04
+	OPT_DECODE_UNICODE_ESCAPES = 0x08 // \uXXXX escapes become utf-8 in DataBuffer
+	OPT_ALLOW_TRAILING_COMMAS  = 0x10 // a comma may follow the last entry of an array or dict
+	OPT_TRACK_POSITIONS        = 0x20 // Parser.Position and the value spans are kept, see Position.go
+	OPT_TRACK_LINES            = 0x40 // OPT_TRACK_POSITIONS with lines and columns
 )
 
+// IsDictKey reports whether the string currently being parsed is a dict
+// key rather than a value; only meaningful between its EVT_STRING and
+// EVT_LEAVE events
+func (p *Parser) IsDictKey() bool {
+	if len(p.ContextStack) == 0 {
+		return false
+	}
+	top := p.ContextStack[len(p.ContextStack)-1]
+	return top == HANDLE_DICT_KV_DELIM
+}
+
 func (p *Parser) ParseStop() {
 	p.userSignal = SIG_STOP
-	p.yieldToUserSig = userSigStop
 }
 
-func (p *Parser) Parse(byteReader io.ByteReader, onEvent eventReceiver_t, onData dataReceiver_t) error {
-	isEmptyJson := true
-	handle := p.handleStart
-	var literalStateIndex uint8 = 1
-	var b byte
-	var err error
-	var signal signal_t
-	var hexShortBuffer [2]byte
-	handlePtr := &handle
+// ParseBytes parses a document held in memory; strings and numbers
+// without escapes reach OnData as one sub-slice of data, flagged by
+// DataAliasesInput, instead of being copied through DataBuffer a buffer
+// at a time, and a string falls back to copying from its first escape on
+//
+// Aliased slices are only valid while data is unchanged and must not be
+// appended to
+func (p *Parser) ParseBytes(data []byte, onEvent eventReceiver_t, onData dataReceiver_t) error {
+	p.beginInput(data)
+	err := p.Parse(p.input, onEvent, onData)
+	p.endInput()
+	return err
+}
+
+// beginInput sets up the zero-copy reading of data
+func (p *Parser) beginInput(data []byte) {
+	p.inputReader = sliceByteReader{data: data}
+	p.input, p.ownBuffer = &p.inputReader, p.DataBuffer[:0]
+	p.aliasing, p.copying = false, false
+}
 
+// endInput hands DataBuffer back and lets go of the input
+func (p *Parser) endInput() {
+	if p.DataAliasesInput {
+		p.DataBuffer, p.DataAliasesInput = p.ownBuffer[:0], false
+	}
+	p.input, p.inputReader, p.ownBuffer = nil, sliceByteReader{}, nil
+	p.aliasing, p.copying = false, false
+}
+
+// parseHandler receives the events and data of the parse loop, which is
+// shared by Parse, the batched parsers and position tracking through their
+// own handler types
+type parseHandler interface {
+	OnEvent(p *Parser, evt event_t)
+	OnData(p *Parser, endOfData bool)
+}
+
+// callbackHandler is the parseHandler of Parse, calling its function values
+type callbackHandler struct{}
+
+func (callbackHandler) OnEvent(p *Parser, evt event_t)   { p.onEvent(p, evt) }
+func (callbackHandler) OnData(p *Parser, endOfData bool) { p.OnData(p, endOfData) }
+
+func (p *Parser) Parse(byteReader io.ByteReader, onEvent eventReceiver_t, onData dataReceiver_t) error {
 	if onEvent != nil {
 		p.onEvent = onEvent
 	} else {
 		p.onEvent = defaultOnEvent
 	}
+	p.OnData, p.wantsData = onData, onData != nil
+	if p.options&positionOptions != 0 {
+		return parseTracked(p, byteReader, callbackHandler{})
+	}
+	return parse(p, byteReader, callbackHandler{}, HANDLE_START)
+}
+
+// parseWith parses like Parse, handing events and data to the methods of
+// handler, always collecting data
+func parseWith[H parseHandler](p *Parser, byteReader io.ByteReader, handler H) error {
+	p.onEvent, p.OnData, p.wantsData = nil, nil, true
+	if p.options&positionOptions != 0 {
+		return parseTracked(p, byteReader, handler)
+	}
+	return parse(p, byteReader, handler, HANDLE_START)
+}
+
+// parseBytesWith is parseWith for a document in memory, see ParseBytes
+func parseBytesWith[H parseHandler](p *Parser, data []byte, handler H) error {
+	p.beginInput(data)
+	err := parseWith(p, p.input, handler)
+	p.endInput()
+	return err
+}
 
-	p.OnData = onData
+// parse runs the parser from handle, HANDLE_START unless resuming a
+// document with the states of ContextStack
+func parse[H parseHandler](p *Parser, byteReader io.ByteReader, h H, handle handle_t) error {
+	table := p.table
+	var literalStateIndex uint8 = 1
+	var b byte
+	var err error
+	var signal signal_t
+	var hexShortBuffer [2]byte
+	var hexRune, highSurrogate rune
+	handlePtr := &handle
 
 NEXT_BYTE:
 	b, err = byteReader.ReadByte()
+READ_BYTE:
 	if err == nil {
 	PARSE_LOOP:
 		// fmt.Printf("%s: %d\n", string(b), handle)  // DEBUG
-		switch handle {
-		case HANDLE_START_AEW:
-			if isCharWhitespace(b) {
+		if handle < HANDLE_TABLE_STATES {
+			transition := table[handle][byteClasses[b]&(parserTableRow-1)]
+			switch transition.action() {
+			case ACT_WHITESPACE:
+				skipWhitespaceRun(p)
 				goto NEXT_BYTE
-			}
-			fallthrough
-		case HANDLE_START:
-			handle = p.handleEnd
-			if b == '[' {
-				pushEnterHandle(p, handlePtr, p.handleArrayStart, EVT_ARRAY)
-			} else if b == '{' {
-				pushEnterHandle(p, handlePtr, p.handleDictStart, EVT_DICT)
-			} else {
+			case ACT_SKIP:
+				handle = transition.next()
+				goto NEXT_BYTE
+			case ACT_DATA:
+				handle = transition.next()
+				signal = signalDataNextByte(p, h, b)
+			case ACT_DIGITS:
+				signal = signalDataDigitRun(p, h, b)
+			case ACT_STRING_DATA:
+				signal = signalDataStringRun(p, h, b)
+				if p.input != nil || signal != SIG_NEXT_BYTE {
+					break
+				}
+				// a byte reader has no run to scan ahead, so the rest of the
+				// string is taken here rather than a dispatch at a time
+				row := &table[handle]
+				for {
+					if b, err = byteReader.ReadByte(); err != nil {
+						goto READ_BYTE
+					}
+					if row[byteClasses[b]&(parserTableRow-1)].action() != ACT_STRING_DATA {
+						goto PARSE_LOOP
+					}
+					if size := len(p.DataBuffer); p.wantsData && size != cap(p.DataBuffer) {
+						p.DataBuffer = p.DataBuffer[:size+1]
+						p.DataBuffer[size] = b
+					} else if signal = signalDataNextByte(p, h, b); signal != SIG_NEXT_BYTE {
+						break
+					}
+				}
+			case ACT_ESCAPE:
+				// reverse solidus prefix detected
+				handle = transition.next()
+				if p.input != nil && !p.copying && p.wantsData {
+					if signalAliasedData(p, h) == SIG_STOP {
+						return nil
+					}
+				}
+				goto NEXT_BYTE
+			case ACT_DATA_EVENT:
+				h.OnEvent(p, transition.evt())
+				if p.userSignal == SIG_STOP {
+					return nil
+				}
+				handle = transition.next()
+				signal = signalDataNextByte(p, h, b)
+			case ACT_END_NUMBER:
+				popHandleEvent(p, h, handlePtr)
+				signal = yieldToUserSig(p, SIG_REUSE_BYTE)
+			case ACT_CLOSE:
+				popHandleEvent(p, h, handlePtr)
+				signal = yieldToUserSig(p, SIG_NEXT_BYTE)
+			case ACT_PUSH:
+				handle = transition.next()
+				p.DataIsJsonNum = false
+				pushEnterHandle(p, h, handlePtr, transition.push(), transition.evt())
+				signal = yieldToUserSig(p, SIG_NEXT_BYTE)
+			case ACT_PUSH_NUMBER:
+				handle = transition.next()
+				p.DataIsJsonNum = true
+				pushEnterHandle(p, h, handlePtr, transition.push(), EVT_NUMBER)
+				signalDataNextByte(p, h, b)
+				signal = yieldToUserSig(p, SIG_NEXT_BYTE)
+			case ACT_PUSH_LITERAL:
+				handle = transition.next()
+				h.OnEvent(p, transition.evt())
+				pushHandle(p, handlePtr, transition.push())
+				signal = yieldToUserSig(p, SIG_NEXT_BYTE)
+			case ACT_STOP:
+				return nil
+			case ACT_STRICT_EXPONENT:
+				return invalidStricterExponentFormat
+			default:
 				return unspecifiedParserError
 			}
-			isEmptyJson = false
-			signal = p.yieldToUserSig(SIG_NEXT_BYTE)
+			goto SIGNAL_PROCESSING
+		}
+		switch handle {
 		case HANDLE_NULL:
 			if b == VALUE_STR_NULL[literalStateIndex] {
 				if literalStateIndex != uint8(len(VALUE_STR_NULL)-1) {
@@ -369,162 +530,6 @@ NEXT_BYTE:
 				goto NEXT_BYTE
 			}
 			return unspecifiedParserError
-		case HANDLE_ZD_EXP_START:
-			switch b {
-			case '.':
-				p.onEvent(p, EVT_DECIMAL)
-				if p.userSignal != SIG_STOP {
-					handle = HANDLE_DEC_FRAC_START
-					signal = signalDataNextByte(p, b)
-					break
-				}
-				return nil
-			case 'e':
-				p.onEvent(p, EVT_EXPONENT)
-				if p.userSignal != SIG_STOP {
-					handle = HANDLE_EXP_COEF_START
-					signal = signalDataNextByte(p, b)
-					break
-				}
-				return nil
-			default:
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_REUSE_BYTE)
-			}
-		case HANDLE_INT:
-			switch b {
-			case '0':
-				fallthrough
-			case '1':
-				fallthrough
-			case '2':
-				fallthrough
-			case '3':
-				fallthrough
-			case '4':
-				fallthrough
-			case '5':
-				fallthrough
-			case '6':
-				fallthrough
-			case '7':
-				fallthrough
-			case '8':
-				fallthrough
-			case '9':
-				break
-			case '.':
-				p.onEvent(p, EVT_DECIMAL)
-				if p.userSignal != SIG_STOP {
-					handle = HANDLE_DEC_FRAC_START
-				} else {
-					return nil
-				}
-			case 'e':
-				p.onEvent(p, EVT_EXPONENT)
-				if p.userSignal != SIG_STOP {
-					handle = HANDLE_EXP_COEF_START
-				} else {
-					return nil
-				}
-			default:
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_REUSE_BYTE)
-				goto SIGNAL_PROCESSING
-			}
-			signal = signalDataNextByte(p, b)
-		case HANDLE_ZD_EXPN_START:
-			if b == '0' {
-				handle = HANDLE_ZD_EXP_START
-			} else if b >= '1' && b <= '9' {
-				handle = HANDLE_INT
-			} else {
-				return unspecifiedParserError
-			}
-			signal = signalDataNextByte(p, b)
-		case HANDLE_DEC_FRAC_START:
-			if b >= '0' && b <= '9' {
-				handle = HANDLE_DEC_FRAC_END
-				signal = signalDataNextByte(p, b)
-			} else {
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_REUSE_BYTE)
-			}
-		case HANDLE_DEC_FRAC_END:
-			switch {
-			case b >= '0' && b <= '9':
-				signal = signalDataNextByte(p, b)
-			case b == 'e':
-				p.onEvent(p, EVT_EXPONENT)
-				if p.userSignal != SIG_STOP {
-					handle = HANDLE_EXP_COEF_START
-					signal = signalDataNextByte(p, b)
-					break
-				}
-				return nil
-			default:
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_REUSE_BYTE)
-			}
-		case HANDLE_EXP_COEF_START:
-			if b >= '1' && b <= '9' {
-				handle = HANDLE_EXP_COEF_END
-			} else if b == '0' {
-				handle = p.handleExponentCoefficientLeadingZero
-			} else if b == '-' {
-				handle = HANDLE_EXP_COEF_NEG
-			} else {
-				return unspecifiedParserError
-			}
-			signal = signalDataNextByte(p, b)
-		case HANDLE_EXP_COEF_NEG:
-			if b >= '1' && b <= '9' {
-				handle = HANDLE_EXP_COEF_END
-			} else if b == '0' {
-				handle = p.handleExponentCoefficientLeadingZero
-			} else {
-				return unspecifiedParserError
-			}
-			signal = signalDataNextByte(p, b)
-		case HANDLE_EXP_COEF_LZERO:
-			if b >= '1' && b <= '9' {
-				handle = HANDLE_EXP_COEF_END
-			} else if b != '0' {
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_REUSE_BYTE)
-				break
-			}
-			signal = signalDataNextByte(p, b)
-		case HANDLE_EXP_COEF_STRICT_LZERO:
-			if b >= '1' && b <= '9' {
-				handle = HANDLE_EXP_COEF_END
-				signal = signalDataNextByte(p, b)
-			} else if b == '0' {
-				return invalidStricterExponentFormat
-			} else {
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_REUSE_BYTE)
-			}
-		case HANDLE_EXP_COEF_END:
-			if b >= '0' && b <= '9' {
-				signal = signalDataNextByte(p, b)
-			} else {
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_REUSE_BYTE)
-			}
-		case HANDLE_STRING:
-			switch b {
-			case '\\':
-				// reverse solidus prefix detected
-				handle = HANDLE_STRING_RSP
-				goto NEXT_BYTE
-			case '"':
-				// end of string
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_NEXT_BYTE)
-			default:
-				signal = signalDataNextByte(p, b)
-			}
 		case HANDLE_STRING_RSP:
 			switch b {
 			case 'b':
@@ -554,21 +559,20 @@ NEXT_BYTE:
 			case '"':
 				goto UNESCAPED
 			case 'u':
-				if p.OnData == nil {
+				if !p.wantsData {
 					handle = HANDLE_HEX_NR
 				} else {
-					handle = HANDLE_HEX_EVEN
+					handle = p.handleHexShort
 				}
 				goto NEXT_BYTE
 			default:
 				return unspecifiedParserError
 			}
-			break
 		UNESCAPED:
 			handle = HANDLE_STRING
-			signal = signalDataNextByte(p, b)
+			signal = signalDataNextByte(p, h, b)
 		case HANDLE_HEX_NR:
-			if _, exists := allhexchars[b]; exists {
+			if hexNibbles[b] != HEX_INVALID {
 				newLiteralStateIndex := literalStateIndex + 1
 				if newLiteralStateIndex != 5 {
 					literalStateIndex = newLiteralStateIndex
@@ -581,170 +585,94 @@ NEXT_BYTE:
 				return unspecifiedParserError
 			}
 		case HANDLE_HEX_EVEN:
-			handle = HANDLE_HEX_ODD
-			if _, exists := lowerhexchars[b]; exists {
-				// Do Nothing
-			} else if _, exists = caphexchars[b]; exists {
-				b = b + ('a' - 'A') // convert to lower case hex char (speedup)
-			} else {
+			nibble := hexNibbles[b]
+			if nibble == HEX_INVALID {
 				return unspecifiedParserError
 			}
-			hexShortBuffer[literalStateIndex] = b
+			handle = HANDLE_HEX_ODD
+			hexShortBuffer[literalStateIndex] = nibble
 			goto NEXT_BYTE
 		case HANDLE_HEX_ODD:
-			if _, exists := lowerhexchars[b]; exists {
-				// Do Nothing
-			} else if _, exists = caphexchars[b]; exists {
-				b = b + ('a' - 'A') // convert to lower case hex char (speedup)
-			} else {
-				return unspecifiedParserError
-			}
-			var err error
-			decodedBytes := []byte{0}
-			newLiteralStateIndex := literalStateIndex
-			if _, err = hex.Decode(decodedBytes, []byte{hexShortBuffer[newLiteralStateIndex], b}); err == nil {
-				if newLiteralStateIndex == 1 {
-					literalStateIndex = 0
-					handle = HANDLE_HEX_EVEN
-					hexShortBuffer[1] = decodedBytes[0]
-					goto NEXT_BYTE
-				} else {
-					literalStateIndex = 1
-					handle = HANDLE_STRING
-				}
-				size := len(p.DataBuffer)
-				if !(size+1 >= cap(p.DataBuffer)) {
-					p.DataBuffer = p.DataBuffer[0 : size+2]
-					p.DataBuffer[size] = hexShortBuffer[1]
-					size++
-					p.DataBuffer[size] = decodedBytes[0]
-					goto NEXT_BYTE
-				}
-				p.OnData(p, DATA_CONTINUES)
-				if p.userSignal != SIG_STOP {
-					p.DataBuffer = p.DataBuffer[0:2]
-					p.DataBuffer[0] = hexShortBuffer[1]
-					p.DataBuffer[1] = decodedBytes[0]
-					goto NEXT_BYTE
-				}
-				return nil
-			}
-			return err
-		case HANDLE_DICT_START_AEW:
-			if isCharWhitespace(b) {
-				goto NEXT_BYTE
-			}
-			fallthrough
-		case HANDLE_DICT_START:
-			if b == '"' {
-				handle = p.handleDictKVDelim
-				pushEnterHandle(p, handlePtr, HANDLE_STRING, EVT_STRING)
-			} else if b == '}' {
-				popHandleEvent(p, handlePtr)
-			} else {
+			nibble := hexNibbles[b]
+			if nibble == HEX_INVALID {
 				return unspecifiedParserError
 			}
-			signal = p.yieldToUserSig(SIG_NEXT_BYTE)
-		case HANDLE_DICT_KV_DELIM_AEW:
-			if isCharWhitespace(b) {
+			decoded := hexShortBuffer[literalStateIndex]<<4 | nibble
+			if literalStateIndex == 1 {
+				literalStateIndex = 0
+				handle = HANDLE_HEX_EVEN
+				hexShortBuffer[1] = decoded
 				goto NEXT_BYTE
 			}
-			fallthrough
-		case HANDLE_DICT_KV_DELIM:
-			if b == ':' {
-				handle = p.handleDictValue
-				goto NEXT_BYTE
-			} else {
-				return unspecifiedParserError
-			}
-		case HANDLE_DICT_VALUE_AEW:
-			if isCharWhitespace(b) {
-				goto NEXT_BYTE
-			}
-			fallthrough
-		case HANDLE_DICT_VALUE:
-			handle = p.handleDictValueEnd
-			signal = pushNewValueHandle(p, handlePtr, &err, b)
-		case HANDLE_DICT_VALUE_END_AEW:
-			if isCharWhitespace(b) {
-				goto NEXT_BYTE
-			}
-			fallthrough
-		case HANDLE_DICT_VALUE_END:
-			switch b {
-			case ',':
-				handle = p.handleDictExpectKey
-				goto NEXT_BYTE
-			case '}':
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_NEXT_BYTE)
-			default:
-				return unspecifiedParserError
-			}
-		case HANDLE_DICT_EXPECT_KEY_AEW:
-			if isCharWhitespace(b) {
+			literalStateIndex = 1
+			handle = HANDLE_STRING
+			size := len(p.DataBuffer)
+			if !(size+1 >= cap(p.DataBuffer)) {
+				p.DataBuffer = p.DataBuffer[0 : size+2]
+				p.DataBuffer[size] = hexShortBuffer[1]
+				size++
+				p.DataBuffer[size] = decoded
 				goto NEXT_BYTE
 			}
-			fallthrough
-		case HANDLE_DICT_EXPECT_KEY:
-			if b == '"' {
-				handle = p.handleDictKVDelim
-				pushEnterHandle(p, handlePtr, HANDLE_STRING, EVT_STRING)
-				signal = p.yieldToUserSig(SIG_NEXT_BYTE)
-			} else {
-				return unspecifiedParserError
-			}
-		case HANDLE_ARRAY_START_AEW:
-			if isCharWhitespace(b) {
+			p.spills++
+			h.OnData(p, DATA_CONTINUES)
+			if p.userSignal != SIG_STOP {
+				p.DataBuffer = p.DataBuffer[0:2]
+				p.DataBuffer[0] = hexShortBuffer[1]
+				p.DataBuffer[1] = decoded
 				goto NEXT_BYTE
 			}
-			fallthrough
-		case HANDLE_ARRAY_START:
-			if b != ']' {
-				handle = p.handleArrayDelim
-				signal = pushNewValueHandle(p, handlePtr, &err, b)
-			} else {
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_NEXT_BYTE)
+			return nil
+		case HANDLE_HEX_UTF8:
+			if value, ok := hexCharValue(b); ok {
+				hexRune = hexRune<<4 | value
+				if literalStateIndex != 4 {
+					literalStateIndex++
+					goto NEXT_BYTE
+				}
+				literalStateIndex = 1
+				value, hexRune = hexRune, 0
+				if highSurrogate != 0 {
+					if value >= 0xDC00 && value <= 0xDFFF {
+						value = (highSurrogate-0xD800)<<10 | (value - 0xDC00) + 0x10000
+					} else if signalDataRune(p, h, utf8.RuneError) == SIG_STOP {
+						return nil
+					}
+					highSurrogate = 0
+				}
+				if value >= 0xD800 && value <= 0xDBFF {
+					highSurrogate = value
+					handle = HANDLE_HEX_LS_RSP
+					goto NEXT_BYTE
+				}
+				handle = HANDLE_STRING
+				signal = signalDataRune(p, h, value)
+				break
 			}
-		case HANDLE_ARRAY_DELIM_AEW:
-			if isCharWhitespace(b) {
+			return unspecifiedParserError
+		case HANDLE_HEX_LS_RSP:
+			if b == '\\' {
+				handle = HANDLE_HEX_LS_U
 				goto NEXT_BYTE
 			}
-			fallthrough
-		case HANDLE_ARRAY_DELIM:
-			switch b {
-			case ',':
-				handle = p.handleArrayExpectEntry
-				goto NEXT_BYTE
-			case ']':
-				popHandleEvent(p, handlePtr)
-				signal = p.yieldToUserSig(SIG_NEXT_BYTE)
-			default:
-				return unspecifiedParserError
+			// lone high surrogate
+			highSurrogate = 0
+			handle = HANDLE_STRING
+			if signalDataRune(p, h, utf8.RuneError) != SIG_STOP {
+				goto PARSE_LOOP
 			}
-		case HANDLE_ARRAY_EXPECT_ENTRY_AEW:
-			if isCharWhitespace(b) {
+			return nil
+		case HANDLE_HEX_LS_U:
+			if b == 'u' {
+				handle = HANDLE_HEX_UTF8
 				goto NEXT_BYTE
 			}
-			fallthrough
-		case HANDLE_ARRAY_EXPECT_ENTRY:
-			handle = p.handleArrayDelim
-			signal = pushNewValueHandle(p, handlePtr, &err, b)
-		case HANDLE_END_AEW:
-			for isCharWhitespace(b) {
-				b, err = byteReader.ReadByte()
-				if err == nil {
-					continue
-				}
-				if err == io.EOF {
-					return nil
-				}
+			// lone high surrogate followed by some other escape
+			highSurrogate = 0
+			handle = HANDLE_STRING_RSP
+			if signalDataRune(p, h, utf8.RuneError) != SIG_STOP {
+				goto PARSE_LOOP
 			}
-			return unspecifiedParserError
-		case HANDLE_END:
-			return unspecifiedParserError
-		case HANDLE_STOP:
 			return nil
 		}
 	SIGNAL_PROCESSING:
@@ -762,7 +690,7 @@ NEXT_BYTE:
 			}
 			return err
 		}
-	} else if err == io.EOF && len(p.ContextStack) == 0 && !isEmptyJson {
+	} else if err == io.EOF && len(p.ContextStack) == 0 && handle != HANDLE_START {
 		return nil
 	}
 	return err
@@ -777,91 +705,76 @@ type Parser struct {
 	OnData   dataReceiver_t
 
 	// BEGIN: configured calls
-	handleStart                          handle_t
-	handleDictStart                      handle_t
-	handleDictKVDelim                    handle_t
-	handleDictValue                      handle_t
-	handleDictValueEnd                   handle_t
-	handleDictExpectKey                  handle_t
-	handleArrayStart                     handle_t
-	handleArrayDelim                     handle_t
-	handleArrayExpectEntry               handle_t
-	handleEnd                            handle_t
-	handleExponentCoefficientLeadingZero handle_t
+	options        uint8
+	table          *parserTable_t
+	handleHexShort handle_t
 
 	// END: configured calls
 
-	ContextStack   []handle_t
-	DataBuffer     []byte
-	DataIsJsonNum  bool
-	userSignal     signal_t
-	yieldToUserSig userSig_t
+	ContextStack  []handle_t
+	DataBuffer    []byte
+	DataIsJsonNum bool
+	userSignal    signal_t
+	wantsData     bool // the data of strings and numbers is collected
+	spills        int  // times DataBuffer filled up mid value since put in a Pool
+
+	// BEGIN: zero-copy state of ParseBytes
+	DataAliasesInput bool // DataBuffer is a slice of the input, not a copy
+	input            *sliceByteReader
+	inputReader      sliceByteReader
+	ownBuffer        []byte
+	aliasing         bool // a run of input is standing in for DataBuffer
+	copying          bool // an escape has been seen, the value is rewritten
+	aliasStart       int
+	aliasEnd         int
+	// END: zero-copy state
+
+	// BEGIN: batched delivery
+	BatchData []byte // copied data the spans of the current batch point into
+	batch     parserBatch
+	// END: batched delivery
+
+	positions parserPositions
 }
 
+// Reset readies the parser for the next document whatever became of the
+// last one, an error or a stop included; it keeps the options, UserData
+// and the buffers, so a reset parser behaves exactly like a new one
 func (p *Parser) Reset() {
 	p.userSignal = SIG_NEXT_BYTE
-	p.yieldToUserSig = userSigNone
+	p.onEvent, p.OnData, p.wantsData = nil, nil, false
+	p.ContextStack, p.DataBuffer, p.DataIsJsonNum = p.ContextStack[:0], p.DataBuffer[:0], false
+	p.aliasing, p.copying = false, false
+	p.BatchData, p.batch = p.BatchData[:0], parserBatch{}
+	p.positions = parserPositions{open: p.positions.open[:0]}
 }
 
+// NewParser makes a parser of options; a dataBuffer or contextStack whose
+// capacity is below the minimum, nil included, is replaced by a new one of
+// the minimum size
 func NewParser(dataBuffer []byte, contextStack []handle_t, options uint8) Parser {
-	self := Parser{}
+	self := Parser{options: options}
 	self.Reset()
 
-	if options&OPT_ALLOW_EXTRA_WHITESPACE == 0 {
-		self.handleStart = HANDLE_START
-		self.handleDictStart = HANDLE_DICT_START
-		self.handleDictKVDelim = HANDLE_DICT_KV_DELIM
-		self.handleDictValue = HANDLE_DICT_VALUE
-		self.handleDictValueEnd = HANDLE_DICT_VALUE_END
-		self.handleDictExpectKey = HANDLE_DICT_EXPECT_KEY
-		self.handleArrayStart = HANDLE_ARRAY_START
-		self.handleArrayDelim = HANDLE_ARRAY_DELIM
-		self.handleArrayExpectEntry = HANDLE_ARRAY_EXPECT_ENTRY
-
-		if options&OPT_PARSE_UNTIL_EOF == 0 {
-			self.handleEnd = HANDLE_STOP
-		} else {
-			self.handleEnd = HANDLE_END
-		}
-	} else {
-		self.handleStart = HANDLE_START_AEW
-		self.handleDictStart = HANDLE_DICT_START_AEW
-		self.handleDictKVDelim = HANDLE_DICT_KV_DELIM_AEW
-		self.handleDictValue = HANDLE_DICT_VALUE_AEW
-		self.handleDictValueEnd = HANDLE_DICT_VALUE_END_AEW
-		self.handleDictExpectKey = HANDLE_DICT_EXPECT_KEY_AEW
-		self.handleArrayStart = HANDLE_ARRAY_START_AEW
-		self.handleArrayDelim = HANDLE_ARRAY_DELIM_AEW
-		self.handleArrayExpectEntry = HANDLE_ARRAY_EXPECT_ENTRY_AEW
-
-		if options&OPT_PARSE_UNTIL_EOF == 0 {
-			self.handleEnd = HANDLE_STOP
-		} else {
-			self.handleEnd = HANDLE_END_AEW
-		}
-	}
+	self.table = parserTables[options&parserOverlayOptions]
 
-	if options&OPT_STRICTER_EXPONENTS == 0 {
-		self.handleExponentCoefficientLeadingZero = HANDLE_EXP_COEF_LZERO
+	minDataBufferSize := MIN_DATA_BUFFER_SIZE
+	if options&OPT_DECODE_UNICODE_ESCAPES == 0 {
+		self.handleHexShort = HANDLE_HEX_EVEN
 	} else {
-		self.handleExponentCoefficientLeadingZero = HANDLE_EXP_COEF_STRICT_LZERO
+		self.handleHexShort = HANDLE_HEX_UTF8
+		minDataBufferSize = MIN_UTF8_DATA_BUFFER_SIZE
 	}
 
-	if contextStack == nil {
+	if cap(contextStack) < MIN_STACK_DEPTH {
 		contextStack = make([]handle_t, 0, MIN_STACK_DEPTH)
 	} else {
-		if cap(contextStack) < MIN_STACK_DEPTH {
-			contextStack = contextStack[0:MIN_STACK_DEPTH]
-		}
 		contextStack = contextStack[:0]
 	}
 	self.ContextStack = contextStack
-	if dataBuffer == nil {
-		dataBuffer = make([]byte, MIN_DATA_BUFFER_SIZE)
+	if cap(dataBuffer) < minDataBufferSize {
+		dataBuffer = make([]byte, 0, minDataBufferSize)
 	} else {
-		if cap(dataBuffer) < MIN_DATA_BUFFER_SIZE {
-			dataBuffer = dataBuffer[0:MIN_DATA_BUFFER_SIZE]
-		}
 		dataBuffer = dataBuffer[:0]
 	}
 	self.DataBuffer = dataBuffer
@@ -0,0 +1,464 @@
+package EvLJson
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const TEST_PARSER_TABLES_COMMAND = "go test -run TestParserTablesUpToDate -update-tables"
+
+var updateParserTables = flag.Bool("update-tables", false, "rewrite ParserTables.go from Parser.grammar")
+
+// the grammar of Parser.grammar, see the comment at its top
+
+type grammarTransition struct {
+	line    int
+	states  []string // overlays only
+	classes []string
+	action  string
+	next    string
+	push    string
+	evt     string
+}
+
+type grammarOverlay struct {
+	options     []string
+	transitions []grammarTransition
+}
+
+type parserGrammar struct {
+	classes       []string
+	classBytes    [256]string
+	actions       []string
+	comments      map[string]string // of actions and states
+	states        []string
+	manual        []string
+	transitions   map[string][]grammarTransition
+	macros        map[string][]grammarTransition
+	overlays      []*grammarOverlay
+	stateIndex    map[string]int
+	classIndex    map[string]int
+	actionDefined map[string]bool
+}
+
+func parseGrammarByte(token string) ([]byte, error) {
+	switch {
+	case len(token) == 1:
+		return []byte{token[0]}, nil
+	case len(token) == 3 && token[1] == '-' && token[0] <= token[2]:
+		var run []byte
+		for b := int(token[0]); b <= int(token[2]); b++ {
+			run = append(run, byte(b))
+		}
+		return run, nil
+	case len(token) == 4 && strings.HasPrefix(token, `\x`):
+		value, err := strconv.ParseUint(token[2:], 16, 8)
+		return []byte{byte(value)}, err
+	}
+	value, _, tail, err := strconv.UnquoteChar(token, 0)
+	if err != nil || tail != "" || value > 0x7f {
+		return nil, fmt.Errorf("bad byte %q", token)
+	}
+	return []byte{byte(value)}, nil
+}
+
+func parseGrammarTransition(line int, fields []string, overlay bool) (grammarTransition, error) {
+	transition := grammarTransition{line: line}
+	if overlay {
+		if len(fields) == 0 {
+			return transition, fmt.Errorf("line %d: missing states", line)
+		}
+		transition.states, fields = strings.Split(fields[0], ","), fields[1:]
+	}
+	if len(fields) < 2 {
+		return transition, fmt.Errorf("line %d: expected classes and an action", line)
+	}
+	transition.classes, transition.action = strings.Split(fields[0], ","), fields[1]
+	for _, field := range fields[2:] {
+		key, value, found := strings.Cut(field, "=")
+		switch {
+		case !found:
+			return transition, fmt.Errorf("line %d: expected key=value, got %q", line, field)
+		case key == "next":
+			transition.next = value
+		case key == "push":
+			transition.push = value
+		case key == "evt":
+			transition.evt = value
+		default:
+			return transition, fmt.Errorf("line %d: unknown key %q", line, key)
+		}
+	}
+	return transition, nil
+}
+
+func parseParserGrammar(text string) (*parserGrammar, error) {
+	g := &parserGrammar{
+		comments:      map[string]string{},
+		transitions:   map[string][]grammarTransition{},
+		macros:        map[string][]grammarTransition{},
+		stateIndex:    map[string]int{},
+		classIndex:    map[string]int{},
+		actionDefined: map[string]bool{},
+	}
+	// transitions of a state or macro go to block[blockName]
+	var block map[string][]grammarTransition
+	var blockName string
+	var overlay *grammarOverlay
+	pendingComment := ""
+	for number, line := range strings.Split(text, "\n") {
+		number++
+		code, comment, _ := strings.Cut(line, "#")
+		comment = strings.TrimSpace(comment)
+		fields := strings.Fields(code)
+		if len(fields) == 0 {
+			if strings.TrimSpace(line) == "" {
+				pendingComment = ""
+			} else {
+				pendingComment = comment
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			switch {
+			case overlay != nil:
+				transition, err := parseGrammarTransition(number, fields, true)
+				if err != nil {
+					return nil, err
+				}
+				overlay.transitions = append(overlay.transitions, transition)
+			case block == nil:
+				return nil, fmt.Errorf("line %d: transition outside of a state, macro or overlay", number)
+			case strings.HasPrefix(fields[0], "@"):
+				macro, exists := g.macros[fields[0][1:]]
+				if !exists {
+					return nil, fmt.Errorf("line %d: unknown macro %q", number, fields[0])
+				}
+				keys, err := parseGrammarTransition(number, append([]string{"-", "-"}, fields[1:]...), false)
+				if err != nil {
+					return nil, err
+				}
+				for _, transition := range macro {
+					transition.line = number
+					if keys.next != "" {
+						transition.next = keys.next
+					}
+					if keys.push != "" {
+						transition.push = keys.push
+					}
+					if keys.evt != "" {
+						transition.evt = keys.evt
+					}
+					block[blockName] = append(block[blockName], transition)
+				}
+			default:
+				transition, err := parseGrammarTransition(number, fields, false)
+				if err != nil {
+					return nil, err
+				}
+				block[blockName] = append(block[blockName], transition)
+			}
+			continue
+		}
+		block, overlay = nil, nil
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: %q needs a name", number, fields[0])
+		}
+		name := fields[1]
+		switch fields[0] {
+		case "class":
+			if _, exists := g.classIndex[name]; exists {
+				return nil, fmt.Errorf("line %d: class %s redefined", number, name)
+			}
+			g.classes = append(g.classes, name)
+			g.classIndex[name] = len(g.classes)
+			for _, token := range fields[2:] {
+				run, err := parseGrammarByte(token)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %s", number, err)
+				}
+				for _, b := range run {
+					if g.classBytes[b] != "" {
+						return nil, fmt.Errorf("line %d: %q is already in class %s", number, b, g.classBytes[b])
+					}
+					g.classBytes[b] = name
+				}
+			}
+		case "action":
+			g.actions = append(g.actions, name)
+			g.actionDefined[name] = true
+			g.comments["ACT_"+name] = comment
+		case "macro":
+			block, blockName = g.macros, name
+			block[name] = nil
+		case "state":
+			if _, exists := g.stateIndex[name]; exists {
+				return nil, fmt.Errorf("line %d: state %s redefined", number, name)
+			}
+			g.stateIndex[name] = len(g.states)
+			g.states = append(g.states, name)
+			g.comments["HANDLE_"+name] = pendingComment
+			block, blockName = g.transitions, name
+		case "manual":
+			for _, state := range fields[1:] {
+				if _, exists := g.stateIndex[state]; exists {
+					return nil, fmt.Errorf("line %d: state %s redefined", number, state)
+				}
+				g.stateIndex[state] = -1
+				g.manual = append(g.manual, state)
+			}
+		case "overlay":
+			overlay = &grammarOverlay{options: fields[1:]}
+			g.overlays = append(g.overlays, overlay)
+		default:
+			return nil, fmt.Errorf("line %d: unknown directive %q", number, fields[0])
+		}
+		pendingComment = ""
+	}
+	return g, nil
+}
+
+// grammarCell is one resolved table entry
+type grammarCell struct {
+	state, class int
+	transition   grammarTransition
+}
+
+// expand resolves transitions of the given states to cells, applying the
+// ones for every class first so listed classes override them
+func (g *parserGrammar) expand(transitions []grammarTransition, states []string) ([]grammarCell, error) {
+	ordered := append([]grammarTransition{}, transitions...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].classes[0] == "*" && ordered[j].classes[0] != "*"
+	})
+	cells := map[[2]int]grammarTransition{}
+	for _, transition := range ordered {
+		if !g.actionDefined[transition.action] {
+			return nil, fmt.Errorf("line %d: unknown action %q", transition.line, transition.action)
+		}
+		for _, name := range []string{transition.next, transition.push} {
+			if _, exists := g.stateIndex[name]; name != "" && !exists {
+				return nil, fmt.Errorf("line %d: unknown state %q", transition.line, name)
+			}
+		}
+		if transition.evt != "" && !strings.HasPrefix(transition.evt, "EVT_") {
+			return nil, fmt.Errorf("line %d: bad event %q", transition.line, transition.evt)
+		}
+		classes := transition.classes
+		if len(classes) == 1 && classes[0] == "*" {
+			classes = append([]string{"OTHER"}, g.classes...)
+		}
+		for _, stateName := range append(states, transition.states...) {
+			state, exists := g.stateIndex[stateName]
+			if !exists || state < 0 {
+				return nil, fmt.Errorf("line %d: unknown table state %q", transition.line, stateName)
+			}
+			resolved := transition
+			if resolved.next == "" {
+				resolved.next = stateName
+			}
+			for _, className := range classes {
+				class, exists := g.classIndex[className]
+				if !exists && className != "OTHER" {
+					return nil, fmt.Errorf("line %d: unknown class %q", transition.line, className)
+				}
+				cells[[2]int{state, class}] = resolved
+			}
+		}
+	}
+	var result []grammarCell
+	for key, transition := range cells {
+		result = append(result, grammarCell{key[0], key[1], transition})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].state != result[j].state {
+			return result[i].state < result[j].state
+		}
+		return result[i].class < result[j].class
+	})
+	return result, nil
+}
+
+func (g *parserGrammar) className(class int) string {
+	if class == 0 {
+		return "CLASS_OTHER"
+	}
+	return "CLASS_" + g.classes[class-1]
+}
+
+func (g *parserGrammar) transitionLiteral(transition grammarTransition) string {
+	literal := fmt.Sprintf("ACT_%s | HANDLE_%s<<8", transition.action, transition.next)
+	if transition.push != "" {
+		literal += fmt.Sprintf(" | HANDLE_%s<<16", transition.push)
+	}
+	if transition.evt != "" {
+		literal += fmt.Sprintf(" | %s<<24", transition.evt)
+	}
+	return literal
+}
+
+func writeGrammarConst(out *bytes.Buffer, typeName, prefix string, names []string, comments map[string]string) {
+	fmt.Fprintf(out, "const ( // %s\n", typeName)
+	for i, name := range names {
+		if comment := comments[prefix+name]; comment != "" {
+			fmt.Fprintf(out, "\t// %s\n", comment)
+		}
+		if i == 0 {
+			fmt.Fprintf(out, "\t%s%s = iota\n", prefix, name)
+		} else {
+			fmt.Fprintf(out, "\t%s%s\n", prefix, name)
+		}
+	}
+	fmt.Fprintf(out, ")\n\n")
+}
+
+// generateParserTables writes the go source of the tables for a grammar
+func generateParserTables(text string) ([]byte, error) {
+	g, err := parseParserGrammar(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.states) == 0 || len(g.manual) == 0 {
+		return nil, fmt.Errorf("grammar needs table and manual states")
+	}
+	if len(g.classes) >= parserTableRow {
+		return nil, fmt.Errorf("%d byte classes do not fit a table row", len(g.classes)+1)
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated from Parser.grammar by %s; DO NOT EDIT.\n\npackage EvLJson\n\n", TEST_PARSER_TABLES_COMMAND)
+
+	writeGrammarConst(&out, "handle_t", "HANDLE_", append(append([]string{}, g.states...), g.manual...), g.comments)
+	fmt.Fprintf(&out, "// HANDLE_TABLE_STATES is the number of states dispatched through parserTable_t,\n// the manual states follow them\nconst HANDLE_TABLE_STATES = HANDLE_%s\n\n", g.manual[0])
+	writeGrammarConst(&out, "byteClass_t", "CLASS_", append(append([]string{"OTHER"}, g.classes...), "COUNT"), g.comments)
+	writeGrammarConst(&out, "parserAction_t", "ACT_", g.actions, g.comments)
+	if g.actions[0] != "ERROR" {
+		return nil, fmt.Errorf("the first action must be ERROR, the one of the empty transition")
+	}
+
+	fmt.Fprintf(&out, "var byteClasses = [256]byteClass_t{\n")
+	for b, className := range g.classBytes {
+		if className != "" {
+			fmt.Fprintf(&out, "\t%s: CLASS_%s,\n", strconv.QuoteRune(rune(b)), className)
+		}
+	}
+	fmt.Fprintf(&out, "}\n\n")
+
+	var base []grammarCell
+	for _, state := range g.states {
+		cells, err := g.expand(g.transitions[state], []string{state})
+		if err != nil {
+			return nil, err
+		}
+		base = append(base, cells...)
+	}
+	fmt.Fprintf(&out, "// parserBaseTable holds the transitions without any options, missing ones\n// are ACT_ERROR\nvar parserBaseTable = parserTable_t{\n")
+	for i, cell := range base {
+		if i == 0 || base[i-1].state != cell.state {
+			fmt.Fprintf(&out, "\tHANDLE_%s: {\n", g.states[cell.state])
+		}
+		fmt.Fprintf(&out, "\t\t%s: %s,\n", g.className(cell.class), g.transitionLiteral(cell.transition))
+		if i == len(base)-1 || base[i+1].state != cell.state {
+			fmt.Fprintf(&out, "\t},\n")
+		}
+	}
+	fmt.Fprintf(&out, "}\n\n")
+
+	fmt.Fprintf(&out, "// parserOverlays replace transitions of parserBaseTable, in order, when all\n// of their options are set\nvar parserOverlays = []parserOverlay{\n")
+	for _, overlay := range g.overlays {
+		cells, err := g.expand(overlay.transitions, nil)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, "\t{%s, []parserPatch{\n", strings.Join(overlay.options, " | "))
+		for _, cell := range cells {
+			fmt.Fprintf(&out, "\t\t{HANDLE_%s, %s, %s},\n", g.states[cell.state], g.className(cell.class), g.transitionLiteral(cell.transition))
+		}
+		fmt.Fprintf(&out, "\t}},\n")
+	}
+	fmt.Fprintf(&out, "}\n")
+	return format.Source(out.Bytes())
+}
+
+func TestParserTablesUpToDate(t *testing.T) {
+	grammar, err := os.ReadFile("Parser.grammar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated, err := generateParserTables(string(grammar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updateParserTables {
+		if err = os.WriteFile("ParserTables.go", generated, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	existing, err := os.ReadFile("ParserTables.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generated, existing) {
+		t.Fatal("ParserTables.go is stale, regenerate it with: " + TEST_PARSER_TABLES_COMMAND)
+	}
+}
+
+func TestParserGrammarErrors(t *testing.T) {
+	for _, grammar := range []string{
+		"class A a\nclass B a\n",
+		"action ERROR\nstate S\n\tA ERROR\nmanual M\n",
+		"action ERROR\nstate S\n\t* NOPE\nmanual M\n",
+		"action ERROR\nstate S\n\t* ERROR next=T\nmanual M\n",
+		"action ERROR\nstate S\n\t@nope\nmanual M\n",
+		"action ERROR\nstate S\n\t* ERROR size=1\nmanual M\n",
+		"\t* ERROR\n",
+		"action SKIP\nstate S\nmanual M\n",
+	} {
+		if _, err := generateParserTables(grammar); err == nil {
+			t.Fatalf("%q accepted", grammar)
+		}
+	}
+}
+
+func TestParserOverlays(t *testing.T) {
+	testCases := []struct {
+		json    string
+		options uint8
+		err     error
+	}{
+		{"[1,]", OPT_ALLOW_TRAILING_COMMAS, nil},
+		{"{\"a\":[{},],}", OPT_ALLOW_TRAILING_COMMAS, nil},
+		{" [ 1 , ] ", OPT_ALLOW_TRAILING_COMMAS | OPT_ALLOW_EXTRA_WHITESPACE | OPT_PARSE_UNTIL_EOF, nil},
+		{"[1,]", 0, unspecifiedParserError},
+		{"[,]", OPT_ALLOW_TRAILING_COMMAS, unspecifiedParserError},
+		{"[1,,]", OPT_ALLOW_TRAILING_COMMAS, unspecifiedParserError},
+		{"{,}", OPT_ALLOW_TRAILING_COMMAS, unspecifiedParserError},
+		{"[1e00]", 0, nil},
+		{"[1e-01]", OPT_STRICTER_EXPONENTS, nil},
+		{"[1e00]", OPT_STRICTER_EXPONENTS, invalidStricterExponentFormat},
+		{"[1] x", 0, nil},
+		{"[1] x", OPT_ALLOW_EXTRA_WHITESPACE, nil},
+		{"[1]\n", OPT_PARSE_UNTIL_EOF, unspecifiedParserError},
+		{"[1]\n", OPT_PARSE_UNTIL_EOF | OPT_ALLOW_EXTRA_WHITESPACE, nil},
+		{"[1]\n x", OPT_PARSE_UNTIL_EOF | OPT_ALLOW_EXTRA_WHITESPACE, unspecifiedParserError},
+		{"\n", OPT_PARSE_UNTIL_EOF | OPT_ALLOW_EXTRA_WHITESPACE, io.EOF},
+	}
+	for _, testCase := range testCases {
+		evLJsonParser := NewParser(nil, nil, testCase.options)
+		if err := evLJsonParser.Parse(bytes.NewReader([]byte(testCase.json)), nil, nil); err != testCase.err {
+			t.Fatalf("%q with options %#x: %v", testCase.json, testCase.options, err)
+		}
+	}
+	for options := 0; options < 256; options++ {
+		if parserTables[uint8(options)&parserOverlayOptions] == nil {
+			t.Fatalf("no table for options %#x", options)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+// Code generated from Parser.grammar by go test -run TestParserTablesUpToDate -update-tables; DO NOT EDIT.
+
+package EvLJson
+
+const ( // handle_t
+	HANDLE_START = iota
+	HANDLE_DICT_START
+	HANDLE_DICT_KV_DELIM
+	HANDLE_DICT_VALUE
+	HANDLE_DICT_VALUE_END
+	HANDLE_DICT_EXPECT_KEY
+	HANDLE_ARRAY_START
+	HANDLE_ARRAY_DELIM
+	HANDLE_ARRAY_EXPECT_ENTRY
+	// after the top level value
+	HANDLE_END
+	HANDLE_STRING
+	// a leading zero
+	HANDLE_ZD_EXP_START
+	HANDLE_INT
+	// after a minus sign
+	HANDLE_ZD_EXPN_START
+	HANDLE_DEC_FRAC_START
+	HANDLE_DEC_FRAC_END
+	HANDLE_EXP_COEF_START
+	// after the sign of an exponent
+	HANDLE_EXP_COEF_NEG
+	HANDLE_EXP_COEF_LZERO
+	HANDLE_EXP_COEF_END
+	HANDLE_STRING_RSP
+	HANDLE_HEX_NR
+	HANDLE_HEX_EVEN
+	HANDLE_HEX_ODD
+	HANDLE_HEX_UTF8
+	HANDLE_HEX_LS_RSP
+	HANDLE_HEX_LS_U
+	HANDLE_NULL
+	HANDLE_TRUE
+	HANDLE_FALSE
+)
+
+// HANDLE_TABLE_STATES is the number of states dispatched through parserTable_t,
+// the manual states follow them
+const HANDLE_TABLE_STATES = HANDLE_STRING_RSP
+
+const ( // byteClass_t
+	CLASS_OTHER = iota
+	CLASS_WS
+	CLASS_QUOTE
+	CLASS_BACKSLASH
+	CLASS_LBRACKET
+	CLASS_RBRACKET
+	CLASS_LBRACE
+	CLASS_RBRACE
+	CLASS_COLON
+	CLASS_COMMA
+	CLASS_MINUS
+	CLASS_PLUS
+	CLASS_ZERO
+	CLASS_DIGIT
+	CLASS_DOT
+	CLASS_EXP
+	CLASS_NULL
+	CLASS_TRUE
+	CLASS_FALSE
+	CLASS_COUNT
+)
+
+const ( // parserAction_t
+	// fail with UnspecifiedJsonParserError
+	ACT_ERROR = iota
+	// skip a run of whitespace, same state
+	ACT_WHITESPACE
+	// move to next
+	ACT_SKIP
+	// move to next, the byte is data
+	ACT_DATA
+	// the byte and the digits after it are data, same state
+	ACT_DIGITS
+	// the byte and the unescaped run after it are data, same state
+	ACT_STRING_DATA
+	// move to next, data after this is rewritten
+	ACT_ESCAPE
+	// fire evt, move to next, the byte is data
+	ACT_DATA_EVENT
+	// leave the number, the byte belongs to the parent
+	ACT_END_NUMBER
+	// leave the string or container the byte closes
+	ACT_CLOSE
+	// move to next, then enter push firing evt
+	ACT_PUSH
+	// move to next, then enter the number push
+	ACT_PUSH_NUMBER
+	// move to next, then fire evt and match the literal push
+	ACT_PUSH_LITERAL
+	// the document is over, stop without error
+	ACT_STOP
+	// fail with InvalidStricterExponentFormat
+	ACT_STRICT_EXPONENT
+)
+
+var byteClasses = [256]byteClass_t{
+	'\t': CLASS_WS,
+	'\n': CLASS_WS,
+	'\r': CLASS_WS,
+	' ':  CLASS_WS,
+	'"':  CLASS_QUOTE,
+	'+':  CLASS_PLUS,
+	',':  CLASS_COMMA,
+	'-':  CLASS_MINUS,
+	'.':  CLASS_DOT,
+	'0':  CLASS_ZERO,
+	'1':  CLASS_DIGIT,
+	'2':  CLASS_DIGIT,
+	'3':  CLASS_DIGIT,
+	'4':  CLASS_DIGIT,
+	'5':  CLASS_DIGIT,
+	'6':  CLASS_DIGIT,
+	'7':  CLASS_DIGIT,
+	'8':  CLASS_DIGIT,
+	'9':  CLASS_DIGIT,
+	':':  CLASS_COLON,
+	'E':  CLASS_EXP,
+	'[':  CLASS_LBRACKET,
+	'\\': CLASS_BACKSLASH,
+	']':  CLASS_RBRACKET,
+	'e':  CLASS_EXP,
+	'f':  CLASS_FALSE,
+	'n':  CLASS_NULL,
+	't':  CLASS_TRUE,
+	'{':  CLASS_LBRACE,
+	'}':  CLASS_RBRACE,
+}
+
+// parserBaseTable holds the transitions without any options, missing ones
+// are ACT_ERROR
+var parserBaseTable = parserTable_t{
+	HANDLE_START: {
+		CLASS_LBRACKET: ACT_PUSH | HANDLE_END<<8 | HANDLE_ARRAY_START<<16 | EVT_ARRAY<<24,
+		CLASS_LBRACE:   ACT_PUSH | HANDLE_END<<8 | HANDLE_DICT_START<<16 | EVT_DICT<<24,
+	},
+	HANDLE_DICT_START: {
+		CLASS_QUOTE:  ACT_PUSH | HANDLE_DICT_KV_DELIM<<8 | HANDLE_STRING<<16 | EVT_STRING<<24,
+		CLASS_RBRACE: ACT_CLOSE | HANDLE_DICT_START<<8,
+	},
+	HANDLE_DICT_KV_DELIM: {
+		CLASS_COLON: ACT_SKIP | HANDLE_DICT_VALUE<<8,
+	},
+	HANDLE_DICT_VALUE: {
+		CLASS_QUOTE:    ACT_PUSH | HANDLE_DICT_VALUE_END<<8 | HANDLE_STRING<<16 | EVT_STRING<<24,
+		CLASS_LBRACKET: ACT_PUSH | HANDLE_DICT_VALUE_END<<8 | HANDLE_ARRAY_START<<16 | EVT_ARRAY<<24,
+		CLASS_LBRACE:   ACT_PUSH | HANDLE_DICT_VALUE_END<<8 | HANDLE_DICT_START<<16 | EVT_DICT<<24,
+		CLASS_MINUS:    ACT_PUSH_NUMBER | HANDLE_DICT_VALUE_END<<8 | HANDLE_ZD_EXPN_START<<16,
+		CLASS_ZERO:     ACT_PUSH_NUMBER | HANDLE_DICT_VALUE_END<<8 | HANDLE_ZD_EXP_START<<16,
+		CLASS_DIGIT:    ACT_PUSH_NUMBER | HANDLE_DICT_VALUE_END<<8 | HANDLE_INT<<16,
+		CLASS_NULL:     ACT_PUSH_LITERAL | HANDLE_DICT_VALUE_END<<8 | HANDLE_NULL<<16 | EVT_NULL<<24,
+		CLASS_TRUE:     ACT_PUSH_LITERAL | HANDLE_DICT_VALUE_END<<8 | HANDLE_TRUE<<16 | EVT_TRUE<<24,
+		CLASS_FALSE:    ACT_PUSH_LITERAL | HANDLE_DICT_VALUE_END<<8 | HANDLE_FALSE<<16 | EVT_FALSE<<24,
+	},
+	HANDLE_DICT_VALUE_END: {
+		CLASS_RBRACE: ACT_CLOSE | HANDLE_DICT_VALUE_END<<8,
+		CLASS_COMMA:  ACT_SKIP | HANDLE_DICT_EXPECT_KEY<<8,
+	},
+	HANDLE_DICT_EXPECT_KEY: {
+		CLASS_QUOTE: ACT_PUSH | HANDLE_DICT_KV_DELIM<<8 | HANDLE_STRING<<16 | EVT_STRING<<24,
+	},
+	HANDLE_ARRAY_START: {
+		CLASS_QUOTE:    ACT_PUSH | HANDLE_ARRAY_DELIM<<8 | HANDLE_STRING<<16 | EVT_STRING<<24,
+		CLASS_LBRACKET: ACT_PUSH | HANDLE_ARRAY_DELIM<<8 | HANDLE_ARRAY_START<<16 | EVT_ARRAY<<24,
+		CLASS_RBRACKET: ACT_CLOSE | HANDLE_ARRAY_START<<8,
+		CLASS_LBRACE:   ACT_PUSH | HANDLE_ARRAY_DELIM<<8 | HANDLE_DICT_START<<16 | EVT_DICT<<24,
+		CLASS_MINUS:    ACT_PUSH_NUMBER | HANDLE_ARRAY_DELIM<<8 | HANDLE_ZD_EXPN_START<<16,
+		CLASS_ZERO:     ACT_PUSH_NUMBER | HANDLE_ARRAY_DELIM<<8 | HANDLE_ZD_EXP_START<<16,
+		CLASS_DIGIT:    ACT_PUSH_NUMBER | HANDLE_ARRAY_DELIM<<8 | HANDLE_INT<<16,
+		CLASS_NULL:     ACT_PUSH_LITERAL | HANDLE_ARRAY_DELIM<<8 | HANDLE_NULL<<16 | EVT_NULL<<24,
+		CLASS_TRUE:     ACT_PUSH_LITERAL | HANDLE_ARRAY_DELIM<<8 | HANDLE_TRUE<<16 | EVT_TRUE<<24,
+		CLASS_FALSE:    ACT_PUSH_LITERAL | HANDLE_ARRAY_DELIM<<8 | HANDLE_FALSE<<16 | EVT_FALSE<<24,
+	},
+	HANDLE_ARRAY_DELIM: {
+		CLASS_RBRACKET: ACT_CLOSE | HANDLE_ARRAY_DELIM<<8,
+		CLASS_COMMA:    ACT_SKIP | HANDLE_ARRAY_EXPECT_ENTRY<<8,
+	},
+	HANDLE_ARRAY_EXPECT_ENTRY: {
+		CLASS_QUOTE:    ACT_PUSH | HANDLE_ARRAY_DELIM<<8 | HANDLE_STRING<<16 | EVT_STRING<<24,
+		CLASS_LBRACKET: ACT_PUSH | HANDLE_ARRAY_DELIM<<8 | HANDLE_ARRAY_START<<16 | EVT_ARRAY<<24,
+		CLASS_LBRACE:   ACT_PUSH | HANDLE_ARRAY_DELIM<<8 | HANDLE_DICT_START<<16 | EVT_DICT<<24,
+		CLASS_MINUS:    ACT_PUSH_NUMBER | HANDLE_ARRAY_DELIM<<8 | HANDLE_ZD_EXPN_START<<16,
+		CLASS_ZERO:     ACT_PUSH_NUMBER | HANDLE_ARRAY_DELIM<<8 | HANDLE_ZD_EXP_START<<16,
+		CLASS_DIGIT:    ACT_PUSH_NUMBER | HANDLE_ARRAY_DELIM<<8 | HANDLE_INT<<16,
+		CLASS_NULL:     ACT_PUSH_LITERAL | HANDLE_ARRAY_DELIM<<8 | HANDLE_NULL<<16 | EVT_NULL<<24,
+		CLASS_TRUE:     ACT_PUSH_LITERAL | HANDLE_ARRAY_DELIM<<8 | HANDLE_TRUE<<16 | EVT_TRUE<<24,
+		CLASS_FALSE:    ACT_PUSH_LITERAL | HANDLE_ARRAY_DELIM<<8 | HANDLE_FALSE<<16 | EVT_FALSE<<24,
+	},
+	HANDLE_END: {
+		CLASS_OTHER:     ACT_STOP | HANDLE_END<<8,
+		CLASS_WS:        ACT_STOP | HANDLE_END<<8,
+		CLASS_QUOTE:     ACT_STOP | HANDLE_END<<8,
+		CLASS_BACKSLASH: ACT_STOP | HANDLE_END<<8,
+		CLASS_LBRACKET:  ACT_STOP | HANDLE_END<<8,
+		CLASS_RBRACKET:  ACT_STOP | HANDLE_END<<8,
+		CLASS_LBRACE:    ACT_STOP | HANDLE_END<<8,
+		CLASS_RBRACE:    ACT_STOP | HANDLE_END<<8,
+		CLASS_COLON:     ACT_STOP | HANDLE_END<<8,
+		CLASS_COMMA:     ACT_STOP | HANDLE_END<<8,
+		CLASS_MINUS:     ACT_STOP | HANDLE_END<<8,
+		CLASS_PLUS:      ACT_STOP | HANDLE_END<<8,
+		CLASS_ZERO:      ACT_STOP | HANDLE_END<<8,
+		CLASS_DIGIT:     ACT_STOP | HANDLE_END<<8,
+		CLASS_DOT:       ACT_STOP | HANDLE_END<<8,
+		CLASS_EXP:       ACT_STOP | HANDLE_END<<8,
+		CLASS_NULL:      ACT_STOP | HANDLE_END<<8,
+		CLASS_TRUE:      ACT_STOP | HANDLE_END<<8,
+		CLASS_FALSE:     ACT_STOP | HANDLE_END<<8,
+	},
+	HANDLE_STRING: {
+		CLASS_OTHER:     ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_WS:        ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_QUOTE:     ACT_CLOSE | HANDLE_STRING<<8,
+		CLASS_BACKSLASH: ACT_ESCAPE | HANDLE_STRING_RSP<<8,
+		CLASS_LBRACKET:  ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_RBRACKET:  ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_LBRACE:    ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_RBRACE:    ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_COLON:     ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_COMMA:     ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_MINUS:     ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_PLUS:      ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_ZERO:      ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_DIGIT:     ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_DOT:       ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_EXP:       ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_NULL:      ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_TRUE:      ACT_STRING_DATA | HANDLE_STRING<<8,
+		CLASS_FALSE:     ACT_STRING_DATA | HANDLE_STRING<<8,
+	},
+	HANDLE_ZD_EXP_START: {
+		CLASS_OTHER:     ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_WS:        ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_QUOTE:     ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_BACKSLASH: ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_LBRACKET:  ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_RBRACKET:  ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_LBRACE:    ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_RBRACE:    ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_COLON:     ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_COMMA:     ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_MINUS:     ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_PLUS:      ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_ZERO:      ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_DIGIT:     ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_DOT:       ACT_DATA_EVENT | HANDLE_DEC_FRAC_START<<8 | EVT_DECIMAL<<24,
+		CLASS_EXP:       ACT_DATA_EVENT | HANDLE_EXP_COEF_START<<8 | EVT_EXPONENT<<24,
+		CLASS_NULL:      ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_TRUE:      ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+		CLASS_FALSE:     ACT_END_NUMBER | HANDLE_ZD_EXP_START<<8,
+	},
+	HANDLE_INT: {
+		CLASS_OTHER:     ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_WS:        ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_QUOTE:     ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_BACKSLASH: ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_LBRACKET:  ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_RBRACKET:  ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_LBRACE:    ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_RBRACE:    ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_COLON:     ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_COMMA:     ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_MINUS:     ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_PLUS:      ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_ZERO:      ACT_DIGITS | HANDLE_INT<<8,
+		CLASS_DIGIT:     ACT_DIGITS | HANDLE_INT<<8,
+		CLASS_DOT:       ACT_DATA_EVENT | HANDLE_DEC_FRAC_START<<8 | EVT_DECIMAL<<24,
+		CLASS_EXP:       ACT_DATA_EVENT | HANDLE_EXP_COEF_START<<8 | EVT_EXPONENT<<24,
+		CLASS_NULL:      ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_TRUE:      ACT_END_NUMBER | HANDLE_INT<<8,
+		CLASS_FALSE:     ACT_END_NUMBER | HANDLE_INT<<8,
+	},
+	HANDLE_ZD_EXPN_START: {
+		CLASS_ZERO:  ACT_DATA | HANDLE_ZD_EXP_START<<8,
+		CLASS_DIGIT: ACT_DATA | HANDLE_INT<<8,
+	},
+	HANDLE_DEC_FRAC_START: {
+		CLASS_OTHER:     ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_WS:        ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_QUOTE:     ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_BACKSLASH: ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_LBRACKET:  ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_RBRACKET:  ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_LBRACE:    ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_RBRACE:    ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_COLON:     ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_COMMA:     ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_MINUS:     ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_PLUS:      ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_ZERO:      ACT_DATA | HANDLE_DEC_FRAC_END<<8,
+		CLASS_DIGIT:     ACT_DATA | HANDLE_DEC_FRAC_END<<8,
+		CLASS_DOT:       ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_EXP:       ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_NULL:      ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_TRUE:      ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+		CLASS_FALSE:     ACT_END_NUMBER | HANDLE_DEC_FRAC_START<<8,
+	},
+	HANDLE_DEC_FRAC_END: {
+		CLASS_OTHER:     ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_WS:        ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_QUOTE:     ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_BACKSLASH: ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_LBRACKET:  ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_RBRACKET:  ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_LBRACE:    ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_RBRACE:    ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_COLON:     ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_COMMA:     ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_MINUS:     ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_PLUS:      ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_ZERO:      ACT_DIGITS | HANDLE_DEC_FRAC_END<<8,
+		CLASS_DIGIT:     ACT_DIGITS | HANDLE_DEC_FRAC_END<<8,
+		CLASS_DOT:       ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_EXP:       ACT_DATA_EVENT | HANDLE_EXP_COEF_START<<8 | EVT_EXPONENT<<24,
+		CLASS_NULL:      ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_TRUE:      ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+		CLASS_FALSE:     ACT_END_NUMBER | HANDLE_DEC_FRAC_END<<8,
+	},
+	HANDLE_EXP_COEF_START: {
+		CLASS_MINUS: ACT_DATA | HANDLE_EXP_COEF_NEG<<8,
+		CLASS_PLUS:  ACT_DATA | HANDLE_EXP_COEF_NEG<<8,
+		CLASS_ZERO:  ACT_DATA | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_DIGIT: ACT_DATA | HANDLE_EXP_COEF_END<<8,
+	},
+	HANDLE_EXP_COEF_NEG: {
+		CLASS_ZERO:  ACT_DATA | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_DIGIT: ACT_DATA | HANDLE_EXP_COEF_END<<8,
+	},
+	HANDLE_EXP_COEF_LZERO: {
+		CLASS_OTHER:     ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_WS:        ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_QUOTE:     ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_BACKSLASH: ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_LBRACKET:  ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_RBRACKET:  ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_LBRACE:    ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_RBRACE:    ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_COLON:     ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_COMMA:     ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_MINUS:     ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_PLUS:      ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_ZERO:      ACT_DATA | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_DIGIT:     ACT_DATA | HANDLE_EXP_COEF_END<<8,
+		CLASS_DOT:       ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_EXP:       ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_NULL:      ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_TRUE:      ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+		CLASS_FALSE:     ACT_END_NUMBER | HANDLE_EXP_COEF_LZERO<<8,
+	},
+	HANDLE_EXP_COEF_END: {
+		CLASS_OTHER:     ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_WS:        ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_QUOTE:     ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_BACKSLASH: ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_LBRACKET:  ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_RBRACKET:  ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_LBRACE:    ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_RBRACE:    ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_COLON:     ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_COMMA:     ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_MINUS:     ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_PLUS:      ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_ZERO:      ACT_DIGITS | HANDLE_EXP_COEF_END<<8,
+		CLASS_DIGIT:     ACT_DIGITS | HANDLE_EXP_COEF_END<<8,
+		CLASS_DOT:       ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_EXP:       ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_NULL:      ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_TRUE:      ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+		CLASS_FALSE:     ACT_END_NUMBER | HANDLE_EXP_COEF_END<<8,
+	},
+}
+
+// parserOverlays replace transitions of parserBaseTable, in order, when all
+// of their options are set
+var parserOverlays = []parserOverlay{
+	{OPT_ALLOW_EXTRA_WHITESPACE, []parserPatch{
+		{HANDLE_START, CLASS_WS, ACT_WHITESPACE | HANDLE_START<<8},
+		{HANDLE_DICT_START, CLASS_WS, ACT_WHITESPACE | HANDLE_DICT_START<<8},
+		{HANDLE_DICT_KV_DELIM, CLASS_WS, ACT_WHITESPACE | HANDLE_DICT_KV_DELIM<<8},
+		{HANDLE_DICT_VALUE, CLASS_WS, ACT_WHITESPACE | HANDLE_DICT_VALUE<<8},
+		{HANDLE_DICT_VALUE_END, CLASS_WS, ACT_WHITESPACE | HANDLE_DICT_VALUE_END<<8},
+		{HANDLE_DICT_EXPECT_KEY, CLASS_WS, ACT_WHITESPACE | HANDLE_DICT_EXPECT_KEY<<8},
+		{HANDLE_ARRAY_START, CLASS_WS, ACT_WHITESPACE | HANDLE_ARRAY_START<<8},
+		{HANDLE_ARRAY_DELIM, CLASS_WS, ACT_WHITESPACE | HANDLE_ARRAY_DELIM<<8},
+		{HANDLE_ARRAY_EXPECT_ENTRY, CLASS_WS, ACT_WHITESPACE | HANDLE_ARRAY_EXPECT_ENTRY<<8},
+	}},
+	{OPT_PARSE_UNTIL_EOF, []parserPatch{
+		{HANDLE_END, CLASS_OTHER, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_WS, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_QUOTE, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_BACKSLASH, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_LBRACKET, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_RBRACKET, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_LBRACE, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_RBRACE, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_COLON, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_COMMA, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_MINUS, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_PLUS, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_ZERO, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_DIGIT, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_DOT, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_EXP, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_NULL, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_TRUE, ACT_ERROR | HANDLE_END<<8},
+		{HANDLE_END, CLASS_FALSE, ACT_ERROR | HANDLE_END<<8},
+	}},
+	{OPT_PARSE_UNTIL_EOF | OPT_ALLOW_EXTRA_WHITESPACE, []parserPatch{
+		{HANDLE_END, CLASS_WS, ACT_WHITESPACE | HANDLE_END<<8},
+	}},
+	{OPT_STRICTER_EXPONENTS, []parserPatch{
+		{HANDLE_EXP_COEF_LZERO, CLASS_ZERO, ACT_STRICT_EXPONENT | HANDLE_EXP_COEF_LZERO<<8},
+	}},
+	{OPT_ALLOW_TRAILING_COMMAS, []parserPatch{
+		{HANDLE_DICT_EXPECT_KEY, CLASS_RBRACE, ACT_CLOSE | HANDLE_DICT_EXPECT_KEY<<8},
+		{HANDLE_ARRAY_EXPECT_ENTRY, CLASS_RBRACKET, ACT_CLOSE | HANDLE_ARRAY_EXPECT_ENTRY<<8},
+	}},
+}
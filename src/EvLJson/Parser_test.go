@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"io"
 	"log"
+	"strings"
 	"testing"
 )
 
@@ -340,3 +341,452 @@ func BenchmarkSpeedupHexConversion(b *testing.B) {
 		log.Fatal(err)
 	}
 }
+
+func collectStringData(jsonString string, dataBuffer []byte, options uint8) ([]string, error) {
+	var values []string
+	var value []byte
+	reader := bytes.NewReader([]byte(jsonString))
+	evLJsonParser := NewParser(dataBuffer, nil, options)
+	onEvent := func(parser *Parser, evt event_t) {
+		if evt == EVT_LEAVE && value != nil {
+			values = append(values, string(value))
+			value = nil
+		}
+	}
+	onData := func(parser *Parser, endOfData bool) {
+		value = append(value, parser.DataBuffer...)
+	}
+	err := evLJsonParser.Parse(reader, onEvent, onData)
+	return values, err
+}
+
+func TestNumberData(t *testing.T) {
+	values, err := collectStringData("[123,-4,0,0.5,-0.5e-7,1E5,2e+3]", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"123", "-4", "0", "0.5", "-0.5e-7", "1E5", "2e+3"}
+	if len(values) != len(expected) {
+		t.Fatalf("%q", values)
+	}
+	for i, str := range expected {
+		if values[i] != str {
+			t.Fatalf("%q != %q", values[i], str)
+		}
+	}
+}
+
+func TestExponentForms(t *testing.T) {
+	for _, options := range []uint8{0, OPT_STRICTER_EXPONENTS} {
+		values, err := collectStringData("[1E+2,1e+2,1E-2,1E2,0E+0,-1.5E+02]", nil, options)
+		if err != nil {
+			t.Fatal(options, err)
+		}
+		if strings.Join(values, " ") != "1E+2 1e+2 1E-2 1E2 0E+0 -1.5E+02" {
+			t.Fatalf("%d: %q", options, values)
+		}
+	}
+	for _, bad := range []string{"[1E]", "[1E+]", "[1e+-2]", "[1+2]", "[1E++2]"} {
+		if err := parseStringWithoutCallbacksOrOptions(bad); err == nil {
+			t.Fatal(bad)
+		}
+	}
+}
+
+func TestNewParserBuffers(t *testing.T) {
+	for _, dataBuffer := range [][]byte{nil, make([]byte, 1), make([]byte, 2, 2)} {
+		evLJsonParser := NewParser(dataBuffer, make([]handle_t, 1), 0)
+		if len(evLJsonParser.DataBuffer) != 0 || cap(evLJsonParser.DataBuffer) < MIN_DATA_BUFFER_SIZE || cap(evLJsonParser.ContextStack) < MIN_STACK_DEPTH {
+			t.Fatal(len(dataBuffer), cap(evLJsonParser.DataBuffer), cap(evLJsonParser.ContextStack))
+		}
+		if err := evLJsonParser.Parse(bytes.NewReader([]byte(`["a long string",12345]`)), nil, func(*Parser, bool) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a buffer large enough is used as it is, emptied
+	dataBuffer := make([]byte, 5, TEST_DATA_BUFFER_SIZE)
+	evLJsonParser := NewParser(dataBuffer, nil, 0)
+	if len(evLJsonParser.DataBuffer) != 0 || &evLJsonParser.DataBuffer[:1][0] != &dataBuffer[0] {
+		t.Fatal(cap(evLJsonParser.DataBuffer))
+	}
+	utf8Parser := NewParser(make([]byte, 0, MIN_DATA_BUFFER_SIZE), nil, OPT_DECODE_UNICODE_ESCAPES)
+	if cap(utf8Parser.DataBuffer) < MIN_UTF8_DATA_BUFFER_SIZE {
+		t.Fatal(cap(utf8Parser.DataBuffer))
+	}
+}
+
+func TestHexShortEscapes(t *testing.T) {
+	testCases := [][2]string{
+		{`["\u00e9"]`, "\x00\xe9"},
+		{`["a\u20ACb\uFfFf"]`, "a\x20\xacb\xff\xff"},
+		{`["\u0041\u0042\u0043"]`, "\x00A\x00B\x00C"},
+	}
+	for _, testCase := range testCases {
+		t.Logf(LOG_STMT_FMT, testCase[0])
+		for _, dataBuffer := range [][]byte{nil, make([]byte, 0, TEST_DATA_BUFFER_SIZE)} {
+			values, err := collectStringData(testCase[0], dataBuffer, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(values) != 1 || values[0] != testCase[1] {
+				t.Fatalf("%q != %q", values, testCase[1])
+			}
+		}
+	}
+	for _, str := range []string{`["\u00g0"]`, `["\u0g00"]`, `["\u00"]`, `["\u"]`} {
+		if _, err := collectStringData(str, nil, 0); err == nil {
+			t.Fatalf("%q accepted", str)
+		}
+	}
+}
+
+func TestDecodeUnicodeEscapes(t *testing.T) {
+	testCases := [][2]string{
+		{`["\u00e9"]`, "\u00e9"},
+		{`["a\u20ACb"]`, "a\u20acb"},
+		{`["\ud83d\ude00"]`, "\U0001f600"},
+		{`["\ud83dx"]`, "\ufffdx"},
+		{`["\ud83d\n"]`, "\ufffd\n"},
+		{`["\ud83dA"]`, "\ufffdA"},
+		{`["\ude00"]`, "\ufffd"},
+		{`["\ud83d"]`, "\ufffd"},
+	}
+	for _, testCase := range testCases {
+		t.Logf(LOG_STMT_FMT, testCase[0])
+		for _, dataBuffer := range [][]byte{nil, make([]byte, 0, TEST_DATA_BUFFER_SIZE)} {
+			values, err := collectStringData(testCase[0], dataBuffer, OPT_DECODE_UNICODE_ESCAPES)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(values) != 1 || values[0] != testCase[1] {
+				t.Fatalf("%q != %q", values, testCase[1])
+			}
+		}
+	}
+}
+
+func BenchmarkParseBytesWithCallbacks(b *testing.B) {
+	var err error
+	dataBuffer := make([]byte, TEST_DATA_BUFFER_SIZE)
+	evLJsonParser := NewParser(dataBuffer, nil, 0)
+	onData := func(parser *Parser, endOfData bool) {}
+
+	for i := 0; i < b.N; i++ {
+		if err = evLJsonParser.ParseBytes(BENCHMARK_BYTES, nil, onData); err == nil {
+			evLJsonParser.Reset()
+			continue
+		}
+		log.Fatal(err)
+	}
+}
+
+type dataCall struct {
+	data      string
+	aliased   bool
+	endOfData bool
+}
+
+func collectDataCalls(jsonString string, dataBuffer []byte, options uint8, zeroCopy bool) ([]string, []dataCall, error) {
+	var values []string
+	var calls []dataCall
+	var value []byte
+	input := []byte(jsonString)
+	evLJsonParser := NewParser(dataBuffer, nil, options)
+	onEvent := func(parser *Parser, evt event_t) {
+		if evt == EVT_LEAVE && value != nil {
+			values = append(values, string(value))
+			value = nil
+		}
+	}
+	onData := func(parser *Parser, endOfData bool) {
+		if parser.DataAliasesInput && cap(parser.DataBuffer) != len(parser.DataBuffer) {
+			log.Fatal("aliased DataBuffer can be appended to")
+		}
+		value = append(value, parser.DataBuffer...)
+		calls = append(calls, dataCall{string(parser.DataBuffer), parser.DataAliasesInput, endOfData})
+	}
+	var err error
+	if zeroCopy {
+		err = evLJsonParser.ParseBytes(input, onEvent, onData)
+	} else {
+		err = evLJsonParser.Parse(bytes.NewReader(input), onEvent, onData)
+	}
+	return values, calls, err
+}
+
+func TestParseBytesMatchesParse(t *testing.T) {
+	docs := []string{
+		`["plain", "", "esc\"aped", "\\", "tail\n", -12.5e+3, 0, {"kéy": [true, null, "xéy"]}]`,
+		`{"a": "😀 smile", "b": "a long string without any escapes in it", "c": 1234567890}`,
+	}
+	for _, doc := range docs {
+		for _, options := range []uint8{OPT_ALLOW_EXTRA_WHITESPACE, OPT_ALLOW_EXTRA_WHITESPACE | OPT_DECODE_UNICODE_ESCAPES} {
+			expected, _, err := collectDataCalls(doc, nil, options, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values, _, err := collectDataCalls(doc, nil, options, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Logf(LOG_STMT_FMT, doc)
+			if len(values) != len(expected) {
+				t.Fatalf("%q != %q", values, expected)
+			}
+			for i := range expected {
+				if values[i] != expected[i] {
+					t.Fatalf("%q != %q", values[i], expected[i])
+				}
+			}
+		}
+	}
+}
+
+func TestParseBytesAliases(t *testing.T) {
+	_, calls, err := collectDataCalls(`["a plain string", "split\there", "\"quoted", 12.5e3]`, make([]byte, 0, TEST_DATA_BUFFER_SIZE), OPT_ALLOW_EXTRA_WHITESPACE, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []dataCall{
+		{"a plain string", true, DATA_END},
+		{"split", true, DATA_CONTINUES},
+		{"\there", false, DATA_END},
+		{"\"quoted", false, DATA_END},
+		{"12.5e3", true, DATA_END},
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("%+v", calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("%+v != %+v", calls[i], expected[i])
+		}
+	}
+}
+
+func TestParseBytesStop(t *testing.T) {
+	dataBuffer := make([]byte, 0, TEST_DATA_BUFFER_SIZE)
+	evLJsonParser := NewParser(dataBuffer, nil, 0)
+	onData := func(parser *Parser, endOfData bool) {
+		parser.ParseStop()
+	}
+	if err := evLJsonParser.ParseBytes([]byte(`["stop here"]`), nil, onData); err != nil {
+		t.Fatal(err)
+	}
+	if evLJsonParser.DataAliasesInput || cap(evLJsonParser.DataBuffer) != TEST_DATA_BUFFER_SIZE {
+		t.Fatal("DataBuffer left aliasing the input")
+	}
+}
+
+func TestParseStringSpills(t *testing.T) {
+	_, calls, err := collectDataCalls(`["abcdefgh","ab\"cdefg"]`, make([]byte, 0, 4), 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []dataCall{
+		{"abcd", false, DATA_CONTINUES},
+		{"efgh", false, DATA_END},
+		{"ab\"c", false, DATA_CONTINUES},
+		{"defg", false, DATA_END},
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("%+v", calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("%+v != %+v", calls[i], expected[i])
+		}
+	}
+	evLJsonParser := NewParser(make([]byte, 0, 4), nil, 0)
+	var stops int
+	onData := func(parser *Parser, endOfData bool) {
+		stops++
+		parser.ParseStop()
+	}
+	if err := evLJsonParser.Parse(strings.NewReader(`["abcdefgh"]`), nil, onData); err != nil || stops != 1 {
+		t.Fatal(stops, err)
+	}
+}
+
+// allocTestDocs are free of whitespace, so every option combination
+// accepts them
+func allocTestDocs() [][]byte {
+	deep := strings.Repeat(`{"a":[`, 200) + "0" + strings.Repeat("]}", 200)
+	return [][]byte{
+		[]byte(`{"s":"x\"\\\/\b\f\n\r\t\u00e9\uD83D\uDE00\u00C9\uD800","n":[1,-0.5e+10,0E-1,2e01],"l":[true,false,null]}`),
+		[]byte(deep),
+	}
+}
+
+// allocTestParse parses doc with a warmed up parser of every combination
+// of options, with and without callbacks
+func allocTestParse(parsers []Parser, reader *bytes.Reader, doc []byte, zeroCopy bool) {
+	onEvent := func(*Parser, event_t) {}
+	onData := func(*Parser, bool) {}
+	for i := range parsers {
+		for _, callbacks := range []bool{true, false} {
+			evLJsonParser := &parsers[i]
+			evLJsonParser.Reset()
+			reader.Reset(doc)
+			var err error
+			switch {
+			case zeroCopy && callbacks:
+				err = evLJsonParser.ParseBytes(doc, onEvent, onData)
+			case zeroCopy:
+				err = evLJsonParser.ParseBytes(doc, nil, nil)
+			case callbacks:
+				err = evLJsonParser.Parse(reader, onEvent, onData)
+			default:
+				err = evLJsonParser.Parse(reader, nil, nil)
+			}
+			if err != nil {
+				log.Fatalf("options %#x: %s", i, err)
+			}
+		}
+	}
+}
+
+func allocTestParsers() []Parser {
+	parsers := make([]Parser, OPT_TRACK_LINES<<1)
+	for options := range parsers {
+		parsers[options] = NewParser(make([]byte, 0, TEST_DATA_BUFFER_SIZE), nil, uint8(options))
+	}
+	return parsers
+}
+
+func TestParseZeroAllocs(t *testing.T) {
+	parsers, reader := allocTestParsers(), bytes.NewReader(nil)
+	for _, doc := range allocTestDocs() {
+		for _, zeroCopy := range []bool{false, true} {
+			allocTestParse(parsers, reader, doc, zeroCopy)
+			if allocs := testing.AllocsPerRun(10, func() { allocTestParse(parsers, reader, doc, zeroCopy) }); allocs != 0 {
+				t.Fatalf("%q, zero copy %v: %v allocations", doc[:20], zeroCopy, allocs)
+			}
+		}
+	}
+}
+
+func BenchmarkParseAllocs(b *testing.B) {
+	parsers, reader, docs := allocTestParsers(), bytes.NewReader(nil), allocTestDocs()
+	for _, doc := range docs {
+		allocTestParse(parsers, reader, doc, false)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, doc := range docs {
+			allocTestParse(parsers, reader, doc, false)
+			allocTestParse(parsers, reader, doc, true)
+		}
+	}
+}
+
+// recordingHandler writes the events it receives and the data, ended by
+// a bar, to a buffer; recordingCallbacks are its methods for Parse
+type recordingHandler struct {
+	out *bytes.Buffer
+}
+
+func (h recordingHandler) OnEvent(p *Parser, evt event_t) {
+	h.out.WriteByte('0' + byte(evt))
+	if evt == EVT_STRING && p.IsDictKey() {
+		h.out.WriteByte('k')
+	}
+}
+
+func (h recordingHandler) OnData(p *Parser, endOfData bool) {
+	h.out.Write(p.DataBuffer)
+	if endOfData {
+		h.out.WriteByte('|')
+	}
+}
+
+func recordingCallbacks(out *bytes.Buffer) (eventReceiver_t, dataReceiver_t) {
+	h := recordingHandler{out}
+	return h.OnEvent, h.OnData
+}
+
+func TestParseWithMatchesParse(t *testing.T) {
+	docs := append(whitespaceTestCases(), TEST_VALIDATE_DOC, string(BENCHMARK_BYTES), `{"a": [1, 2,, 3]}`)
+	for _, doc := range docs {
+		for _, options := range []uint8{OPT_ALLOW_EXTRA_WHITESPACE, OPT_ALLOW_EXTRA_WHITESPACE | OPT_DECODE_UNICODE_ESCAPES} {
+			var expected, out bytes.Buffer
+			onEvent, onData := recordingCallbacks(&expected)
+			evLJsonParser := NewParser(make([]byte, 0, MIN_UTF8_DATA_BUFFER_SIZE), nil, options)
+			expectedErr := evLJsonParser.Parse(bytes.NewReader([]byte(doc)), onEvent, onData)
+			evLJsonParser = NewParser(make([]byte, 0, MIN_UTF8_DATA_BUFFER_SIZE), nil, options)
+			err := parseWith(&evLJsonParser, bytes.NewReader([]byte(doc)), recordingHandler{&out})
+			if err != expectedErr || out.String() != expected.String() {
+				t.Fatalf("%q: %v %q != %v %q", doc, err, out.String(), expectedErr, expected.String())
+			}
+			out.Reset()
+			evLJsonParser = NewParser(make([]byte, 0, MIN_UTF8_DATA_BUFFER_SIZE), nil, options)
+			err = parseBytesWith(&evLJsonParser, []byte(doc), recordingHandler{&out})
+			if err != expectedErr || out.String() != expected.String() {
+				t.Fatalf("%q: zero copy: %v %q != %v %q", doc, err, out.String(), expectedErr, expected.String())
+			}
+		}
+	}
+}
+
+// stopHandler stops the parser at the first data
+type stopHandler struct {
+	events *int
+}
+
+func (h stopHandler) OnEvent(p *Parser, evt event_t) {
+	*h.events++
+}
+
+func (h stopHandler) OnData(p *Parser, endOfData bool) {
+	p.ParseStop()
+}
+
+func TestParseWithStop(t *testing.T) {
+	events := 0
+	evLJsonParser := NewParser(nil, nil, 0)
+	if err := parseWith(&evLJsonParser, bytes.NewReader([]byte(`["stop here",1,2]`)), stopHandler{&events}); err != nil {
+		t.Fatal(err)
+	}
+	// EVT_ENTER, EVT_ARRAY, EVT_ENTER, EVT_STRING and nothing after the data
+	if events != 4 {
+		t.Fatalf("%d events", events)
+	}
+}
+
+// countingHandler is the handler of the parseWith alloc tests
+type countingHandler struct {
+	strings *int
+}
+
+func (h countingHandler) OnEvent(p *Parser, evt event_t) {
+	if evt == EVT_STRING {
+		*h.strings++
+	}
+}
+
+func (h countingHandler) OnData(p *Parser, endOfData bool) {}
+
+func TestParseWithZeroAllocs(t *testing.T) {
+	strings := 0
+	parsers, reader := allocTestParsers(), bytes.NewReader(nil)
+	for _, doc := range allocTestDocs() {
+		parse := func() {
+			for i := range parsers {
+				parsers[i].Reset()
+				reader.Reset(doc)
+				if err := parseWith(&parsers[i], reader, countingHandler{&strings}); err != nil {
+					log.Fatal(err)
+				}
+				parsers[i].Reset()
+				if err := parseBytesWith(&parsers[i], doc, countingHandler{&strings}); err != nil {
+					log.Fatal(err)
+				}
+			}
+		}
+		parse()
+		if allocs := testing.AllocsPerRun(10, parse); allocs != 0 {
+			t.Fatalf("%q: %v allocations", doc[:20], allocs)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package EvLJson
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	POOL_DATA_BUFFER_SIZE     = 512
+	POOL_MAX_DATA_BUFFER_SIZE = 1 << 16
+	POOL_STACK_DEPTH          = 32
+	POOL_MAX_STACK_DEPTH      = 1 << 12
+)
+
+// Pool recycles parsers of one set of options along with their buffers,
+// see the buffer recycling of docs/references.txt; the zero Pool hands out
+// parsers without options and a Pool must not be copied after first use
+//
+// New parsers are sized from the parsers put back: their context stack as
+// deep as any document has needed and their DataBuffer doubled whenever
+// values did not fit, up to POOL_MAX_STACK_DEPTH and
+// POOL_MAX_DATA_BUFFER_SIZE
+type Pool struct {
+	Options uint8
+
+	parsers  sync.Pool
+	depth    atomic.Int32
+	dataSize atomic.Int32
+}
+
+func (pool *Pool) sizes() (int, int) {
+	depth, dataSize := int(pool.depth.Load()), int(pool.dataSize.Load())
+	if depth == 0 {
+		depth = POOL_STACK_DEPTH
+	}
+	if dataSize == 0 {
+		dataSize = POOL_DATA_BUFFER_SIZE
+	}
+	return depth, dataSize
+}
+
+// grow raises size to at least observed, up to max
+func poolGrow(size *atomic.Int32, observed int, max int) {
+	if observed > max {
+		observed = max
+	}
+	for {
+		current := size.Load()
+		if int(current) >= observed || size.CompareAndSwap(current, int32(observed)) {
+			return
+		}
+	}
+}
+
+// Get is a reset parser with the options of the pool
+func (pool *Pool) Get() *Parser {
+	if p, ok := pool.parsers.Get().(*Parser); ok {
+		return p
+	}
+	depth, dataSize := pool.sizes()
+	p := NewParser(make([]byte, 0, dataSize), make([]handle_t, 0, depth), pool.Options)
+	return &p
+}
+
+// Put resets p and keeps it for a later Get, learning from what its last
+// documents needed; parsers of other options are left to the collector
+func (pool *Pool) Put(p *Parser) {
+	if p.options != pool.Options {
+		return
+	}
+	if p.spills != 0 {
+		poolGrow(&pool.dataSize, 2*cap(p.DataBuffer), POOL_MAX_DATA_BUFFER_SIZE)
+	}
+	poolGrow(&pool.depth, cap(p.ContextStack), POOL_MAX_STACK_DEPTH)
+	p.Reset()
+	p.UserData, p.spills = nil, 0
+	depth, dataSize := pool.sizes()
+	if cap(p.DataBuffer) < dataSize || cap(p.DataBuffer) > POOL_MAX_DATA_BUFFER_SIZE {
+		p.DataBuffer = make([]byte, 0, dataSize)
+	}
+	if cap(p.ContextStack) > POOL_MAX_STACK_DEPTH {
+		p.ContextStack = make([]handle_t, 0, depth)
+	}
+	if cap(p.BatchData) > POOL_MAX_DATA_BUFFER_SIZE {
+		p.BatchData = nil
+	}
+	pool.parsers.Put(p)
+}
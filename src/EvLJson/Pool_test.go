@@ -0,0 +1,179 @@
+package EvLJson
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+// reuseTestDocs are parsed by reused parsers and compared with new ones;
+// the last is truncated so errors are compared too
+var reuseTestDocs = []string{TEST_VALIDATE_DOC, TEST_BATCH_DOC, `{"a": [1`}
+
+// stopAfterHandler records like recordingHandler and stops the parser at
+// its stopAt-th event or data
+type stopAfterHandler struct {
+	recordingHandler
+	calls  *int
+	stopAt int
+}
+
+func (h stopAfterHandler) count(p *Parser) {
+	if *h.calls++; *h.calls == h.stopAt {
+		p.ParseStop()
+	}
+}
+
+func (h stopAfterHandler) OnEvent(p *Parser, evt event_t) {
+	h.recordingHandler.OnEvent(p, evt)
+	h.count(p)
+}
+
+func (h stopAfterHandler) OnData(p *Parser, endOfData bool) {
+	h.recordingHandler.OnData(p, endOfData)
+	h.count(p)
+}
+
+// reuseTestParse parses doc in one of the ways a parser can be used,
+// recording what it receives
+func reuseTestParse(p *Parser, doc string, mode int, stopAt int) (string, error) {
+	var out bytes.Buffer
+	calls := 0
+	h := stopAfterHandler{recordingHandler{&out}, &calls, stopAt}
+	var err error
+	switch mode {
+	case 0:
+		err = p.Parse(bytes.NewReader([]byte(doc)), h.OnEvent, h.OnData)
+	case 1:
+		err = p.ParseBytes([]byte(doc), h.OnEvent, h.OnData)
+	case 2:
+		err = parseWith(p, bytes.NewReader([]byte(doc)), h)
+	case 3:
+		err = parseBytesWith(p, []byte(doc), h)
+	default:
+		err = p.ParseBytesBatched([]byte(doc), make([]BatchEvent, 3), func(p *Parser, events []BatchEvent) {
+			for _, event := range events {
+				h.OnEvent(p, event.Kind)
+				out.Write(p.BatchBytes(event))
+			}
+		})
+	}
+	return out.String(), err
+}
+
+const reuseTestModes = 5
+
+// reuseTestFailures leave parsers in every state a document can end in:
+// errors at each byte of a document, bad documents and stops at each event
+func reuseTestFailures(test func(doc string, mode int, stopAt int)) {
+	var docs []string
+	for i := 0; i < len(TEST_VALIDATE_DOC); i++ {
+		docs = append(docs, TEST_VALIDATE_DOC[:i], TEST_VALIDATE_DOC[:i]+"x")
+	}
+	docs = append(docs, validateCases()...)
+	for mode := 0; mode < reuseTestModes; mode++ {
+		for _, doc := range docs {
+			test(doc, mode, -1)
+		}
+		for stopAt := 1; stopAt < 80; stopAt++ {
+			test(TEST_VALIDATE_DOC, mode, stopAt)
+		}
+	}
+}
+
+func TestReuseAfterErrors(t *testing.T) {
+	for _, options := range []uint8{OPT_ALLOW_EXTRA_WHITESPACE, OPT_ALLOW_EXTRA_WHITESPACE | OPT_DECODE_UNICODE_ESCAPES, 0x1f} {
+		p := NewParser(make([]byte, 0, MIN_UTF8_DATA_BUFFER_SIZE), nil, options)
+		reuseTestFailures(func(doc string, mode int, stopAt int) {
+			reuseTestParse(&p, doc, mode, stopAt)
+			p.Reset()
+			for _, good := range reuseTestDocs {
+				for goodMode := 0; goodMode < reuseTestModes; goodMode++ {
+					fresh := NewParser(make([]byte, 0, MIN_UTF8_DATA_BUFFER_SIZE), nil, options)
+					expected, expectedErr := reuseTestParse(&fresh, good, goodMode, -1)
+					out, err := reuseTestParse(&p, good, goodMode, -1)
+					if err != expectedErr || out != expected {
+						t.Fatalf("options %#x, after %q mode %d stop %d, mode %d: %v %q != %v %q", options, doc, mode, stopAt, goodMode, err, out, expectedErr, expected)
+					}
+					p.Reset()
+				}
+			}
+		})
+	}
+}
+
+func TestPoolGetAfterErrors(t *testing.T) {
+	pool := Pool{Options: OPT_ALLOW_EXTRA_WHITESPACE | OPT_DECODE_UNICODE_ESCAPES}
+	fresh := NewParser(nil, nil, pool.Options)
+	expected, expectedErr := reuseTestParse(&fresh, TEST_VALIDATE_DOC, 0, -1)
+	p := pool.Get()
+	reuseTestFailures(func(doc string, mode int, stopAt int) {
+		reuseTestParse(p, doc, mode, stopAt)
+		pool.Put(p)
+		p = pool.Get()
+		if out, err := reuseTestParse(p, TEST_VALIDATE_DOC, 0, -1); err != expectedErr || out != expected {
+			t.Fatalf("after %q mode %d stop %d: %v %q", doc, mode, stopAt, err, out)
+		}
+		pool.Put(p)
+		p = pool.Get()
+	})
+}
+
+func TestPoolSizing(t *testing.T) {
+	pool := Pool{Options: OPT_ALLOW_EXTRA_WHITESPACE}
+	p := pool.Get()
+	if cap(p.DataBuffer) != POOL_DATA_BUFFER_SIZE || cap(p.ContextStack) != POOL_STACK_DEPTH || p.options != pool.Options {
+		t.Fatalf("%d %d %#x", cap(p.DataBuffer), cap(p.ContextStack), p.options)
+	}
+	deep := strings.Repeat("[", 100) + strings.Repeat("]", 100)
+	long := `["` + strings.Repeat("x", 3*POOL_DATA_BUFFER_SIZE) + `"]`
+	onData := func(p *Parser, endOfData bool) {}
+	for _, doc := range []string{deep, long} {
+		if err := p.Parse(bytes.NewReader([]byte(doc)), nil, onData); err != nil {
+			t.Fatal(err)
+		}
+		p.Reset()
+	}
+	pool.Put(p)
+	if depth, dataSize := pool.sizes(); depth < 100 || dataSize != 2*POOL_DATA_BUFFER_SIZE {
+		t.Fatalf("%d %d", depth, dataSize)
+	}
+	if p = pool.Get(); cap(p.DataBuffer) < 2*POOL_DATA_BUFFER_SIZE || p.spills != 0 {
+		t.Fatalf("%d %d", cap(p.DataBuffer), p.spills)
+	}
+
+	// aliased values do not spill, and the growth is bounded
+	if err := p.ParseBytes([]byte(long), nil, onData); err != nil || p.spills != 0 {
+		t.Fatal(err, p.spills)
+	}
+	p.spills, p.DataBuffer = 1, make([]byte, 0, POOL_MAX_DATA_BUFFER_SIZE)
+	pool.Put(p)
+	if _, dataSize := pool.sizes(); dataSize != POOL_MAX_DATA_BUFFER_SIZE {
+		t.Fatal(dataSize)
+	}
+
+	other := NewParser(nil, nil, 0)
+	pool.Put(&other)
+	for i := 0; i < 3; i++ {
+		if p := pool.Get(); p.options != pool.Options {
+			t.Fatalf("%#x", p.options)
+		}
+	}
+}
+
+func BenchmarkPoolParallel(b *testing.B) {
+	pool := Pool{Options: OPT_ALLOW_EXTRA_WHITESPACE}
+	onData := func(p *Parser, endOfData bool) {}
+	b.SetBytes(int64(len(BENCHMARK_BYTES)))
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			p := pool.Get()
+			if err := p.ParseBytes(BENCHMARK_BYTES, nil, onData); err != nil {
+				log.Fatal(err)
+			}
+			pool.Put(p)
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package EvLJson
+
+import (
+	"bytes"
+	"io"
+)
+
+// positionOptions turn on the tracking of positions
+const positionOptions = OPT_TRACK_POSITIONS | OPT_TRACK_LINES
+
+// Position is where a byte sits in the input
+type Position struct {
+	Offset int64 // from the start of the input
+	Line   int64 // counted from 1, 0 without OPT_TRACK_LINES
+	Column int64 // in bytes counted from 1, 0 without OPT_TRACK_LINES
+}
+
+// positionByteReader counts the bytes, and the lines when asked to, the
+// parser reads from a stream; a newline counts as part of the line it ends
+type positionByteReader struct {
+	reader    io.ByteReader
+	lines     bool
+	count     int64
+	newline   bool // the byte last read was one
+	line      int64
+	lineStart int64
+}
+
+func (r *positionByteReader) ReadByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil {
+		if r.lines {
+			if r.newline {
+				r.line, r.lineStart = r.line+1, r.count
+			}
+			r.newline = b == '\n'
+		}
+		r.count++
+	}
+	return b, err
+}
+
+// openValue is a string, number, array or dict entered and not yet left
+type openValue struct {
+	start  Position
+	number bool
+}
+
+// parserPositions is the state of OPT_TRACK_POSITIONS
+type parserPositions struct {
+	reader   positionByteReader
+	inMemory bool // reading the input of ParseBytes, which needs no counting
+
+	// lines of input in memory are counted lazily up to scanned
+	scanned   int64
+	line      int64
+	lineStart int64
+
+	open  []openValue // innermost last
+	start Position    // of the current value
+	end   Position
+	ended bool // end is known
+}
+
+// parseTracked is parse from the start with the positions of values kept
+// around the calls to h
+func parseTracked[H parseHandler](p *Parser, byteReader io.ByteReader, h H) error {
+	s := &p.positions
+	s.open, s.start, s.end, s.ended = s.open[:0], Position{}, Position{}, false
+	s.scanned, s.line, s.lineStart = 0, 0, 0
+	s.inMemory = p.input != nil && byteReader == io.ByteReader(p.input)
+	if !s.inMemory {
+		s.reader = positionByteReader{reader: byteReader, lines: p.options&OPT_TRACK_LINES != 0}
+		byteReader = &s.reader
+	}
+	return parse(p, byteReader, positionHandler[H]{h}, HANDLE_START)
+}
+
+// positionHandler keeps track of the current value before handing events
+// and data on to h
+type positionHandler[H parseHandler] struct {
+	h H
+}
+
+func (t positionHandler[H]) OnEvent(p *Parser, evt event_t) {
+	s := &p.positions
+	switch evt {
+	case EVT_ENTER:
+		s.start, s.ended = p.Position(), false
+		s.open = append(s.open, openValue{start: s.start})
+	case EVT_NUMBER:
+		s.open[len(s.open)-1].number = true
+	case EVT_LEAVE:
+		value := s.open[len(s.open)-1]
+		s.open = s.open[:len(s.open)-1]
+		s.start = value.start
+		s.setEnd(p, value.number)
+	case EVT_NULL:
+		s.setLiteral(p, len(VALUE_STR_NULL))
+	case EVT_TRUE:
+		s.setLiteral(p, len(VALUE_STR_TRUE))
+	case EVT_FALSE:
+		s.setLiteral(p, len(VALUE_STR_FALSE))
+	}
+	t.h.OnEvent(p, evt)
+}
+
+func (t positionHandler[H]) OnData(p *Parser, endOfData bool) {
+	if endOfData {
+		s := &p.positions
+		s.setEnd(p, s.open[len(s.open)-1].number)
+	}
+	t.h.OnData(p, endOfData)
+}
+
+// setEnd ends the current value at the byte being handled: a number just
+// before it, as it is the byte after, anything else just after it
+func (s *parserPositions) setEnd(p *Parser, number bool) {
+	s.end, s.ended = p.Position(), true
+	if !number {
+		s.end.Offset++
+		if s.end.Line != 0 {
+			s.end.Column++
+		}
+	}
+}
+
+// setLiteral makes the literal of size bytes starting at the byte being
+// handled the current value
+func (s *parserPositions) setLiteral(p *Parser, size int) {
+	s.start, s.ended = p.Position(), true
+	s.end = s.start
+	s.end.Offset += int64(size)
+	if s.end.Line != 0 {
+		s.end.Column += int64(size)
+	}
+}
+
+// Position is where the byte the parser is handling sits in the input,
+// with OPT_TRACK_POSITIONS or OPT_TRACK_LINES; the zero Position otherwise
+func (p *Parser) Position() Position {
+	if p.options&positionOptions == 0 {
+		return Position{}
+	}
+	s := &p.positions
+	var position Position
+	if s.inMemory {
+		position.Offset = int64(p.input.pos - 1)
+	} else {
+		position.Offset = s.reader.count - 1
+	}
+	if p.options&OPT_TRACK_LINES == 0 {
+		return position
+	}
+	line, lineStart := s.reader.line, s.reader.lineStart
+	if s.inMemory {
+		if position.Offset > s.scanned {
+			// the newline at Offset belongs to this line
+			skipped := p.input.data[s.scanned:position.Offset]
+			s.line += int64(bytes.Count(skipped, []byte{'\n'}))
+			if last := bytes.LastIndexByte(skipped, '\n'); last >= 0 {
+				s.lineStart = s.scanned + int64(last) + 1
+			}
+			s.scanned = position.Offset
+		}
+		line, lineStart = s.line, s.lineStart
+	}
+	position.Line, position.Column = line+1, position.Offset-lineStart+1
+	return position
+}
+
+// ValueStart is the position of the first byte of the current value: the
+// one whose event is being handled, whose data OnData is given or, at
+// EVT_LEAVE, that is being left; the first byte of a literal is all that
+// has been checked of it yet
+func (p *Parser) ValueStart() Position {
+	return p.positions.start
+}
+
+// ValueEnd is the position just past the current value once known: at
+// EVT_LEAVE, at the event of a literal and at the DATA_END call of OnData
+func (p *Parser) ValueEnd() (Position, bool) {
+	return p.positions.end, p.positions.ended
+}
@@ -0,0 +1,183 @@
+package EvLJson
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+const TEST_POSITION_DOC = "{\"a\": [1, -2.5e3, \"x\\ny\", true],\n\t\"nested\": {\"k\\\"\": [[], {}],\r\n \"n\": null},\n\n" +
+	"  \"f\": false, \"num\": 0\n, \"s\": \"é\\u00e9😀\"\n}\n"
+
+// positionTestLine is the line and column of offset in doc counted
+// directly
+func positionTestLine(doc string, offset int64) (int64, int64) {
+	before := doc[:offset]
+	return int64(strings.Count(before, "\n")) + 1, offset - int64(strings.LastIndexByte(before, '\n'))
+}
+
+// collectPositions records the spans seen by the callbacks of every event
+// and at the end of every value's data
+func collectPositions(doc string, options uint8, mode int) ([]string, error) {
+	var spans []string
+	record := func(p *Parser, what string) {
+		end, ended := p.ValueEnd()
+		spans = append(spans, fmt.Sprintf("%s %v %v %v %v", what, p.Position(), p.ValueStart(), end, ended))
+	}
+	onEvent := func(p *Parser, evt event_t) { record(p, fmt.Sprint("event ", evt)) }
+	onData := func(p *Parser, endOfData bool) {
+		if endOfData {
+			record(p, "data")
+		}
+	}
+	p := NewParser(make([]byte, 0, TEST_DATA_BUFFER_SIZE), nil, options)
+	var err error
+	switch mode {
+	case 0:
+		err = p.Parse(bytes.NewReader([]byte(doc)), onEvent, onData)
+	case 1:
+		err = p.ParseBytes([]byte(doc), onEvent, onData)
+	case 2:
+		err = p.Parse(bufio.NewReaderSize(iotest.OneByteReader(strings.NewReader(doc)), 16), onEvent, onData)
+	case 3:
+		err = parseWith(&p, bytes.NewReader([]byte(doc)), positionRecordingHandler{record})
+	default:
+		err = parseBytesWith(&p, []byte(doc), positionRecordingHandler{record})
+	}
+	return spans, err
+}
+
+type positionRecordingHandler struct {
+	record func(p *Parser, what string)
+}
+
+func (h positionRecordingHandler) OnEvent(p *Parser, evt event_t) {
+	h.record(p, fmt.Sprint("event ", evt))
+}
+
+func (h positionRecordingHandler) OnData(p *Parser, endOfData bool) {
+	if endOfData {
+		h.record(p, "data")
+	}
+}
+
+func TestPositionsMatchAcrossModes(t *testing.T) {
+	for _, options := range []uint8{OPT_ALLOW_EXTRA_WHITESPACE | OPT_TRACK_POSITIONS, OPT_ALLOW_EXTRA_WHITESPACE | OPT_TRACK_LINES | OPT_DECODE_UNICODE_ESCAPES} {
+		expected, expectedErr := collectPositions(TEST_POSITION_DOC, options, 0)
+		if expectedErr != nil || len(expected) == 0 {
+			t.Fatal(expectedErr)
+		}
+		for mode := 1; mode < 5; mode++ {
+			spans, err := collectPositions(TEST_POSITION_DOC, options, mode)
+			if err != nil || strings.Join(spans, "\n") != strings.Join(expected, "\n") {
+				t.Fatalf("options %#x mode %d: %v\n%s\n!=\n%s", options, mode, err, strings.Join(spans, "\n"), strings.Join(expected, "\n"))
+			}
+		}
+	}
+}
+
+func TestValueSpans(t *testing.T) {
+	doc := TEST_POSITION_DOC
+	p := NewParser(make([]byte, 0, TEST_DATA_BUFFER_SIZE), nil, OPT_ALLOW_EXTRA_WHITESPACE|OPT_TRACK_LINES)
+	checkPosition := func(position Position) {
+		if line, column := positionTestLine(doc, position.Offset); position.Line != line || position.Column != column {
+			t.Fatalf("%v is at line %d column %d", position, line, column)
+		}
+	}
+	values := 0
+	onEvent := func(p *Parser, evt event_t) {
+		checkPosition(p.Position())
+		start := p.ValueStart()
+		checkPosition(start)
+		end, ended := p.ValueEnd()
+		if evt == EVT_ENTER && (ended || start != p.Position()) {
+			t.Fatalf("entered at %v: %v %v", p.Position(), start, ended)
+		}
+		if !ended {
+			return
+		}
+		checkPosition(end)
+		span := doc[start.Offset:end.Offset]
+		// a whole value is a document of its own once in an array
+		check := NewParser(nil, nil, OPT_ALLOW_EXTRA_WHITESPACE)
+		if err := check.ParseBytes([]byte("["+span+"]"), nil, nil); err != nil || strings.TrimSpace(span) != span {
+			t.Fatalf("event %d: %q: %v", evt, span, err)
+		}
+		values++
+	}
+	if err := p.Parse(bytes.NewReader([]byte(doc)), onEvent, nil); err != nil {
+		t.Fatal(err)
+	}
+	if values != 21 {
+		t.Fatal(values)
+	}
+	if end, _ := p.ValueEnd(); end.Offset != int64(strings.LastIndexByte(doc, '}')+1) || p.ValueStart().Offset != 0 {
+		t.Fatal(p.ValueStart(), end)
+	}
+}
+
+func TestPositionsDisabled(t *testing.T) {
+	p := NewParser(nil, nil, OPT_ALLOW_EXTRA_WHITESPACE)
+	err := p.ParseBytes([]byte(TEST_POSITION_DOC), func(p *Parser, evt event_t) {
+		if end, ended := p.ValueEnd(); p.Position() != (Position{}) || p.ValueStart() != (Position{}) || ended || end != (Position{}) {
+			t.Fatal(p.Position())
+		}
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPositionsZeroAllocs(t *testing.T) {
+	doc := []byte(TEST_POSITION_DOC)
+	reader := bytes.NewReader(doc)
+	p := NewParser(make([]byte, 0, TEST_DATA_BUFFER_SIZE), nil, OPT_ALLOW_EXTRA_WHITESPACE|OPT_TRACK_LINES)
+	onEvent := func(p *Parser, evt event_t) { p.ValueStart() }
+	strings := 0
+	parse := func() {
+		p.Reset()
+		reader.Reset(doc)
+		if err := p.Parse(reader, onEvent, nil); err != nil {
+			log.Fatal(err)
+		}
+		p.Reset()
+		if err := parseBytesWith(&p, doc, countingHandler{&strings}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	parse()
+	if allocs := testing.AllocsPerRun(10, parse); allocs != 0 {
+		t.Fatalf("%v allocations", allocs)
+	}
+}
+
+func benchmarkCorpusPositions(b *testing.B, options uint8, zeroCopy bool) {
+	onEvent := func(p *Parser, evt event_t) {}
+	evLJsonParser := NewParser(make([]byte, TEST_DATA_BUFFER_SIZE), nil, OPT_ALLOW_EXTRA_WHITESPACE|options)
+	b.SetBytes(int64(len(BENCHMARK_BYTES)))
+	for i := 0; i < b.N; i++ {
+		var err error
+		if zeroCopy {
+			err = evLJsonParser.ParseBytes(BENCHMARK_BYTES, onEvent, nil)
+		} else {
+			err = evLJsonParser.Parse(bytes.NewReader(BENCHMARK_BYTES), onEvent, nil)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		evLJsonParser.Reset()
+	}
+}
+
+func BenchmarkCorpusUntracked(b *testing.B)      { benchmarkCorpusPositions(b, 0, false) }
+func BenchmarkCorpusPositions(b *testing.B)      { benchmarkCorpusPositions(b, OPT_TRACK_POSITIONS, false) }
+func BenchmarkCorpusLines(b *testing.B)          { benchmarkCorpusPositions(b, OPT_TRACK_LINES, false) }
+func BenchmarkCorpusBytesUntracked(b *testing.B) { benchmarkCorpusPositions(b, 0, true) }
+func BenchmarkCorpusBytesPositions(b *testing.B) {
+	benchmarkCorpusPositions(b, OPT_TRACK_POSITIONS, true)
+}
+func BenchmarkCorpusBytesLines(b *testing.B) { benchmarkCorpusPositions(b, OPT_TRACK_LINES, true) }
@@ -0,0 +1,206 @@
+package EvLJson
+
+import (
+	"io"
+	"strconv"
+)
+
+// strings and numbers up to this many bytes are assembled by default
+const DEFAULT_MAX_SCALAR_SIZE = 64 * 1024
+
+type ScalarTooLongError struct {
+	Path  string
+	Limit int
+}
+
+func (err ScalarTooLongError) Error() string {
+	return "Value at \"" + err.Path + "\" is longer than " + strconv.Itoa(err.Limit) + " bytes"
+}
+
+type scalarFrame struct {
+	isDict bool
+	index  int
+	base   int // length of the path up to and including the container
+	key    []byte
+}
+
+// ScalarHandler assembles complete scalar values from parser events and
+// DataBuffer chunks and passes them, decoded, to typed callbacks along
+// with the json pointer to the value; nil callbacks are skipped
+//
+// Numbers without a fraction or exponent that fit in an int64 go to OnInt,
+// all others to OnFloat; when OnInt is nil integers go to OnFloat too
+//
+// Strings longer than MaxSize are streamed to OnStringChunk instead of
+// OnString, ending with a call that has endOfData set; without
+// OnStringChunk, and for keys and numbers, exceeding MaxSize is an error.
+// A MaxSize of 0, as in the zero ScalarHandler, is DEFAULT_MAX_SCALAR_SIZE
+type ScalarHandler struct {
+	MaxSize       int
+	OnString      func(path string, value string)
+	OnStringChunk func(path string, chunk []byte, endOfData bool)
+	OnInt         func(path string, value int64)
+	OnFloat       func(path string, value float64)
+	OnBool        func(path string, value bool)
+	OnNull        func(path string)
+
+	frames    []scalarFrame
+	path      []byte
+	token     writerToken_t
+	text      []byte
+	chunked   bool // the current string has spilled over to OnStringChunk
+	chunkDone bool
+	err       error
+}
+
+func NewScalarHandler() ScalarHandler {
+	return ScalarHandler{MaxSize: DEFAULT_MAX_SCALAR_SIZE}
+}
+
+func (h *ScalarHandler) maxSize() int {
+	if h.MaxSize == 0 {
+		return DEFAULT_MAX_SCALAR_SIZE
+	}
+	return h.MaxSize
+}
+
+// startValue points path at the value starting now
+func (h *ScalarHandler) startValue() {
+	if len(h.frames) == 0 {
+		h.path = h.path[:0]
+		return
+	}
+	frame := &h.frames[len(h.frames)-1]
+	h.path = append(h.path[:frame.base], '/')
+	if frame.isDict {
+		h.path = append(h.path, escapePointerToken(string(frame.key))...)
+	} else {
+		h.path = strconv.AppendInt(h.path, int64(frame.index), 10)
+		frame.index++
+	}
+}
+
+func (h *ScalarHandler) fail(p *Parser, err error) {
+	if h.err == nil {
+		h.err = err
+	}
+	p.ParseStop()
+}
+
+func (h *ScalarHandler) number(p *Parser) {
+	isInt := h.OnInt != nil
+	for _, b := range h.text {
+		if b == '.' || b == 'e' || b == 'E' {
+			isInt = false
+			break
+		}
+	}
+	if isInt {
+		if value, err := strconv.ParseInt(string(h.text), 10, 64); err == nil {
+			h.OnInt(string(h.path), value)
+			return
+		}
+	}
+	if h.OnFloat != nil {
+		value, err := strconv.ParseFloat(string(h.text), 64)
+		if err != nil {
+			h.fail(p, NumberOutOfRangeError{string(h.text)})
+			return
+		}
+		h.OnFloat(string(h.path), value)
+	}
+}
+
+func ScalarOnEvent(p *Parser, evt event_t) {
+	h := p.UserData.(*ScalarHandler)
+	switch evt {
+	case EVT_NULL:
+		h.startValue()
+		if h.OnNull != nil {
+			h.OnNull(string(h.path))
+		}
+	case EVT_TRUE, EVT_FALSE:
+		h.startValue()
+		if h.OnBool != nil {
+			h.OnBool(string(h.path), evt == EVT_TRUE)
+		}
+	case EVT_ARRAY, EVT_DICT:
+		h.startValue()
+		h.frames = append(h.frames, scalarFrame{isDict: evt == EVT_DICT, base: len(h.path)})
+	case EVT_STRING:
+		if p.IsDictKey() {
+			h.token = WRITER_TOKEN_KEY
+		} else {
+			h.startValue()
+			h.token = WRITER_TOKEN_STRING
+		}
+	case EVT_NUMBER:
+		h.startValue()
+		h.token = WRITER_TOKEN_NUMBER
+	case EVT_LEAVE:
+		switch h.token {
+		case WRITER_TOKEN_KEY:
+			frame := &h.frames[len(h.frames)-1]
+			frame.key = append(frame.key[:0], h.text...)
+		case WRITER_TOKEN_STRING:
+			if h.chunked {
+				if !h.chunkDone {
+					h.OnStringChunk(string(h.path), nil, DATA_END)
+				}
+			} else if h.OnString != nil {
+				h.OnString(string(h.path), string(h.text))
+			}
+		case WRITER_TOKEN_NUMBER:
+			h.number(p)
+		default:
+			h.frames = h.frames[:len(h.frames)-1]
+		}
+		h.token = WRITER_TOKEN_NONE
+		h.text = h.text[:0]
+		h.chunked = false
+		h.chunkDone = false
+	}
+}
+
+func ScalarOnData(p *Parser, endOfData bool) {
+	h := p.UserData.(*ScalarHandler)
+	if h.chunked {
+		h.OnStringChunk(string(h.path), p.DataBuffer, endOfData)
+		h.chunkDone = endOfData
+		return
+	}
+	if len(h.text)+len(p.DataBuffer) <= h.maxSize() {
+		h.text = append(h.text, p.DataBuffer...)
+		return
+	}
+	if h.token != WRITER_TOKEN_STRING || h.OnStringChunk == nil {
+		h.fail(p, ScalarTooLongError{string(h.path), h.maxSize()})
+		return
+	}
+	// spill what was assembled so far and stream the rest
+	h.chunked = true
+	if len(h.text) != 0 {
+		h.OnStringChunk(string(h.path), h.text, DATA_CONTINUES)
+		h.text = h.text[:0]
+	}
+	h.OnStringChunk(string(h.path), p.DataBuffer, endOfData)
+	h.chunkDone = endOfData
+}
+
+// Parse reads one document from byteReader and calls the callbacks for
+// every scalar in it
+func (h *ScalarHandler) Parse(byteReader io.ByteReader) error {
+	h.frames, h.path, h.text, h.err = h.frames[:0], h.path[:0], h.text[:0], nil
+	h.token, h.chunked, h.chunkDone = WRITER_TOKEN_NONE, false, false
+	parser := documentParsers.Get()
+	defer documentParsers.Put(parser)
+	parser.UserData = h
+	err := parser.Parse(byteReader, ScalarOnEvent, ScalarOnData)
+	if h.err != nil {
+		return h.err
+	}
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,119 @@
+package EvLJson
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestScalarHandlerCallbacks(t *testing.T) {
+	var got []string
+	h := NewScalarHandler()
+	h.OnString = func(path string, value string) { got = append(got, "string "+path+" "+value) }
+	h.OnInt = func(path string, value int64) { got = append(got, "int "+path+" "+strconv.FormatInt(value, 10)) }
+	h.OnFloat = func(path string, value float64) {
+		got = append(got, "float "+path+" "+strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	h.OnBool = func(path string, value bool) {
+		if value {
+			got = append(got, "bool "+path+" true")
+		} else {
+			got = append(got, "bool "+path+" false")
+		}
+	}
+	h.OnNull = func(path string) { got = append(got, "null "+path) }
+
+	doc := `{"name": "café au lait", "n": [-12, 1.5, 2e3, 99999999999999999999],` +
+		` "a/b~c": {"": true, "off": false, "none": null}, "empty": ""}`
+	if err := h.Parse(strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"string /name café au lait",
+		"int /n/0 -12",
+		"float /n/1 1.5",
+		"float /n/2 2000",
+		"float /n/3 1e+20",
+		"bool /a~1b~0c/ true",
+		"bool /a~1b~0c/off false",
+		"null /a~1b~0c/none",
+		"string /empty ",
+	}
+	t.Logf("%q", got)
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("%q", expected)
+	}
+}
+
+func TestScalarHandlerIntsToFloat(t *testing.T) {
+	var got []float64
+	h := NewScalarHandler()
+	h.OnFloat = func(path string, value float64) { got = append(got, value) }
+	if err := h.Parse(strings.NewReader(`[1, -2, 3.25]`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != -2 || got[2] != 3.25 {
+		t.Fatalf("%v", got)
+	}
+}
+
+func TestScalarHandlerZeroValue(t *testing.T) {
+	var got []string
+	var h ScalarHandler
+	h.OnString = func(path string, value string) { got = append(got, value) }
+	h.OnStringChunk = func(path string, chunk []byte, endOfData bool) { t.Fatal("chunked", path) }
+	long := strings.Repeat("x", DEFAULT_MAX_SCALAR_SIZE)
+	if err := h.Parse(strings.NewReader(`["a", "` + long + `"]`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "a" || got[1] != long {
+		t.Fatalf("%d strings", len(got))
+	}
+}
+
+func TestScalarHandlerChunkFallback(t *testing.T) {
+	long := strings.Repeat("0123456789", 5)
+	for _, size := range []int{len(long) - 1, len(long) - 2, 8} {
+		var strs []string
+		var chunks []byte
+		ends := 0
+		h := NewScalarHandler()
+		h.MaxSize = size
+		h.OnString = func(path string, value string) { strs = append(strs, value) }
+		h.OnStringChunk = func(path string, chunk []byte, endOfData bool) {
+			if path != "/1" {
+				t.Fatalf("path %q", path)
+			}
+			if ends != 0 {
+				t.Fatal("chunk after end of data")
+			}
+			chunks = append(chunks, chunk...)
+			if endOfData {
+				ends++
+			}
+		}
+		if err := h.Parse(strings.NewReader(`["short", "` + long + `", "after"]`)); err != nil {
+			t.Fatal(err)
+		}
+		if string(chunks) != long || ends != 1 {
+			t.Fatalf("size %d: chunks %q, %d ends", size, chunks, ends)
+		}
+		if len(strs) != 2 || strs[0] != "short" || strs[1] != "after" {
+			t.Fatalf("size %d: strings %q", size, strs)
+		}
+	}
+}
+
+func TestScalarHandlerTooLong(t *testing.T) {
+	for _, doc := range []string{`{"a": ["0123456789"]}`, `{"a": [1234567890123]}`, `{"0123456789": 1}`} {
+		h := NewScalarHandler()
+		h.MaxSize = 8
+		h.OnString = func(path string, value string) {}
+		h.OnStringChunk = nil
+		err := h.Parse(strings.NewReader(doc))
+		t.Logf("%s: %v", doc, err)
+		if _, ok := err.(ScalarTooLongError); !ok {
+			t.FailNow()
+		}
+	}
+}
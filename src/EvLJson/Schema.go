@@ -0,0 +1,690 @@
+package EvLJson
+
+import (
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const ( // schemaType_t
+	SCHEMA_TYPE_NULL = 1 << iota
+	SCHEMA_TYPE_BOOLEAN
+	SCHEMA_TYPE_OBJECT
+	SCHEMA_TYPE_ARRAY
+	SCHEMA_TYPE_NUMBER
+	SCHEMA_TYPE_INTEGER
+	SCHEMA_TYPE_STRING
+)
+
+// how many schemas $ref, allOf, anyOf and oneOf may chain through without
+// descending into the instance; cycles among them are rejected by
+// CompileSchema, this bounds the acyclic chains
+const MAX_SCHEMA_EXPANSION_DEPTH = 64
+
+type schemaType_t uint8
+
+var schemaTypeNames = map[string]schemaType_t{
+	"null":    SCHEMA_TYPE_NULL,
+	"boolean": SCHEMA_TYPE_BOOLEAN,
+	"object":  SCHEMA_TYPE_OBJECT,
+	"array":   SCHEMA_TYPE_ARRAY,
+	"number":  SCHEMA_TYPE_NUMBER,
+	"integer": SCHEMA_TYPE_INTEGER,
+	"string":  SCHEMA_TYPE_STRING,
+}
+
+func (t schemaType_t) String() string {
+	var names []string
+	for name, bit := range schemaTypeNames {
+		if t&bit != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, " or ")
+}
+
+type InvalidSchemaError struct {
+	Pointer string
+	Message string
+}
+
+func (err InvalidSchemaError) Error() string {
+	return "Invalid schema at \"" + err.Pointer + "\": " + err.Message
+}
+
+// SchemaError is one place where a document does not match its schema
+type SchemaError struct {
+	Pointer string // json pointer to the offending value
+	Offset  int64  // byte offset in the document the value starts at
+	Keyword string // schema keyword that was violated
+	Message string
+}
+
+func (err SchemaError) Error() string {
+	return "\"" + err.Pointer + "\" at offset " + strconv.FormatInt(err.Offset, 10) + ": " + err.Keyword + ": " + err.Message
+}
+
+// schemaNode is one compiled (sub)schema; absent integer limits are -1
+type schemaNode struct {
+	alwaysFalse  bool
+	falseKeyword string // keyword the false schema came from, for messages
+	types        schemaType_t
+	enum         []interface{}
+	hasConst     bool
+	constValue   interface{}
+	required     []string
+	properties   map[string]*schemaNode
+	additional   *schemaNode
+	items        *schemaNode
+	minItems     int
+	maxItems     int
+	minLength    int
+	maxLength    int
+	pattern      *regexp.Regexp
+	hasMinimum   bool
+	minimum      float64
+	hasMaximum   bool
+	maximum      float64
+	allOf        []*schemaNode
+	anyOf        []*schemaNode
+	oneOf        []*schemaNode
+	ref          *schemaNode
+}
+
+// Schema is a compiled draft 2020-12 json schema, limited to the keywords:
+// type, enum, const, required, properties, additionalProperties, items,
+// minItems, maxItems, minLength, maxLength, pattern, minimum, maximum,
+// allOf, anyOf, oneOf and local $ref; all other keywords are ignored
+type Schema struct {
+	root *schemaNode
+}
+
+type schemaCompiler struct {
+	document interface{}
+	nodes    map[string]*schemaNode // by json pointer, so $ref cycles resolve
+}
+
+func CompileSchema(byteReader io.ByteReader) (*Schema, error) {
+	document, err := parseGeneric(byteReader)
+	if err != nil {
+		return nil, err
+	}
+	c := schemaCompiler{document: document, nodes: map[string]*schemaNode{}}
+	root, err := c.compile("", "", document)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkCycles(); err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// checkCycles rejects a schema reaching itself through $ref, allOf, anyOf
+// and oneOf alone, as it would expand forever on every value it applies to
+func (c *schemaCompiler) checkCycles() error {
+	pointers := make(map[*schemaNode]string, len(c.nodes))
+	sorted := make([]string, 0, len(c.nodes))
+	for pointer, node := range c.nodes {
+		pointers[node] = pointer
+		sorted = append(sorted, pointer)
+	}
+	sort.Strings(sorted)
+	const visiting, visited = 1, 2
+	states := map[*schemaNode]int{}
+	var visit func(node *schemaNode) error
+	visit = func(node *schemaNode) error {
+		switch states[node] {
+		case visiting:
+			return InvalidSchemaError{pointers[node], "$ref cycle that consumes no input"}
+		case visited:
+			return nil
+		}
+		states[node] = visiting
+		for _, list := range [][]*schemaNode{{node.ref}, node.allOf, node.anyOf, node.oneOf} {
+			for _, child := range list {
+				if child == nil {
+					continue
+				}
+				if err := visit(child); err != nil {
+					return err
+				}
+			}
+		}
+		states[node] = visited
+		return nil
+	}
+	for _, pointer := range sorted {
+		if err := visit(c.nodes[pointer]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func escapePointerToken(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+func unescapePointerToken(token string) string {
+	return strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+}
+
+func schemaLimit(pointer string, arg interface{}) (int, error) {
+	if number, ok := arg.(float64); ok && number >= 0 && number == math.Trunc(number) {
+		return int(number), nil
+	}
+	return 0, InvalidSchemaError{pointer, "expected a non-negative integer"}
+}
+
+func (c *schemaCompiler) compileList(pointer string, arg interface{}) ([]*schemaNode, error) {
+	list, ok := arg.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, InvalidSchemaError{pointer, "expected a non-empty array of schemas"}
+	}
+	nodes := make([]*schemaNode, len(list))
+	for i, value := range list {
+		node, err := c.compile(pointer+"/"+strconv.Itoa(i), "", value)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = node
+	}
+	return nodes, nil
+}
+
+func (c *schemaCompiler) resolve(pointer string, ref string) (*schemaNode, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, InvalidSchemaError{pointer, "only local $ref values starting with # are supported"}
+	}
+	target := c.document
+	if ref != "#" {
+		if !strings.HasPrefix(ref, "#/") {
+			return nil, InvalidSchemaError{pointer, "$ref must be a json pointer fragment"}
+		}
+		for _, token := range strings.Split(ref[2:], "/") {
+			token = unescapePointerToken(token)
+			switch container := target.(type) {
+			case map[string]interface{}:
+				target = container[token]
+			case []interface{}:
+				index, err := strconv.Atoi(token)
+				if err != nil || index < 0 || index >= len(container) {
+					return nil, InvalidSchemaError{pointer, "$ref " + ref + " does not resolve"}
+				}
+				target = container[index]
+			default:
+				target = nil
+			}
+			if target == nil {
+				return nil, InvalidSchemaError{pointer, "$ref " + ref + " does not resolve"}
+			}
+		}
+	}
+	return c.compile(ref[1:], "", target)
+}
+
+func (c *schemaCompiler) compile(pointer string, keyword string, value interface{}) (*schemaNode, error) {
+	if node, exists := c.nodes[pointer]; exists {
+		return node, nil
+	}
+	node := &schemaNode{minItems: -1, maxItems: -1, minLength: -1, maxLength: -1}
+	c.nodes[pointer] = node
+
+	var schema map[string]interface{}
+	switch value := value.(type) {
+	case bool:
+		node.alwaysFalse = !value
+		node.falseKeyword = keyword
+		return node, nil
+	case map[string]interface{}:
+		schema = value
+	default:
+		return nil, InvalidSchemaError{pointer, "a schema must be a dict or boolean"}
+	}
+
+	var err error
+	for keyword, arg := range schema {
+		at := pointer + "/" + escapePointerToken(keyword)
+		switch keyword {
+		case "type":
+			switch arg := arg.(type) {
+			case string:
+				if node.types = schemaTypeNames[arg]; node.types == 0 {
+					return nil, InvalidSchemaError{at, "unknown type " + strconv.Quote(arg)}
+				}
+			case []interface{}:
+				for _, name := range arg {
+					name, _ := name.(string)
+					bit := schemaTypeNames[name]
+					if bit == 0 {
+						return nil, InvalidSchemaError{at, "unknown type " + strconv.Quote(name)}
+					}
+					node.types |= bit
+				}
+			default:
+				return nil, InvalidSchemaError{at, "expected a type name or array of them"}
+			}
+		case "enum":
+			list, ok := arg.([]interface{})
+			if !ok {
+				return nil, InvalidSchemaError{at, "expected an array"}
+			}
+			node.enum = list
+		case "const":
+			node.hasConst = true
+			node.constValue = arg
+		case "required":
+			list, ok := arg.([]interface{})
+			if !ok {
+				return nil, InvalidSchemaError{at, "expected an array of strings"}
+			}
+			for _, name := range list {
+				name, ok := name.(string)
+				if !ok {
+					return nil, InvalidSchemaError{at, "expected an array of strings"}
+				}
+				node.required = append(node.required, name)
+			}
+		case "properties":
+			dict, ok := arg.(map[string]interface{})
+			if !ok {
+				return nil, InvalidSchemaError{at, "expected a dict of schemas"}
+			}
+			node.properties = make(map[string]*schemaNode, len(dict))
+			for name, value := range dict {
+				if node.properties[name], err = c.compile(at+"/"+escapePointerToken(name), "", value); err != nil {
+					return nil, err
+				}
+			}
+		case "additionalProperties", "items":
+			child, err := c.compile(at, keyword, arg)
+			if err != nil {
+				return nil, err
+			}
+			if keyword == "items" {
+				node.items = child
+			} else {
+				node.additional = child
+			}
+		case "minItems":
+			node.minItems, err = schemaLimit(at, arg)
+		case "maxItems":
+			node.maxItems, err = schemaLimit(at, arg)
+		case "minLength":
+			node.minLength, err = schemaLimit(at, arg)
+		case "maxLength":
+			node.maxLength, err = schemaLimit(at, arg)
+		case "pattern":
+			pattern, ok := arg.(string)
+			if !ok {
+				return nil, InvalidSchemaError{at, "expected a string"}
+			}
+			if node.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, InvalidSchemaError{at, err.Error()}
+			}
+		case "minimum", "maximum":
+			number, ok := arg.(float64)
+			if !ok {
+				return nil, InvalidSchemaError{at, "expected a number"}
+			}
+			if keyword == "minimum" {
+				node.hasMinimum, node.minimum = true, number
+			} else {
+				node.hasMaximum, node.maximum = true, number
+			}
+		case "allOf":
+			node.allOf, err = c.compileList(at, arg)
+		case "anyOf":
+			node.anyOf, err = c.compileList(at, arg)
+		case "oneOf":
+			node.oneOf, err = c.compileList(at, arg)
+		case "$ref":
+			ref, ok := arg.(string)
+			if !ok {
+				return nil, InvalidSchemaError{at, "expected a string"}
+			}
+			node.ref, err = c.resolve(at, ref)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+type schemaSink struct {
+	errors []SchemaError
+}
+
+// one schema applied to one instance value, reporting into sink
+type schemaCheck struct {
+	node *schemaNode
+	sink *schemaSink
+}
+
+// anyOf / oneOf branches each collect their own errors until the instance
+// value ends and it is known how many of them matched
+type schemaCombo struct {
+	oneOf    bool
+	branches []*schemaSink
+	sink     *schemaSink
+}
+
+// schemaFrame tracks one instance value that has started but not ended
+type schemaFrame struct {
+	checks   []schemaCheck
+	combos   []schemaCombo
+	kind     schemaType_t
+	offset   int64
+	segment  string // pointer token of this value within its parent
+	count    int    // array items or dict members seen so far
+	keys     map[string]bool
+	length   int
+	text     []byte
+	needText bool
+	capture  *genericBuilder
+}
+
+type schemaValidator struct {
+	root     *schemaNode
+	reader   countingByteReader
+	frames   []schemaFrame
+	result   schemaSink
+	token    writerToken_t
+	key      []byte
+	lastKey  string
+	captures int
+}
+
+func (v *schemaValidator) pointer() string {
+	var pointer strings.Builder
+	for i := 1; i < len(v.frames); i++ {
+		pointer.WriteByte('/')
+		pointer.WriteString(escapePointerToken(v.frames[i].segment))
+	}
+	return pointer.String()
+}
+
+func (v *schemaValidator) fail(sink *schemaSink, keyword string, message string) {
+	frame := &v.frames[len(v.frames)-1]
+	sink.errors = append(sink.errors, SchemaError{v.pointer(), frame.offset, keyword, message})
+}
+
+func (v *schemaValidator) expand(frame *schemaFrame, node *schemaNode, sink *schemaSink, depth int) {
+	if depth > MAX_SCHEMA_EXPANSION_DEPTH {
+		v.fail(sink, "$ref", "schema references chain too deep without consuming the document")
+		return
+	}
+	frame.checks = append(frame.checks, schemaCheck{node, sink})
+	if node.ref != nil {
+		v.expand(frame, node.ref, sink, depth+1)
+	}
+	for _, child := range node.allOf {
+		v.expand(frame, child, sink, depth+1)
+	}
+	for i, list := range [2][]*schemaNode{node.anyOf, node.oneOf} {
+		if list == nil {
+			continue
+		}
+		combo := schemaCombo{oneOf: i == 1, branches: make([]*schemaSink, len(list)), sink: sink}
+		for j := range list {
+			combo.branches[j] = &schemaSink{}
+		}
+		frame.combos = append(frame.combos, combo)
+		for j, child := range list {
+			v.expand(frame, child, combo.branches[j], depth+1)
+		}
+	}
+}
+
+func (v *schemaValidator) startValue(kind schemaType_t) {
+	v.frames = append(v.frames, schemaFrame{kind: kind, offset: v.reader.offset()})
+	last := len(v.frames) - 1
+	frame := &v.frames[last]
+	if last == 0 {
+		v.expand(frame, v.root, &v.result, 0)
+	} else {
+		parent := &v.frames[last-1]
+		if parent.kind == SCHEMA_TYPE_OBJECT {
+			frame.segment = v.lastKey
+		} else {
+			frame.segment = strconv.Itoa(parent.count)
+			parent.count++
+		}
+		for _, check := range parent.checks {
+			child := check.node.items
+			if parent.kind == SCHEMA_TYPE_OBJECT {
+				if child = check.node.properties[v.lastKey]; child == nil {
+					child = check.node.additional
+				}
+			}
+			if child != nil {
+				v.expand(frame, child, check.sink, 0)
+			}
+		}
+	}
+
+	needCapture := false
+	for _, check := range frame.checks {
+		node := check.node
+		if node.alwaysFalse {
+			if node.falseKeyword == "additionalProperties" {
+				v.fail(check.sink, node.falseKeyword, "property is not allowed")
+			} else {
+				v.fail(check.sink, node.falseKeyword, "no value is allowed here")
+			}
+			continue
+		}
+		if node.types != 0 && node.types&kind == 0 && !(kind == SCHEMA_TYPE_NUMBER && node.types&SCHEMA_TYPE_INTEGER != 0) {
+			v.fail(check.sink, "type", "expected "+node.types.String()+", got "+kind.String())
+		}
+		if node.pattern != nil {
+			frame.needText = true
+		}
+		if node.required != nil && frame.keys == nil {
+			frame.keys = map[string]bool{}
+		}
+		if node.enum != nil || node.hasConst {
+			needCapture = true
+		}
+	}
+	if needCapture {
+		frame.capture = &genericBuilder{}
+		v.captures++
+	}
+}
+
+func (v *schemaValidator) endValue() {
+	last := len(v.frames) - 1
+	frame := &v.frames[last]
+	var number float64
+	if frame.kind == SCHEMA_TYPE_NUMBER {
+		number, _ = strconv.ParseFloat(string(frame.text), 64)
+	}
+	var captured interface{}
+	if frame.capture != nil {
+		captured = frame.capture.result
+		v.captures--
+	}
+
+	for _, check := range frame.checks {
+		node := check.node
+		switch frame.kind {
+		case SCHEMA_TYPE_STRING:
+			if node.minLength >= 0 && frame.length < node.minLength {
+				v.fail(check.sink, "minLength", "shorter than "+strconv.Itoa(node.minLength)+" characters")
+			}
+			if node.maxLength >= 0 && frame.length > node.maxLength {
+				v.fail(check.sink, "maxLength", "longer than "+strconv.Itoa(node.maxLength)+" characters")
+			}
+			if node.pattern != nil && !node.pattern.Match(frame.text) {
+				v.fail(check.sink, "pattern", "does not match "+strconv.Quote(node.pattern.String()))
+			}
+		case SCHEMA_TYPE_NUMBER:
+			if node.types != 0 && node.types&SCHEMA_TYPE_NUMBER == 0 && node.types&SCHEMA_TYPE_INTEGER != 0 && number != math.Trunc(number) {
+				v.fail(check.sink, "type", "expected "+node.types.String()+", got number")
+			}
+			if node.hasMinimum && number < node.minimum {
+				v.fail(check.sink, "minimum", "less than "+strconv.FormatFloat(node.minimum, 'g', -1, 64))
+			}
+			if node.hasMaximum && number > node.maximum {
+				v.fail(check.sink, "maximum", "greater than "+strconv.FormatFloat(node.maximum, 'g', -1, 64))
+			}
+		case SCHEMA_TYPE_ARRAY:
+			if node.minItems >= 0 && frame.count < node.minItems {
+				v.fail(check.sink, "minItems", "fewer than "+strconv.Itoa(node.minItems)+" items")
+			}
+			if node.maxItems >= 0 && frame.count > node.maxItems {
+				v.fail(check.sink, "maxItems", "more than "+strconv.Itoa(node.maxItems)+" items")
+			}
+		case SCHEMA_TYPE_OBJECT:
+			for _, name := range node.required {
+				if !frame.keys[name] {
+					v.fail(check.sink, "required", "missing property "+strconv.Quote(name))
+				}
+			}
+		}
+		if node.hasConst && !genericEqual(captured, node.constValue) {
+			v.fail(check.sink, "const", "does not equal the constant value")
+		}
+		if node.enum != nil {
+			found := false
+			for _, value := range node.enum {
+				if genericEqual(captured, value) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				v.fail(check.sink, "enum", "is not one of the enumerated values")
+			}
+		}
+	}
+
+	// combos nested inside other combos' branches were added later, so
+	// settle them first
+	for i := len(frame.combos) - 1; i >= 0; i-- {
+		combo := &frame.combos[i]
+		matched := 0
+		for _, branch := range combo.branches {
+			if len(branch.errors) == 0 {
+				matched++
+			}
+		}
+		if combo.oneOf && matched != 1 {
+			v.fail(combo.sink, "oneOf", "matches "+strconv.Itoa(matched)+" of the schemas instead of exactly one")
+		} else if !combo.oneOf && matched == 0 {
+			v.fail(combo.sink, "anyOf", "does not match any of the schemas")
+		}
+	}
+
+	v.frames = v.frames[:last]
+}
+
+func (v *schemaValidator) feedCaptures(evt event_t, isKey bool) {
+	for i := range v.frames {
+		if capture := v.frames[i].capture; capture != nil {
+			capture.onEvent(evt, isKey)
+		}
+	}
+}
+
+func validateOnEvent(p *Parser, evt event_t) {
+	v := p.UserData.(*schemaValidator)
+	isKey := evt == EVT_STRING && p.IsDictKey()
+	switch evt {
+	case EVT_NULL, EVT_TRUE, EVT_FALSE:
+		if evt == EVT_NULL {
+			v.startValue(SCHEMA_TYPE_NULL)
+		} else {
+			v.startValue(SCHEMA_TYPE_BOOLEAN)
+		}
+		if v.captures != 0 {
+			v.feedCaptures(evt, false)
+		}
+		v.endValue()
+		return
+	case EVT_ARRAY:
+		v.startValue(SCHEMA_TYPE_ARRAY)
+	case EVT_DICT:
+		v.startValue(SCHEMA_TYPE_OBJECT)
+	case EVT_STRING:
+		if isKey {
+			v.token = WRITER_TOKEN_KEY
+			v.key = v.key[:0]
+		} else {
+			v.token = WRITER_TOKEN_STRING
+			v.startValue(SCHEMA_TYPE_STRING)
+		}
+	case EVT_NUMBER:
+		v.token = WRITER_TOKEN_NUMBER
+		v.startValue(SCHEMA_TYPE_NUMBER)
+		v.frames[len(v.frames)-1].needText = true
+	case EVT_LEAVE:
+		if v.captures != 0 {
+			v.feedCaptures(evt, false)
+		}
+		if v.token == WRITER_TOKEN_KEY {
+			v.lastKey = string(v.key)
+			if frame := &v.frames[len(v.frames)-1]; frame.keys != nil {
+				frame.keys[v.lastKey] = true
+			}
+		} else {
+			v.endValue()
+		}
+		v.token = WRITER_TOKEN_NONE
+		return
+	}
+	if v.captures != 0 {
+		v.feedCaptures(evt, isKey)
+	}
+}
+
+func validateOnData(p *Parser, endOfData bool) {
+	v := p.UserData.(*schemaValidator)
+	if v.captures != 0 {
+		for i := range v.frames {
+			if capture := v.frames[i].capture; capture != nil {
+				capture.onData(p.DataBuffer)
+			}
+		}
+	}
+	if v.token == WRITER_TOKEN_KEY {
+		v.key = append(v.key, p.DataBuffer...)
+		return
+	}
+	frame := &v.frames[len(v.frames)-1]
+	frame.length += countColumns(p.DataBuffer)
+	if frame.needText {
+		frame.text = append(frame.text, p.DataBuffer...)
+	}
+}
+
+// Validate checks the document read from byteReader against the schema as
+// it streams past, holding only the values some keyword needs whole (enum,
+// const and pattern subjects), and returns every violation found in order
+// of where the offending values start
+//
+// The error is only for documents that are not well formed json
+func (s *Schema) Validate(byteReader io.ByteReader) ([]SchemaError, error) {
+	v := schemaValidator{root: s.root, reader: countingByteReader{reader: byteReader}}
+	parser := documentParsers.Get()
+	defer documentParsers.Put(parser)
+	parser.UserData = &v
+	if err := parser.Parse(&v.reader, validateOnEvent, validateOnData); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	errors := v.result.errors
+	sort.SliceStable(errors, func(i, j int) bool {
+		return errors[i].Offset < errors[j].Offset
+	})
+	return errors, nil
+}
@@ -0,0 +1,152 @@
+package EvLJson
+
+import (
+	"bytes"
+	"testing"
+)
+
+const TEST_SCHEMA = `{
+	"$defs": {
+		"tag": {"type": "string", "minLength": 1, "maxLength": 8, "pattern": "^[a-z]+$"}
+	},
+	"type": "object",
+	"required": ["id", "tags"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"ratio": {"type": "number", "minimum": 0, "maximum": 1},
+		"kind": {"enum": ["a", "b", {"c": [1]}]},
+		"version": {"const": 2},
+		"tags": {"type": "array", "items": {"$ref": "#/$defs/tag"}, "minItems": 1, "maxItems": 3},
+		"owner": {"anyOf": [{"type": "null"}, {"type": "string"}]},
+		"shape": {"oneOf": [
+			{"type": "object", "required": ["r"]},
+			{"type": "object", "required": ["w"]}
+		]},
+		"both": {"allOf": [{"type": "string"}, {"minLength": 2}]}
+	}
+}`
+
+func compileTestSchema(t *testing.T, schema string) *Schema {
+	compiled, err := CompileSchema(bytes.NewReader([]byte(schema)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return compiled
+}
+
+func TestSchemaValid(t *testing.T) {
+	schema := compileTestSchema(t, TEST_SCHEMA)
+	testCases := []string{
+		`{"id":1,"tags":["a"]}`,
+		`{"id":2.0,"ratio":0.5,"kind":{"c":[1.0]},"version":2,"tags":["ab","cd"],"owner":null,"shape":{"r":1},"both":"xy"}`,
+		`{"id":3,"tags":["a"],"owner":"me","shape":{"w":1}}`,
+	}
+	for _, str := range testCases {
+		t.Logf(LOG_STMT_FMT, str)
+		errors, err := schema.Validate(bytes.NewReader([]byte(str)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errors) != 0 {
+			t.Fatal(errors)
+		}
+	}
+}
+
+func TestSchemaViolations(t *testing.T) {
+	schema := compileTestSchema(t, TEST_SCHEMA)
+	testCases := []struct {
+		json    string
+		pointer string
+		offset  int64
+		keyword string
+	}{
+		{`{"tags":["a"]}`, "", 0, "required"},
+		{`{"id":0,"tags":["a"]}`, "/id", 6, "minimum"},
+		{`{"id":1.5,"tags":["a"]}`, "/id", 6, "type"},
+		{`{"id":"1","tags":["a"]}`, "/id", 6, "type"},
+		{`{"id":1,"tags":["a"],"x~/":1}`, "/x~0~1", 27, "additionalProperties"},
+		{`{"id":1,"tags":[]}`, "/tags", 15, "minItems"},
+		{`{"id":1,"tags":["a","b","c","d"]}`, "/tags", 15, "maxItems"},
+		{`{"id":1,"tags":["a",""]}`, "/tags/1", 20, "minLength"},
+		{`{"id":1,"tags":["abcdefghi"]}`, "/tags/0", 16, "maxLength"},
+		{`{"id":1,"tags":["A"]}`, "/tags/0", 16, "pattern"},
+		{`{"id":1,"tags":["a"],"ratio":2}`, "/ratio", 29, "maximum"},
+		{`{"id":1,"tags":["a"],"kind":{"c":[2]}}`, "/kind", 28, "enum"},
+		{`{"id":1,"tags":["a"],"version":3}`, "/version", 31, "const"},
+		{`{"id":1,"tags":["a"],"owner":1}`, "/owner", 29, "anyOf"},
+		{`{"id":1,"tags":["a"],"shape":{"r":1,"w":1}}`, "/shape", 29, "oneOf"},
+		{`{"id":1,"tags":["a"],"both":"x"}`, "/both", 28, "minLength"},
+	}
+	for _, testCase := range testCases {
+		t.Logf(LOG_STMT_FMT, testCase.json)
+		errors, err := schema.Validate(bytes.NewReader([]byte(testCase.json)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errors) == 0 {
+			t.FailNow()
+		}
+		for _, found := range errors {
+			if found.Pointer != testCase.pointer || found.Offset != testCase.offset {
+				t.Fatal(found)
+			}
+		}
+		if errors[0].Keyword != testCase.keyword {
+			t.Fatal(errors)
+		}
+	}
+}
+
+func TestSchemaReportsEveryViolation(t *testing.T) {
+	schema := compileTestSchema(t, TEST_SCHEMA)
+	errors, err := schema.Validate(bytes.NewReader([]byte(`{"id":0,"tags":["A",1]}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errors) != 3 || errors[0].Pointer != "/id" || errors[1].Pointer != "/tags/0" || errors[2].Pointer != "/tags/1" {
+		t.Fatal(errors)
+	}
+}
+
+func TestSchemaRecursiveRef(t *testing.T) {
+	schema := compileTestSchema(t, `{"type":"object","properties":{"child":{"$ref":"#"},"n":{"type":"integer"}}}`)
+	errors, err := schema.Validate(bytes.NewReader([]byte(`{"n":1,"child":{"child":{"n":"x"}}}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errors) != 1 || errors[0].Pointer != "/child/child/n" {
+		t.Fatal(errors)
+	}
+	// a cycle consuming no input is caught when compiling
+	if _, err := CompileSchema(bytes.NewReader([]byte(`{"$ref":"#"}`))); err != (InvalidSchemaError{"", "$ref cycle that consumes no input"}) {
+		t.Fatal(err)
+	}
+	// shared, acyclic references are fine
+	compileTestSchema(t, `{"$defs":{"a":{"type":"integer"}},"allOf":[{"$ref":"#/$defs/a"},{"$ref":"#/$defs/a"}],"anyOf":[{"$ref":"#/$defs/a"}]}`)
+}
+
+func TestSchemaInvalid(t *testing.T) {
+	testCases := []string{
+		`{"type":"thing"}`,
+		`{"type":" string "}`,
+		`{"type":["integer","null\n"]}`,
+		`{"type":"String"}`,
+		`{"minItems":-1}`,
+		`{"$ref":"#/missing"}`,
+		`{"$ref":"other.json"}`,
+		`{"pattern":"("}`,
+		`{"anyOf":[]}`,
+		`{"properties":{"a":1}}`,
+		`{"$ref":"#"}`,
+		`{"$defs":{"a":{"$ref":"#/$defs/b"},"b":{"allOf":[{"$ref":"#/$defs/a"}]}},"properties":{"x":{"$ref":"#/$defs/a"}}}`,
+		`{"anyOf":[{"type":"string"},{"oneOf":[{"$ref":"#"}]}]}`,
+	}
+	for _, str := range testCases {
+		t.Logf(LOG_STMT_FMT, str)
+		if _, err := CompileSchema(bytes.NewReader([]byte(str))); err == nil {
+			t.FailNow()
+		}
+	}
+}
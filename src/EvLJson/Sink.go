@@ -0,0 +1,884 @@
+package EvLJson
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Sink receives one json value; containers hand out a Sink for each of
+// their children, so decoders for whole type graphs can be composed from
+// small pieces without reflection
+//
+// String and Number receive complete values, only valid for the duration
+// of the call; End is called once a container's children are done
+type Sink interface {
+	Null() error
+	Bool(value bool) error
+	String(value []byte) error
+	Number(text []byte) error
+	BeginArray() error
+	Item() (Sink, error)
+	BeginDict() error
+	Member(key []byte) (Sink, error)
+	End() error
+}
+
+// ChildEnder is implemented by container Sinks that need to know as soon
+// as each child value is complete rather than when the next one starts
+type ChildEnder interface {
+	ChildEnd() error
+}
+
+// SinkTypeError is a json value that cannot be stored in the go type
+type SinkTypeError struct {
+	JsonType string
+	GoType   string
+}
+
+func (err SinkTypeError) Error() string {
+	return "Cannot decode json " + err.JsonType + " into go value of type " + err.GoType
+}
+
+// DecodeError places an error from a Sink in the document
+type DecodeError struct {
+	Pointer string // json pointer to the value being decoded
+	Offset  int64  // byte offset in the document the value starts at
+	Err     error
+}
+
+func (err DecodeError) Error() string {
+	return "\"" + err.Pointer + "\" at offset " + strconv.FormatInt(err.Offset, 10) + ": " + err.Err.Error()
+}
+
+func (err DecodeError) Unwrap() error {
+	return err.Err
+}
+
+// BaseSink rejects every json value as a type mismatch for GoType, except
+// null which is ignored as encoding/json does; Sinks embed it and override
+// the methods for the values they accept
+type BaseSink struct {
+	GoType string
+}
+
+func (s BaseSink) Null() error {
+	return nil
+}
+
+func (s BaseSink) Bool(value bool) error {
+	return SinkTypeError{"bool", s.GoType}
+}
+
+func (s BaseSink) String(value []byte) error {
+	return SinkTypeError{"string", s.GoType}
+}
+
+func (s BaseSink) Number(text []byte) error {
+	return SinkTypeError{"number", s.GoType}
+}
+
+func (s BaseSink) BeginArray() error {
+	return SinkTypeError{"array", s.GoType}
+}
+
+func (s BaseSink) Item() (Sink, error) {
+	return nil, SinkTypeError{"array", s.GoType}
+}
+
+func (s BaseSink) BeginDict() error {
+	return SinkTypeError{"dict", s.GoType}
+}
+
+func (s BaseSink) Member(key []byte) (Sink, error) {
+	return nil, SinkTypeError{"dict", s.GoType}
+}
+
+func (s BaseSink) End() error {
+	return nil
+}
+
+type skipSink struct{}
+
+func (s skipSink) Null() error                     { return nil }
+func (s skipSink) Bool(value bool) error           { return nil }
+func (s skipSink) String(value []byte) error       { return nil }
+func (s skipSink) Number(text []byte) error        { return nil }
+func (s skipSink) BeginArray() error               { return nil }
+func (s skipSink) Item() (Sink, error)             { return s, nil }
+func (s skipSink) BeginDict() error                { return nil }
+func (s skipSink) Member(key []byte) (Sink, error) { return s, nil }
+func (s skipSink) End() error                      { return nil }
+
+// SkipSink accepts and discards any value, e.g. unknown dict members
+func SkipSink() Sink {
+	return skipSink{}
+}
+
+type stringSink[T ~string] struct {
+	BaseSink
+	p *T
+}
+
+func (s *stringSink[T]) String(value []byte) error {
+	*s.p = T(value)
+	return nil
+}
+
+func StringSink[T ~string](p *T) Sink {
+	return &stringSink[T]{BaseSink{"string"}, p}
+}
+
+type boolSink[T ~bool] struct {
+	BaseSink
+	p *T
+}
+
+func (s *boolSink[T]) Bool(value bool) error {
+	*s.p = T(value)
+	return nil
+}
+
+func BoolSink[T ~bool](p *T) Sink {
+	return &boolSink[T]{BaseSink{"bool"}, p}
+}
+
+type NumberRangeError struct {
+	Number string
+	GoType string
+}
+
+func (err NumberRangeError) Error() string {
+	return "Number " + err.Number + " does not fit in go value of type " + err.GoType
+}
+
+type intSink[T ~int | ~int8 | ~int16 | ~int32 | ~int64] struct {
+	BaseSink
+	p *T
+}
+
+func (s *intSink[T]) Number(text []byte) error {
+	number, err := strconv.ParseInt(string(text), 10, 64)
+	if err != nil || int64(T(number)) != number {
+		return NumberRangeError{string(text), s.GoType}
+	}
+	*s.p = T(number)
+	return nil
+}
+
+func IntSink[T ~int | ~int8 | ~int16 | ~int32 | ~int64](p *T) Sink {
+	return &intSink[T]{BaseSink{"int"}, p}
+}
+
+type uintSink[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr] struct {
+	BaseSink
+	p *T
+}
+
+func (s *uintSink[T]) Number(text []byte) error {
+	number, err := strconv.ParseUint(string(text), 10, 64)
+	if err != nil || uint64(T(number)) != number {
+		return NumberRangeError{string(text), s.GoType}
+	}
+	*s.p = T(number)
+	return nil
+}
+
+func UintSink[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr](p *T) Sink {
+	return &uintSink[T]{BaseSink{"uint"}, p}
+}
+
+type floatSink[T ~float32 | ~float64] struct {
+	BaseSink
+	p *T
+}
+
+func (s *floatSink[T]) Number(text []byte) error {
+	number, err := strconv.ParseFloat(string(text), 64)
+	converted := T(number)
+	if err != nil || (float64(converted)-float64(converted) != 0 && number-number == 0) {
+		// out of range, including float32 overflowing to infinity
+		return NumberRangeError{string(text), s.GoType}
+	}
+	*s.p = converted
+	return nil
+}
+
+func FloatSink[T ~float32 | ~float64](p *T) Sink {
+	return &floatSink[T]{BaseSink{"float"}, p}
+}
+
+type bytesSink struct {
+	BaseSink
+	p *[]byte
+}
+
+func (s *bytesSink) String(value []byte) error {
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(value)))
+	n, err := base64.StdEncoding.Decode(decoded, value)
+	if err != nil {
+		return err
+	}
+	*s.p = decoded[:n]
+	return nil
+}
+
+func (s *bytesSink) Null() error {
+	*s.p = nil
+	return nil
+}
+
+// BytesSink decodes base64 strings, as encoding/json does for []byte
+func BytesSink(p *[]byte) Sink {
+	return &bytesSink{BaseSink{"[]byte"}, p}
+}
+
+type sliceSink[T any] struct {
+	BaseSink
+	p    *[]T
+	elem func(*T) Sink
+}
+
+func (s *sliceSink[T]) Null() error {
+	*s.p = nil
+	return nil
+}
+
+func (s *sliceSink[T]) BeginArray() error {
+	if *s.p == nil {
+		*s.p = []T{}
+	} else {
+		*s.p = (*s.p)[:0]
+	}
+	return nil
+}
+
+func (s *sliceSink[T]) Item() (Sink, error) {
+	var zero T
+	*s.p = append(*s.p, zero)
+	return s.elem(&(*s.p)[len(*s.p)-1]), nil
+}
+
+// SliceSink decodes arrays, using elem to decode each item in place
+func SliceSink[T any](p *[]T, elem func(*T) Sink) Sink {
+	return &sliceSink[T]{BaseSink{"slice"}, p, elem}
+}
+
+// map values are decoded into value and stored once the next member starts
+// or the dict ends, since a Sink is not told when its child is done
+type mapSink[V any] struct {
+	BaseSink
+	p       *map[string]V
+	elem    func(*V) Sink
+	key     string
+	value   V
+	pending bool
+}
+
+func (s *mapSink[V]) Null() error {
+	*s.p = nil
+	return nil
+}
+
+func (s *mapSink[V]) BeginDict() error {
+	if *s.p == nil {
+		*s.p = map[string]V{}
+	}
+	return nil
+}
+
+func (s *mapSink[V]) commit() {
+	if s.pending {
+		(*s.p)[s.key] = s.value
+		var zero V
+		s.value = zero
+		s.pending = false
+	}
+}
+
+func (s *mapSink[V]) Member(key []byte) (Sink, error) {
+	s.commit()
+	s.key = string(key)
+	s.value = (*s.p)[s.key]
+	s.pending = true
+	return s.elem(&s.value), nil
+}
+
+func (s *mapSink[V]) End() error {
+	s.commit()
+	return nil
+}
+
+// MapSink decodes dicts into maps keyed by member name
+func MapSink[V any](p *map[string]V, elem func(*V) Sink) Sink {
+	return &mapSink[V]{BaseSink: BaseSink{"map"}, p: p, elem: elem}
+}
+
+// pointerSink allocates on the first non-null value and then forwards
+// everything to the Sink for the pointed to value
+type pointerSink[T any] struct {
+	p     **T
+	elem  func(*T) Sink
+	inner Sink
+}
+
+func (s *pointerSink[T]) target() Sink {
+	if s.inner == nil {
+		if *s.p == nil {
+			*s.p = new(T)
+		}
+		s.inner = s.elem(*s.p)
+	}
+	return s.inner
+}
+
+func (s *pointerSink[T]) Null() error {
+	*s.p = nil
+	return nil
+}
+
+func (s *pointerSink[T]) Bool(value bool) error {
+	return s.target().Bool(value)
+}
+
+func (s *pointerSink[T]) String(value []byte) error {
+	return s.target().String(value)
+}
+
+func (s *pointerSink[T]) Number(text []byte) error {
+	return s.target().Number(text)
+}
+
+func (s *pointerSink[T]) BeginArray() error {
+	return s.target().BeginArray()
+}
+
+func (s *pointerSink[T]) Item() (Sink, error) {
+	return s.target().Item()
+}
+
+func (s *pointerSink[T]) BeginDict() error {
+	return s.target().BeginDict()
+}
+
+func (s *pointerSink[T]) Member(key []byte) (Sink, error) {
+	return s.target().Member(key)
+}
+
+func (s *pointerSink[T]) End() error {
+	return s.target().End()
+}
+
+func PointerSink[T any](p **T, elem func(*T) Sink) Sink {
+	return &pointerSink[T]{p: p, elem: elem}
+}
+
+// anySink decodes into interface{} the way encoding/json does: nil, bool,
+// float64 (or json.Number), string, []interface{} and map[string]interface{}
+type anySink struct {
+	p         *interface{}
+	useNumber bool
+	array     []interface{}
+	dict      map[string]interface{}
+	inner     Sink
+}
+
+func (s *anySink) elem(p *interface{}) Sink {
+	return &anySink{p: p, useNumber: s.useNumber}
+}
+
+func (s *anySink) Null() error {
+	*s.p = nil
+	return nil
+}
+
+func (s *anySink) Bool(value bool) error {
+	*s.p = value
+	return nil
+}
+
+func (s *anySink) String(value []byte) error {
+	*s.p = string(value)
+	return nil
+}
+
+func (s *anySink) Number(text []byte) error {
+	if s.useNumber {
+		*s.p = json.Number(text)
+		return nil
+	}
+	number, err := strconv.ParseFloat(string(text), 64)
+	if err != nil {
+		return NumberRangeError{string(text), "float64"}
+	}
+	*s.p = number
+	return nil
+}
+
+func (s *anySink) BeginArray() error {
+	s.inner = SliceSink(&s.array, s.elem)
+	return s.inner.BeginArray()
+}
+
+func (s *anySink) Item() (Sink, error) {
+	return s.inner.Item()
+}
+
+func (s *anySink) BeginDict() error {
+	s.inner = MapSink(&s.dict, s.elem)
+	return s.inner.BeginDict()
+}
+
+func (s *anySink) Member(key []byte) (Sink, error) {
+	return s.inner.Member(key)
+}
+
+func (s *anySink) End() error {
+	err := s.inner.End()
+	if s.dict != nil {
+		*s.p = s.dict
+	} else {
+		*s.p = s.array
+	}
+	return err
+}
+
+func AnySink(p *interface{}) Sink {
+	return &anySink{p: p}
+}
+
+// writerSink writes the value it receives through w and calls done once
+// the value is complete
+type writerSink struct {
+	w     *Writer
+	depth int
+	done  func() error
+}
+
+func (s *writerSink) finish() error {
+	if s.depth != 0 || s.done == nil {
+		return s.w.Err()
+	}
+	return s.done()
+}
+
+func (s *writerSink) Null() error {
+	s.w.Null()
+	return s.finish()
+}
+
+func (s *writerSink) Bool(value bool) error {
+	s.w.Bool(value)
+	return s.finish()
+}
+
+func (s *writerSink) String(value []byte) error {
+	s.w.BeginString()
+	s.w.StringData(value)
+	s.w.EndString()
+	return s.finish()
+}
+
+func (s *writerSink) Number(text []byte) error {
+	s.w.BeginNumber()
+	s.w.NumberData(text)
+	s.w.EndNumber()
+	return s.finish()
+}
+
+func (s *writerSink) BeginArray() error {
+	s.depth++
+	s.w.BeginArray()
+	return nil
+}
+
+func (s *writerSink) Item() (Sink, error) {
+	return s, nil
+}
+
+func (s *writerSink) BeginDict() error {
+	s.depth++
+	s.w.BeginDict()
+	return nil
+}
+
+func (s *writerSink) Member(key []byte) (Sink, error) {
+	s.w.BeginKey()
+	s.w.StringData(key)
+	s.w.EndString()
+	return s, nil
+}
+
+func (s *writerSink) End() error {
+	s.depth--
+	s.w.Leave()
+	return s.finish()
+}
+
+// RawSink collects the json text of one value, minified and with string
+// escapes normalised, and passes it to done
+func RawSink(done func(raw []byte) error) Sink {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	return &writerSink{w: &w, done: func() error {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return done(out.Bytes())
+	}}
+}
+
+// WriterSink writes the value it receives through w
+func WriterSink(w *Writer) Sink {
+	return &writerSink{w: w}
+}
+
+// UnmarshalerSink hands the value to a json.Unmarshaler
+func UnmarshalerSink(u json.Unmarshaler) Sink {
+	return RawSink(u.UnmarshalJSON)
+}
+
+type textUnmarshalerSink struct {
+	BaseSink
+	u encoding.TextUnmarshaler
+}
+
+func (s *textUnmarshalerSink) String(value []byte) error {
+	return s.u.UnmarshalText(value)
+}
+
+// TextUnmarshalerSink hands string values to an encoding.TextUnmarshaler
+func TextUnmarshalerSink(u encoding.TextUnmarshaler) Sink {
+	return &textUnmarshalerSink{BaseSink{"encoding.TextUnmarshaler"}, u}
+}
+
+// AutoSink picks a Sink for v at run time using type assertions only: it
+// handles json.Unmarshaler, encoding.TextUnmarshaler and pointers to the
+// builtin types, and rejects everything else
+func AutoSink(v interface{}) Sink {
+	switch p := v.(type) {
+	case json.Unmarshaler:
+		return UnmarshalerSink(p)
+	case encoding.TextUnmarshaler:
+		return TextUnmarshalerSink(p)
+	case *string:
+		return StringSink(p)
+	case *bool:
+		return BoolSink(p)
+	case *int:
+		return IntSink(p)
+	case *int8:
+		return IntSink(p)
+	case *int16:
+		return IntSink(p)
+	case *int32:
+		return IntSink(p)
+	case *int64:
+		return IntSink(p)
+	case *uint:
+		return UintSink(p)
+	case *uint8:
+		return UintSink(p)
+	case *uint16:
+		return UintSink(p)
+	case *uint32:
+		return UintSink(p)
+	case *uint64:
+		return UintSink(p)
+	case *float32:
+		return FloatSink(p)
+	case *float64:
+		return FloatSink(p)
+	case *[]byte:
+		return BytesSink(p)
+	case *interface{}:
+		return AnySink(p)
+	}
+	return BaseSink{"unsupported type"}
+}
+
+// FoldKey lowers a dict key for the case insensitive member matching
+// encoding/json falls back to
+func FoldKey(key []byte) string {
+	return strings.ToLower(string(key))
+}
+
+type sinkFrame struct {
+	sink   Sink
+	isDict bool
+	index  int
+	key    string
+}
+
+// sinkHandler feeds parser events into a tree of Sinks
+type sinkHandler struct {
+	reader countingByteReader
+	frames []sinkFrame
+	root   Sink
+	target Sink // the string or number being read
+	offset int64
+	token  writerToken_t
+	text   []byte
+	err    error
+	// with deferTypeErrors set, values that do not fit their go type are
+	// skipped as encoding/json does, the first of them kept in typeErr
+	deferTypeErrors bool
+	typeErr         error
+}
+
+func (h *sinkHandler) pointer() string {
+	var pointer strings.Builder
+	for _, frame := range h.frames {
+		pointer.WriteByte('/')
+		if frame.isDict {
+			pointer.WriteString(escapePointerToken(frame.key))
+		} else {
+			pointer.WriteString(strconv.Itoa(frame.index - 1))
+		}
+	}
+	return pointer.String()
+}
+
+func (h *sinkHandler) fail(err error) {
+	if h.err != nil || err == nil {
+		return
+	}
+	switch err.(type) {
+	case SinkTypeError, NumberRangeError:
+		if h.deferTypeErrors {
+			if h.typeErr == nil {
+				h.typeErr = DecodeError{h.pointer(), h.offset, err}
+			}
+			return
+		}
+	}
+	h.err = DecodeError{h.pointer(), h.offset, err}
+}
+
+// the Sink for the value starting now
+func (h *sinkHandler) next() Sink {
+	h.offset = h.reader.offset()
+	if len(h.frames) == 0 {
+		return h.root
+	}
+	frame := &h.frames[len(h.frames)-1]
+	var sink Sink
+	var err error
+	if frame.sink == nil {
+		// the children of a skipped container are skipped too
+		if !frame.isDict {
+			frame.index++
+		}
+		return nil
+	}
+	if frame.isDict {
+		sink, err = frame.sink.Member([]byte(frame.key))
+	} else {
+		frame.index++
+		sink, err = frame.sink.Item()
+	}
+	h.fail(err)
+	return sink
+}
+
+// childEnd tells the enclosing container's Sink, if it wants to know, that
+// the value it handed out a Sink for is complete
+func (h *sinkHandler) childEnd() {
+	if h.err != nil || len(h.frames) == 0 {
+		return
+	}
+	if ender, ok := h.frames[len(h.frames)-1].sink.(ChildEnder); ok {
+		h.fail(ender.ChildEnd())
+	}
+}
+
+func sinkOnEvent(p *Parser, evt event_t) {
+	h := p.UserData.(*sinkHandler)
+	switch evt {
+	case EVT_NULL:
+		if sink := h.next(); sink != nil {
+			h.fail(sink.Null())
+		}
+		h.childEnd()
+	case EVT_TRUE, EVT_FALSE:
+		if sink := h.next(); sink != nil {
+			h.fail(sink.Bool(evt == EVT_TRUE))
+		}
+		h.childEnd()
+	case EVT_ARRAY:
+		sink := h.next()
+		if sink != nil {
+			if err := sink.BeginArray(); err != nil {
+				h.fail(err)
+				sink = nil
+			}
+		}
+		h.frames = append(h.frames, sinkFrame{sink: sink})
+	case EVT_DICT:
+		sink := h.next()
+		if sink != nil {
+			if err := sink.BeginDict(); err != nil {
+				h.fail(err)
+				sink = nil
+			}
+		}
+		h.frames = append(h.frames, sinkFrame{sink: sink, isDict: true})
+	case EVT_STRING:
+		if p.IsDictKey() {
+			h.token = WRITER_TOKEN_KEY
+		} else {
+			h.token = WRITER_TOKEN_STRING
+			h.target = h.next()
+		}
+	case EVT_NUMBER:
+		h.token = WRITER_TOKEN_NUMBER
+		h.target = h.next()
+	case EVT_LEAVE:
+		switch h.token {
+		case WRITER_TOKEN_KEY:
+			h.frames[len(h.frames)-1].key = string(h.text)
+		case WRITER_TOKEN_STRING:
+			if h.target != nil {
+				h.fail(h.target.String(h.text))
+			}
+			h.childEnd()
+		case WRITER_TOKEN_NUMBER:
+			if h.target != nil {
+				h.fail(h.target.Number(h.text))
+			}
+			h.childEnd()
+		default:
+			last := len(h.frames) - 1
+			if h.frames[last].sink != nil {
+				h.fail(h.frames[last].sink.End())
+			}
+			h.frames = h.frames[:last]
+			h.childEnd()
+		}
+		h.token = WRITER_TOKEN_NONE
+		h.text = h.text[:0]
+	}
+	if h.err != nil {
+		p.ParseStop()
+	}
+}
+
+func sinkOnData(p *Parser, endOfData bool) {
+	h := p.UserData.(*sinkHandler)
+	h.text = append(h.text, p.DataBuffer...)
+}
+
+// decodeValue feeds the json value in text, which may be a scalar, into
+// sink; the value is wrapped in an array since documents must be arrays
+// or dicts
+func decodeValue(text []byte, sink Sink) error {
+	items := 0
+	wrapper := SliceSink(new([]struct{}), func(*struct{}) Sink {
+		items++
+		return sink
+	})
+	document := make([]byte, 0, len(text)+2)
+	document = append(append(append(document, '['), text...), ']')
+	if err := DecodeSink(bytes.NewReader(document), wrapper); err != nil {
+		return err
+	}
+	if items != 1 {
+		return InvalidValueError{string(text)}
+	}
+	return nil
+}
+
+// InvalidValueError is text that should have held exactly one json value
+type InvalidValueError struct {
+	Text string
+}
+
+func (err InvalidValueError) Error() string {
+	return "Not a single json value: " + strconv.Quote(err.Text)
+}
+
+// DecodeSink parses one document from byteReader into sink, which may be
+// a scalar; errors from sinks stop the parse and come back as a
+// DecodeError
+func DecodeSink(byteReader io.ByteReader, sink Sink) error {
+	return decodeSink(byteReader, sink, false)
+}
+
+// decodeSink is DecodeSink, going on past values that do not fit their go
+// type with deferTypeErrors set
+func decodeSink(byteReader io.ByteReader, sink Sink, deferTypeErrors bool) error {
+	// the parser reads arrays and dicts, scalars are wrapped in an array
+	start := replayByteReader{reader: byteReader}
+	whitespace := int64(0)
+	for {
+		b, err := byteReader.ReadByte()
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+		if !isCharWhitespace(b) {
+			start.b, start.replay = b, true
+			break
+		}
+		whitespace++
+	}
+	if start.b != '[' && start.b != '{' {
+		return decodeScalar(&start, whitespace, sink)
+	}
+	h := sinkHandler{reader: countingByteReader{reader: &start, count: whitespace}, root: sink, deferTypeErrors: deferTypeErrors}
+	parser := documentParsers.Get()
+	defer documentParsers.Put(parser)
+	parser.UserData = &h
+	err := parser.Parse(&h.reader, sinkOnEvent, sinkOnData)
+	if h.err != nil {
+		return h.err
+	}
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return err
+	}
+	return h.typeErr
+}
+
+// decodeScalar is DecodeSink for a document that is not an array or dict,
+// at offset whitespace of the input
+func decodeScalar(byteReader io.ByteReader, whitespace int64, sink Sink) error {
+	var text []byte
+	for {
+		b, err := byteReader.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		text = append(text, b)
+	}
+	err := decodeValue(text, sink)
+	if decodeErr, ok := err.(DecodeError); ok && decodeErr.Pointer == "/0" {
+		// placed in the document as it was given, not in the wrapping array
+		return DecodeError{"", decodeErr.Offset - 1 + whitespace, decodeErr.Err}
+	}
+	return err
+}
+
+// replayByteReader gives the byte it holds before those of reader
+type replayByteReader struct {
+	reader io.ByteReader
+	b      byte
+	replay bool
+}
+
+func (r *replayByteReader) ReadByte() (byte, error) {
+	if r.replay {
+		r.replay = false
+		return r.b, nil
+	}
+	return r.reader.ReadByte()
+}
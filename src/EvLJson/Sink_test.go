@@ -0,0 +1,76 @@
+package EvLJson
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnySink(t *testing.T) {
+	doc := `{"a":[1,2.5,-0,"x",null,true,false,{}],"b":{"c":[[]]},"é":"😀"}`
+	var decoded interface{}
+	if err := DecodeSink(strings.NewReader(doc), AnySink(&decoded)); err != nil {
+		t.Fatal(err)
+	}
+	var expected interface{}
+	if err := json.Unmarshal([]byte(doc), &expected); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Logf("%#v", decoded)
+		t.Fatalf("%#v", expected)
+	}
+}
+
+func TestNumberSinkRanges(t *testing.T) {
+	var i8 int8
+	var u32 uint32
+	var f32 float32
+	tests := []struct {
+		doc  string
+		sink Sink
+		ok   bool
+	}{
+		{`[127]`, IntSink(&i8), true},
+		{`[128]`, IntSink(&i8), false},
+		{`[-1]`, UintSink(&u32), false},
+		{`[4294967295]`, UintSink(&u32), true},
+		{`[1e39]`, FloatSink(&f32), false},
+		{`[1e38]`, FloatSink(&f32), true},
+		{`[1e400]`, FloatSink(new(float64)), false},
+		{`[1.5]`, IntSink(new(int)), false},
+	}
+	for _, test := range tests {
+		err := DecodeSink(strings.NewReader(test.doc), SliceSink(new([]struct{}), func(*struct{}) Sink { return test.sink }))
+		var rangeErr NumberRangeError
+		if test.ok != (err == nil) || (err != nil && !errors.As(err, &rangeErr)) {
+			t.Logf(LOG_STMT_FMT, test.doc)
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRawSink(t *testing.T) {
+	var raw []string
+	sink := SliceSink(new([]struct{}), func(*struct{}) Sink {
+		return RawSink(func(data []byte) error {
+			raw = append(raw, string(data))
+			return nil
+		})
+	})
+	if err := DecodeSink(strings.NewReader(`[ {"a" : [1, "A"]}, 2e5, null, "s" ]`), sink); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(raw, " ") != `{"a":[1,"A"]} 2e5 null "s"` {
+		t.Fatal(raw)
+	}
+}
+
+func TestDecodeSinkTruncated(t *testing.T) {
+	var decoded interface{}
+	if err := DecodeSink(strings.NewReader(`{"a":[1,`), AnySink(&decoded)); err == nil {
+		t.Fatal("no error for a truncated document")
+	}
+}
@@ -0,0 +1,284 @@
+package EvLJson
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"iter"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type PointerNotFoundError struct {
+	Pointer string
+}
+
+func (err PointerNotFoundError) Error() string {
+	return "Nothing at json pointer " + strconv.Quote(err.Pointer)
+}
+
+type InvalidPointerError struct {
+	Pointer string
+}
+
+func (err InvalidPointerError) Error() string {
+	return "Invalid json pointer " + strconv.Quote(err.Pointer)
+}
+
+// errStopStream ends the parse once the consumer or the array is done
+var errStopStream = errors.New("stream stopped")
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, InvalidPointerError{pointer}
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i := range tokens {
+		tokens[i] = unescapePointerToken(tokens[i])
+	}
+	return tokens, nil
+}
+
+// streamPathSink follows the remaining pointer tokens down the document,
+// skipping everything off the path
+type streamPathSink struct {
+	tokens []string
+	target Sink
+	index  int
+	item   int // the item tokens[0] names, -1 when it is no index
+}
+
+func newStreamPathSink(tokens []string, target Sink) *streamPathSink {
+	item, ok := pointerIndex(tokens[0])
+	if !ok {
+		item = -1
+	}
+	return &streamPathSink{tokens: tokens, target: target, item: item}
+}
+
+func (s *streamPathSink) child() Sink {
+	if len(s.tokens) == 1 {
+		return s.target
+	}
+	return newStreamPathSink(s.tokens[1:], s.target)
+}
+
+func (s *streamPathSink) Null() error               { return nil }
+func (s *streamPathSink) Bool(value bool) error     { return nil }
+func (s *streamPathSink) String(value []byte) error { return nil }
+func (s *streamPathSink) Number(text []byte) error  { return nil }
+func (s *streamPathSink) BeginArray() error         { return nil }
+func (s *streamPathSink) BeginDict() error          { return nil }
+func (s *streamPathSink) End() error                { return nil }
+
+func (s *streamPathSink) Item() (Sink, error) {
+	index := s.index
+	s.index++
+	if index == s.item {
+		return s.child(), nil
+	}
+	return SkipSink(), nil
+}
+
+func (s *streamPathSink) Member(key []byte) (Sink, error) {
+	if string(key) == s.tokens[0] {
+		return s.child(), nil
+	}
+	return SkipSink(), nil
+}
+
+// streamArraySink decodes each item of the array into a fresh T and hands
+// it to yield the moment the item is complete
+type streamArraySink[T any] struct {
+	BaseSink
+	yield   func(T, error) bool
+	item    T
+	found   bool
+	stopped bool
+}
+
+func (s *streamArraySink[T]) BeginArray() error {
+	s.found = true
+	return nil
+}
+
+func (s *streamArraySink[T]) Item() (Sink, error) {
+	var zero T
+	s.item = zero
+	return newReflectSink(reflect.ValueOf(&s.item).Elem(), 0), nil
+}
+
+func (s *streamArraySink[T]) ChildEnd() error {
+	item := s.item
+	var zero T
+	s.item = zero
+	if !s.yield(item, nil) {
+		s.stopped = true
+		return errStopStream
+	}
+	return nil
+}
+
+func (s *streamArraySink[T]) End() error {
+	// nothing after the array is needed
+	return errStopStream
+}
+
+// StreamArray decodes the items of the array at the json pointer one at a
+// time, yielding each as soon as its closing byte has been parsed; only
+// one item is held in memory and the document is not read past the end of
+// the array
+//
+// Items are decoded with Decode's rules; an error is yielded once and ends
+// the sequence, as does an array missing from the document
+func StreamArray[T any](reader io.Reader, pointer string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		tokens, err := parsePointer(pointer)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		target := &streamArraySink[T]{BaseSink: BaseSink{"array"}, yield: yield}
+		var root Sink = target
+		if len(tokens) != 0 {
+			root = newStreamPathSink(tokens, target)
+		}
+		byteReader, ok := reader.(io.ByteReader)
+		if !ok {
+			byteReader = bufio.NewReader(reader)
+		}
+		err = DecodeSink(byteReader, root)
+		switch {
+		case target.stopped:
+		case errors.Is(err, errStopStream):
+		case err != nil:
+			yield(zero, err)
+		case !target.found:
+			yield(zero, PointerNotFoundError{pointer})
+		}
+	}
+}
+
+type streamJob[T any] struct {
+	index int
+	item  T
+}
+
+type streamResult[R any] struct {
+	index int
+	value R
+	err   error
+	final bool // a parse error, which ends the sequence
+}
+
+// StreamArrayPool runs work on the items of the array at the json pointer
+// with a pool of workers goroutines and yields the results, in array order
+// when ordered is set and as they complete otherwise
+//
+// At most 2*workers items are in flight, decoded but not yet yielded, so
+// memory stays bounded however long the array is; an error from work is
+// yielded with its item's result and the sequence goes on, while a parse
+// error is yielded after every earlier item and ends it
+func StreamArrayPool[T, R any](reader io.Reader, pointer string, workers int, ordered bool, work func(T) (R, error)) iter.Seq2[R, error] {
+	return func(yield func(R, error) bool) {
+		if workers < 1 {
+			workers = 1
+		}
+		jobs := make(chan streamJob[T])
+		results := make(chan streamResult[R], workers)
+		window := make(chan struct{}, 2*workers)
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(jobs)
+			index := 0
+			for item, err := range StreamArray[T](reader, pointer) {
+				if err != nil {
+					select {
+					case results <- streamResult[R]{index: index, err: err, final: true}:
+					case <-done:
+					}
+					return
+				}
+				select {
+				case window <- struct{}{}:
+				case <-done:
+					return
+				}
+				select {
+				case jobs <- streamJob[T]{index, item}:
+				case <-done:
+					return
+				}
+				index++
+			}
+		}()
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := range jobs {
+					value, err := work(job.item)
+					select {
+					case results <- streamResult[R]{index: job.index, value: value, err: err}:
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			close(results)
+		}()
+		defer func() {
+			close(done)
+			for range results {
+			}
+		}()
+
+		next := 0 // results yielded so far, and in order mode the next index due
+		held := map[int]streamResult[R]{}
+		var final *streamResult[R]
+		for result := range results {
+			switch {
+			case result.final:
+				final = &result
+			case ordered:
+				held[result.index] = result
+			default:
+				next++
+				<-window
+				if !yield(result.value, result.err) {
+					return
+				}
+			}
+			for ordered {
+				due, exists := held[next]
+				if !exists {
+					break
+				}
+				delete(held, next)
+				next++
+				<-window
+				if !yield(due.value, due.err) {
+					return
+				}
+			}
+			if final != nil && next == final.index {
+				var zero R
+				yield(zero, final.err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package EvLJson
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type streamItem struct {
+	ID   int      `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+const TEST_STREAM_DOC = `{"meta": {"items": "decoy"}, "data": {"items": [` +
+	`{"id": 0, "tags": ["a"]}, {"id": 1}, {"id": 2, "tags": []}, {"id": 3, "tags": ["b", "c"]}` +
+	`]}, "trailing": [1, 2, 3]}`
+
+// blockingReader hands out the document in pieces and blocks past limit
+// until released, to show items arrive before the rest has been read
+type blockingReader struct {
+	data    string
+	limit   int
+	release chan struct{}
+}
+
+func (r *blockingReader) ReadByte() (byte, error) {
+	if r.limit == 0 {
+		<-r.release
+		r.limit = -1
+	}
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	r.limit--
+	return b, nil
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	p[0] = b
+	return 1, nil
+}
+
+func TestStreamArray(t *testing.T) {
+	var ids []int
+	for item, err := range StreamArray[streamItem](strings.NewReader(TEST_STREAM_DOC), "/data/items") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.ID == 1 && item.Tags != nil {
+			// a fresh T per item, nothing carried over
+			t.Fatal(item)
+		}
+		ids = append(ids, item.ID)
+	}
+	if len(ids) != 4 || ids[3] != 3 {
+		t.Fatal(ids)
+	}
+}
+
+func TestStreamArrayYieldsEarly(t *testing.T) {
+	first := strings.Index(TEST_STREAM_DOC, "}") + 1
+	first = strings.Index(TEST_STREAM_DOC[first:], "}") + first + 1
+	reader := &blockingReader{data: TEST_STREAM_DOC, limit: first, release: make(chan struct{})}
+	for item, err := range StreamArray[streamItem](reader, "/data/items") {
+		if err != nil || item.ID != 0 {
+			t.Fatal(item, err)
+		}
+		// reaching here without releasing the reader means the item was
+		// yielded when its closing brace was parsed
+		break
+	}
+}
+
+func TestStreamArrayErrors(t *testing.T) {
+	tests := []struct {
+		doc     string
+		pointer string
+	}{
+		{TEST_STREAM_DOC, "/data/missing"},
+		{TEST_STREAM_DOC, "/meta/items"},
+		{TEST_STREAM_DOC, "data"},
+		{`{"items": [{"id": "x"}]}`, "/items"},
+		{`{"items": [{"id": 1}, {"id": 2`, "/items"},
+	}
+	for _, test := range tests {
+		count, errs := 0, 0
+		for _, err := range StreamArray[streamItem](strings.NewReader(test.doc), test.pointer) {
+			if err != nil {
+				errs++
+			} else {
+				count++
+			}
+		}
+		if errs != 1 || count > 1 {
+			t.Fatal(test.pointer, count, errs)
+		}
+	}
+}
+
+func TestStreamArrayRootAndIndex(t *testing.T) {
+	var got []string
+	for item, err := range StreamArray[string](strings.NewReader(`[["a~/b"], ["x", "y"]]`), "/1") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, item)
+	}
+	if strings.Join(got, ",") != "x,y" {
+		t.Fatal(got)
+	}
+	var numbers []int
+	for item, err := range StreamArray[int](strings.NewReader(`[1, 2, 3]`), "") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, item)
+	}
+	if len(numbers) != 3 {
+		t.Fatal(numbers)
+	}
+}
+
+func streamNumbers(n int) string {
+	var doc strings.Builder
+	doc.WriteString(`{"items": [`)
+	for i := 0; i < n; i++ {
+		if i != 0 {
+			doc.WriteString(",")
+		}
+		doc.WriteString(strconv.Itoa(i))
+	}
+	doc.WriteString(`]}`)
+	return doc.String()
+}
+
+func slowSquare(i int) (int, error) {
+	// later items finish first, to exercise reordering
+	time.Sleep(time.Duration(10-i%10) * 100 * time.Microsecond)
+	if i == 7 {
+		return 0, errors.New("seven")
+	}
+	return i * i, nil
+}
+
+func TestStreamArrayPoolOrdered(t *testing.T) {
+	next := 0
+	for value, err := range StreamArrayPool(strings.NewReader(streamNumbers(100)), "/items", 4, true, slowSquare) {
+		if next == 7 {
+			if err == nil {
+				t.Fatal("missing work error")
+			}
+		} else if err != nil || value != next*next {
+			t.Fatal(next, value, err)
+		}
+		next++
+	}
+	if next != 100 {
+		t.Fatal(next)
+	}
+}
+
+func TestStreamArrayPoolUnordered(t *testing.T) {
+	seen := map[int]bool{}
+	errs := 0
+	for value, err := range StreamArrayPool(strings.NewReader(streamNumbers(100)), "/items", 8, false, slowSquare) {
+		if err != nil {
+			errs++
+			continue
+		}
+		seen[value] = true
+	}
+	if len(seen) != 99 || errs != 1 {
+		t.Fatal(len(seen), errs)
+	}
+}
+
+func TestStreamArrayPathAllocations(t *testing.T) {
+	allocs := func(n int) float64 {
+		doc := `{"rows": [` + strings.Repeat(`0,`, n) + `[1, 2]]}`
+		pointer := "/rows/" + strconv.Itoa(n)
+		return testing.AllocsPerRun(5, func() {
+			count := 0
+			for _, err := range StreamArray[int](strings.NewReader(doc), pointer) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				count++
+			}
+			if count != 2 {
+				t.Fatal(count)
+			}
+		})
+	}
+	// the items off the path cost nothing however many there are
+	if few, many := allocs(10), allocs(5000); many > few+10 {
+		t.Fatal(few, many)
+	}
+}
+
+func TestStreamArrayPoolParseErrorLast(t *testing.T) {
+	doc := streamNumbers(20)
+	doc = doc[:len(doc)-2] + `, "x"]}`
+	count := 0
+	var last error
+	for _, err := range StreamArrayPool(strings.NewReader(doc), "/items", 3, false, func(i int) (int, error) { return i, nil }) {
+		count++
+		last = err
+	}
+	if count != 21 || last == nil {
+		t.Fatal(count, last)
+	}
+}
+
+func TestStreamArrayPoolBreak(t *testing.T) {
+	count := 0
+	for range StreamArrayPool(strings.NewReader(streamNumbers(1000)), "/items", 4, true, slowSquare) {
+		count++
+		if count == 5 {
+			break
+		}
+	}
+	if count != 5 {
+		t.Fatal(count)
+	}
+}
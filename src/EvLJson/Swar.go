@@ -0,0 +1,154 @@
+package EvLJson
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// SWAR, simd within a register: the scanners below test eight input bytes
+// per step with plain uint64 arithmetic, so ParseBytes can move over runs
+// of string, whitespace and digit bytes without a state dispatch per byte
+
+const (
+	swarOnes  = 0x0101010101010101
+	swarHighs = 0x8080808080808080
+	swarLows  = 0x7f7f7f7f7f7f7f7f
+)
+
+// swarZeroBytes sets the high bit of exactly the bytes of x that are zero
+func swarZeroBytes(x uint64) uint64 {
+	return ^((x&swarLows + swarLows) | x | swarLows)
+}
+
+// swarEqual sets the high bit of the bytes of x equal to b
+func swarEqual(x uint64, b byte) uint64 {
+	return swarZeroBytes(x ^ swarOnes*uint64(b))
+}
+
+// swarLess sets the high bit of the bytes of x below b, for b <= 0x80
+func swarLess(x uint64, b byte) uint64 {
+	// bytes with the high bit set are never below b; for the others adding
+	// 0x80-b carries into the high bit exactly when the byte is >= b
+	return ^(x&swarLows + swarOnes*uint64(0x80-b)) &^ x & swarHighs
+}
+
+// swarFirst is the index of the first byte marked in mask
+func swarFirst(mask uint64) int {
+	return bits.TrailingZeros64(mask) / 8
+}
+
+// scanStringRun counts the bytes from i up to the next quote, backslash
+// or control byte
+func scanStringRun(data []byte, i int) int {
+	start := i
+	for ; i+8 <= len(data); i += 8 {
+		x := binary.LittleEndian.Uint64(data[i:])
+		if mask := swarEqual(x, '"') | swarEqual(x, '\\') | swarLess(x, 0x20); mask != 0 {
+			return i + swarFirst(mask) - start
+		}
+	}
+	for ; i < len(data); i++ {
+		if b := data[i]; b == '"' || b == '\\' || b < 0x20 {
+			break
+		}
+	}
+	return i - start
+}
+
+// scanWhitespaceRun counts the whitespace bytes from i
+func scanWhitespaceRun(data []byte, i int) int {
+	start := i
+	for ; i+8 <= len(data); i += 8 {
+		x := binary.LittleEndian.Uint64(data[i:])
+		space := swarEqual(x, ' ') | swarEqual(x, '\t') | swarEqual(x, '\n') | swarEqual(x, '\r')
+		if mask := ^space & swarHighs; mask != 0 {
+			return i + swarFirst(mask) - start
+		}
+	}
+	for ; i < len(data) && isCharWhitespace(data[i]); i++ {
+	}
+	return i - start
+}
+
+// scanDigitRun counts the ascii digits from i
+func scanDigitRun(data []byte, i int) int {
+	start := i
+	for ; i+8 <= len(data); i += 8 {
+		x := binary.LittleEndian.Uint64(data[i:])
+		// a digit has 3 for its high nibble and at most 9 for its low one
+		highOk := swarZeroBytes(x&(swarOnes*0xf0) ^ swarOnes*0x30)
+		lowOver := ((x&(swarOnes*0x0f) + swarOnes*0x06) & (swarOnes * 0x10)) << 3
+		if mask := ^(highOk &^ lowOver) & swarHighs; mask != 0 {
+			return i + swarFirst(mask) - start
+		}
+	}
+	for ; i < len(data) && data[i] >= '0' && data[i] <= '9'; i++ {
+	}
+	return i - start
+}
+
+// signalInputRun takes n more bytes of the current string or number from
+// the input, extending the aliased run or, once an escape has forced the
+// value to be copied, filling DataBuffer a buffer at a time
+//
+// Note: user can signal within this function
+func signalInputRun[H parseHandler](p *Parser, h H, n int) signal_t {
+	if n == 0 {
+		return SIG_NEXT_BYTE
+	}
+	data := p.input.data[p.input.pos : p.input.pos+n]
+	p.input.pos += n
+	if !p.wantsData {
+		return SIG_NEXT_BYTE
+	}
+	if p.aliasing {
+		p.aliasEnd = p.input.pos
+		return SIG_NEXT_BYTE
+	}
+	for len(data) != 0 {
+		size := len(p.DataBuffer)
+		if size == cap(p.DataBuffer) {
+			p.spills++
+			h.OnData(p, DATA_CONTINUES)
+			if p.userSignal == SIG_STOP {
+				return SIG_STOP
+			}
+			size = 0
+		}
+		copied := copy(p.DataBuffer[size:cap(p.DataBuffer)], data)
+		p.DataBuffer = p.DataBuffer[:size+copied]
+		data = data[copied:]
+	}
+	return SIG_NEXT_BYTE
+}
+
+// skipWhitespaceRun moves past whitespace following the current byte
+func skipWhitespaceRun(p *Parser) {
+	if p.input != nil {
+		p.input.pos += scanWhitespaceRun(p.input.data, p.input.pos)
+	}
+}
+
+// signalDataStringRun is signalDataNextByte for a byte inside a string,
+// taking the rest of its unescaped run along with it
+//
+// Note: user can signal within this function
+func signalDataStringRun[H parseHandler](p *Parser, h H, b byte) signal_t {
+	signal := signalDataNextByte(p, h, b)
+	if p.input == nil || signal != SIG_NEXT_BYTE {
+		return signal
+	}
+	return signalInputRun(p, h, scanStringRun(p.input.data, p.input.pos))
+}
+
+// signalDataDigitRun is signalDataNextByte for a digit, taking the digits
+// following it along
+//
+// Note: user can signal within this function
+func signalDataDigitRun[H parseHandler](p *Parser, h H, b byte) signal_t {
+	signal := signalDataNextByte(p, h, b)
+	if p.input == nil || signal != SIG_NEXT_BYTE {
+		return signal
+	}
+	return signalInputRun(p, h, scanDigitRun(p.input.data, p.input.pos))
+}
@@ -0,0 +1,123 @@
+package EvLJson
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func naiveRun(data []byte, i int, in func(byte) bool) int {
+	start := i
+	for ; i < len(data) && in(data[i]); i++ {
+	}
+	return i - start
+}
+
+func TestSwarScanners(t *testing.T) {
+	scanners := []struct {
+		name string
+		scan func([]byte, int) int
+		in   func(byte) bool
+		fill byte
+	}{
+		{"string", scanStringRun, func(b byte) bool { return b != '"' && b != '\\' && b >= 0x20 }, 'a'},
+		{"whitespace", scanWhitespaceRun, isCharWhitespace, ' '},
+		{"digit", scanDigitRun, func(b byte) bool { return b >= '0' && b <= '9' }, '7'},
+	}
+	data := make([]byte, 24)
+	for _, scanner := range scanners {
+		// every byte value at every position of a word, and past its end
+		for stop := 0; stop < 256; stop++ {
+			for at := 0; at < len(data); at++ {
+				for i := range data {
+					data[i] = scanner.fill
+				}
+				data[at] = byte(stop)
+				for start := 0; start < 3; start++ {
+					expected := naiveRun(data, start, scanner.in)
+					if n := scanner.scan(data, start); n != expected {
+						t.Fatalf("%s: byte %#x at %d from %d: %d != %d", scanner.name, stop, at, start, n, expected)
+					}
+				}
+			}
+		}
+	}
+}
+
+func swarStringCorpus() []byte {
+	var doc strings.Builder
+	doc.WriteString(`[`)
+	for i := 0; i < 2000; i++ {
+		doc.WriteString(`"a fairly long string value without escapes, as most strings are",`)
+		doc.WriteString(`"and one with an \"escape\" followed by a long unescaped tail of text",`)
+	}
+	doc.WriteString(`1234567890123456]`)
+	return []byte(doc.String())
+}
+
+func swarWhitespaceCorpus() []byte {
+	var doc strings.Builder
+	doc.WriteString("{\n")
+	for i := 0; i < 2000; i++ {
+		doc.WriteString("                \"key\"    :    [\n\t\t\t\t1234567,\r\n                    -98765.4321e+10\n                ],\n")
+	}
+	doc.WriteString("    \"end\": true\n}\n")
+	return []byte(doc.String())
+}
+
+func TestSwarMatchesParse(t *testing.T) {
+	for _, doc := range [][]byte{swarStringCorpus(), swarWhitespaceCorpus()} {
+		for _, dataBuffer := range [][]byte{nil, make([]byte, 0, TEST_DATA_BUFFER_SIZE)} {
+			expected, _, err := collectDataCalls(string(doc), dataBuffer, OPT_ALLOW_EXTRA_WHITESPACE, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			values, _, err := collectDataCalls(string(doc), dataBuffer, OPT_ALLOW_EXTRA_WHITESPACE, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(values, "\x00") != strings.Join(expected, "\x00") {
+				t.Fatal("ParseBytes data differs from Parse")
+			}
+		}
+	}
+	// runs must not carry the parser past invalid bytes
+	for _, doc := range []string{`["abc` + "\x00" + `"]`, `[12345678a]`, `[1.23456789x]`, `[       ,1]`} {
+		if err := parseStringAllowWhitespace(doc); err == nil {
+			continue
+		}
+		evLJsonParser := NewParser(nil, nil, OPT_ALLOW_EXTRA_WHITESPACE)
+		if err := evLJsonParser.ParseBytes([]byte(doc), nil, func(*Parser, bool) {}); err == nil {
+			t.Fatalf("%q accepted by ParseBytes only", doc)
+		}
+	}
+}
+
+func benchmarkCorpus(b *testing.B, doc []byte, zeroCopy bool) {
+	dataBuffer := make([]byte, TEST_DATA_BUFFER_SIZE)
+	evLJsonParser := NewParser(dataBuffer, nil, OPT_ALLOW_EXTRA_WHITESPACE)
+	onData := func(parser *Parser, endOfData bool) {}
+	b.SetBytes(int64(len(doc)))
+	for i := 0; i < b.N; i++ {
+		var err error
+		if zeroCopy {
+			err = evLJsonParser.ParseBytes(doc, nil, onData)
+		} else {
+			err = evLJsonParser.Parse(bytes.NewReader(doc), nil, onData)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		evLJsonParser.Reset()
+	}
+}
+
+func BenchmarkCorpusParse(b *testing.B)      { benchmarkCorpus(b, BENCHMARK_BYTES, false) }
+func BenchmarkCorpusParseBytes(b *testing.B) { benchmarkCorpus(b, BENCHMARK_BYTES, true) }
+
+func BenchmarkStringsParse(b *testing.B)      { benchmarkCorpus(b, swarStringCorpus(), false) }
+func BenchmarkStringsParseBytes(b *testing.B) { benchmarkCorpus(b, swarStringCorpus(), true) }
+
+func BenchmarkWhitespaceParse(b *testing.B)      { benchmarkCorpus(b, swarWhitespaceCorpus(), false) }
+func BenchmarkWhitespaceParseBytes(b *testing.B) { benchmarkCorpus(b, swarWhitespaceCorpus(), true) }
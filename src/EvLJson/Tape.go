@@ -0,0 +1,316 @@
+package EvLJson
+
+import (
+	"bytes"
+	"io"
+	"iter"
+	"strconv"
+)
+
+const ( // tapeKind_t
+	TAPE_NULL = iota
+	TAPE_TRUE
+	TAPE_FALSE
+	TAPE_NUMBER
+	TAPE_STRING
+	TAPE_KEY
+	TAPE_ARRAY
+	TAPE_OBJECT
+	TAPE_INVALID // the Kind of a cursor that is not Valid
+)
+
+type tapeKind_t uint8
+
+// TapeEntry is one value, or one object key, of a document in order
+//
+// Strings, keys and numbers point at their text with Start and End, in the
+// input unless InArena is set because escapes had to be decoded; containers
+// record where they end with Next, the index of the entry after their last
+// descendant, so siblings are skipped in one step
+type TapeEntry struct {
+	Kind    tapeKind_t
+	InArena bool
+	Start   int
+	End     int
+	Next    int // containers only
+	Count   int // items or members of containers
+}
+
+// Tape is the flat record of a parsed document; it is made to be read many
+// times and to be parsed into again without allocating once it has grown
+// to fit the documents it sees
+type Tape struct {
+	Entries []TapeEntry
+	data    []byte
+	arena   []byte
+	stack   []int // entries of the open containers
+	reader  bytes.Reader
+	counter countingByteReader
+	parser  Parser
+
+	token      writerToken_t
+	textStart  int // input offset of the string being recorded
+	textLength int // bytes of the number being recorded
+}
+
+// ParseToTape records data on a new tape
+func ParseToTape(data []byte) (*Tape, error) {
+	tape := &Tape{}
+	if err := tape.Parse(data); err != nil {
+		return nil, err
+	}
+	return tape, nil
+}
+
+func (t *Tape) add(entry TapeEntry) {
+	if entry.Kind != TAPE_KEY && len(t.stack) != 0 {
+		t.Entries[t.stack[len(t.stack)-1]].Count++
+	}
+	t.Entries = append(t.Entries, entry)
+}
+
+func tapeOnEvent(p *Parser, evt event_t) {
+	t := p.UserData.(*Tape)
+	switch evt {
+	case EVT_NULL:
+		t.add(TapeEntry{Kind: TAPE_NULL})
+	case EVT_TRUE:
+		t.add(TapeEntry{Kind: TAPE_TRUE})
+	case EVT_FALSE:
+		t.add(TapeEntry{Kind: TAPE_FALSE})
+	case EVT_ARRAY, EVT_DICT:
+		kind := tapeKind_t(TAPE_ARRAY)
+		if evt == EVT_DICT {
+			kind = TAPE_OBJECT
+		}
+		t.add(TapeEntry{Kind: kind})
+		t.stack = append(t.stack, len(t.Entries)-1)
+	case EVT_STRING:
+		kind := tapeKind_t(TAPE_STRING)
+		if p.IsDictKey() {
+			kind = TAPE_KEY
+		}
+		// text goes to the arena until the closing quote shows whether it
+		// can be read from the input instead
+		t.token = WRITER_TOKEN_STRING
+		t.textStart = int(t.counter.offset()) + 1
+		t.add(TapeEntry{Kind: kind, InArena: true, Start: len(t.arena)})
+	case EVT_NUMBER:
+		t.token = WRITER_TOKEN_NUMBER
+		t.add(TapeEntry{Kind: TAPE_NUMBER, Start: int(t.counter.offset())})
+	case EVT_LEAVE:
+		entry := &t.Entries[len(t.Entries)-1]
+		switch t.token {
+		case WRITER_TOKEN_NUMBER:
+			// numbers are delivered verbatim
+			entry.End = entry.Start + t.textLength
+		case WRITER_TOKEN_STRING:
+			textEnd := int(t.counter.offset())
+			if textEnd-t.textStart == len(t.arena)-entry.Start {
+				// no escapes, the decoded text is the input
+				t.arena = t.arena[:entry.Start]
+				entry.InArena, entry.Start, entry.End = false, t.textStart, textEnd
+			} else {
+				entry.End = len(t.arena)
+			}
+		default:
+			last := len(t.stack) - 1
+			t.Entries[t.stack[last]].Next = len(t.Entries)
+			t.stack = t.stack[:last]
+		}
+		t.token = WRITER_TOKEN_NONE
+		t.textLength = 0
+	}
+}
+
+func tapeOnData(p *Parser, endOfData bool) {
+	t := p.UserData.(*Tape)
+	if t.token == WRITER_TOKEN_NUMBER {
+		t.textLength += len(p.DataBuffer)
+		return
+	}
+	t.arena = append(t.arena, p.DataBuffer...)
+}
+
+// Parse records data on the tape, replacing what was there; data must not
+// change while the tape is in use
+func (t *Tape) Parse(data []byte) error {
+	t.Entries, t.arena, t.stack, t.data = t.Entries[:0], t.arena[:0], t.stack[:0], data
+	t.token, t.textLength = WRITER_TOKEN_NONE, 0
+	t.reader.Reset(data)
+	t.counter = countingByteReader{reader: &t.reader}
+	if t.parser.DataBuffer == nil {
+		t.parser = NewParser(make([]byte, 0, DOCUMENT_DATA_BUFFER_SIZE), nil, DOCUMENT_PARSER_OPTIONS)
+	} else {
+		t.parser.Reset()
+	}
+	t.parser.UserData = t
+	err := t.parser.Parse(&t.counter, tapeOnEvent, tapeOnData)
+	t.parser.UserData = nil
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		t.Entries = t.Entries[:0]
+	}
+	return err
+}
+
+// Root is a cursor on the document's top level container
+func (t *Tape) Root() TapeCursor {
+	return TapeCursor{t, 0}
+}
+
+// TapeCursor is a position on a tape; the zero cursor is not valid and is
+// what lookups return when nothing is found, as is the root of a tape
+// without a document; an invalid cursor reads as TAPE_INVALID and zero
+// values, so lookups chain safely
+type TapeCursor struct {
+	tape  *Tape
+	index int
+}
+
+func (c TapeCursor) Valid() bool {
+	return c.tape != nil && c.index < len(c.tape.Entries)
+}
+
+func (c TapeCursor) entry() *TapeEntry {
+	return &c.tape.Entries[c.index]
+}
+
+func (c TapeCursor) Kind() tapeKind_t {
+	if !c.Valid() {
+		return TAPE_INVALID
+	}
+	return c.entry().Kind
+}
+
+// next is the index after the value, skipping any descendants
+func (c TapeCursor) next() int {
+	entry := c.entry()
+	if entry.Kind == TAPE_ARRAY || entry.Kind == TAPE_OBJECT {
+		return entry.Next
+	}
+	return c.index + 1
+}
+
+// Bytes is the text of a string, key or number; it aliases the input or
+// the tape and is only valid until the next Parse
+func (c TapeCursor) Bytes() []byte {
+	if !c.Valid() {
+		return nil
+	}
+	entry := c.entry()
+	if entry.InArena {
+		return c.tape.arena[entry.Start:entry.End]
+	}
+	return c.tape.data[entry.Start:entry.End]
+}
+
+func (c TapeCursor) Text() string {
+	return string(c.Bytes())
+}
+
+func (c TapeCursor) Bool() bool {
+	return c.Kind() == TAPE_TRUE
+}
+
+func (c TapeCursor) Float64() (float64, error) {
+	return strconv.ParseFloat(string(c.Bytes()), 64)
+}
+
+func (c TapeCursor) Int64() (int64, error) {
+	return strconv.ParseInt(string(c.Bytes()), 10, 64)
+}
+
+// Len is the number of items or members of a container
+func (c TapeCursor) Len() int {
+	if !c.Valid() {
+		return 0
+	}
+	return c.entry().Count
+}
+
+// Items yields the items of an array
+func (c TapeCursor) Items() iter.Seq2[int, TapeCursor] {
+	return func(yield func(int, TapeCursor) bool) {
+		if c.Kind() != TAPE_ARRAY {
+			return
+		}
+		end := c.entry().Next
+		for i, item := 0, (TapeCursor{c.tape, c.index + 1}); item.index < end; i++ {
+			if !yield(i, item) {
+				return
+			}
+			item.index = item.next()
+		}
+	}
+}
+
+// Members yields the keys and values of an object
+func (c TapeCursor) Members() iter.Seq2[[]byte, TapeCursor] {
+	return func(yield func([]byte, TapeCursor) bool) {
+		if c.Kind() != TAPE_OBJECT {
+			return
+		}
+		end := c.entry().Next
+		for key := (TapeCursor{c.tape, c.index + 1}); key.index < end; {
+			value := TapeCursor{c.tape, key.index + 1}
+			if !yield(key.Bytes(), value) {
+				return
+			}
+			key.index = value.next()
+		}
+	}
+}
+
+// Get finds the value of the member named key, the last one if the key is
+// repeated
+func (c TapeCursor) Get(key string) TapeCursor {
+	found := TapeCursor{}
+	for name, value := range c.Members() {
+		if string(name) == key {
+			found = value
+		}
+	}
+	return found
+}
+
+// Index finds the item at index
+func (c TapeCursor) Index(index int) TapeCursor {
+	if index < 0 || c.Kind() != TAPE_ARRAY || index >= c.Len() {
+		return TapeCursor{}
+	}
+	for i, item := range c.Items() {
+		if i == index {
+			return item
+		}
+	}
+	return TapeCursor{}
+}
+
+// Pointer finds the value at the json pointer below the cursor
+func (c TapeCursor) Pointer(pointer string) (TapeCursor, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return TapeCursor{}, err
+	}
+	for _, token := range tokens {
+		switch c.Kind() {
+		case TAPE_OBJECT:
+			c = c.Get(token)
+		case TAPE_ARRAY:
+			index, ok := pointerIndex(token)
+			if !ok {
+				return TapeCursor{}, PointerNotFoundError{pointer}
+			}
+			c = c.Index(index)
+		default:
+			c = TapeCursor{}
+		}
+		if !c.Valid() {
+			return TapeCursor{}, PointerNotFoundError{pointer}
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,126 @@
+package EvLJson
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const TEST_TAPE_DOC = ` {"id": -12.5e3, "name": "plain", "esc\"aped": "tab\there é",` +
+	` "list": [1, [true, false], {"k": null}, "x"], "empty": {}, "last": 0}`
+
+func TestTapeEntries(t *testing.T) {
+	tape, err := ParseToTape([]byte(TEST_TAPE_DOC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tape.Root()
+	if root.Kind() != TAPE_OBJECT || root.Len() != 6 || tape.Entries[0].Next != len(tape.Entries) {
+		t.Fatalf("%+v", tape.Entries[0])
+	}
+	var keys []string
+	for key := range root.Members() {
+		keys = append(keys, string(key))
+	}
+	if strings.Join(keys, ",") != `id,name,esc"aped,list,empty,last` {
+		t.Fatalf("%q", keys)
+	}
+	for pointer, expected := range map[string]string{
+		"/id":        "-12.5e3",
+		"/name":      "plain",
+		"/esc\"aped": "tab\there é",
+		"/list/0":    "1",
+		"/list/3":    "x",
+		"/last":      "0",
+	} {
+		found, err := root.Pointer(pointer)
+		if err != nil || found.Text() != expected {
+			t.Fatalf("%q: %v", pointer, err)
+		}
+	}
+	// unescaped text is read straight from the input
+	if name := root.Get("name"); tape.Entries[name.index].InArena {
+		t.Fatal("plain string copied to the arena")
+	}
+	if value := root.Get("esc\"aped"); !tape.Entries[value.index].InArena {
+		t.Fatal("escaped string read from the input")
+	}
+	if f, err := root.Get("id").Float64(); err != nil || f != -12500 {
+		t.Fatal(f, err)
+	}
+	inner, _ := root.Pointer("/list/1")
+	if inner.Len() != 2 || !inner.Index(0).Bool() || inner.Index(1).Bool() || inner.Index(2).Valid() {
+		t.Fatalf("%+v", tape.Entries[inner.index])
+	}
+	if k, _ := root.Pointer("/list/2/k"); k.Kind() != TAPE_NULL {
+		t.Fatal(k.Kind())
+	}
+	if empty := root.Get("empty"); empty.Len() != 0 || empty.Get("k").Valid() {
+		t.Fatal("empty object has members")
+	}
+	// missed lookups chain to zero values
+	for _, missing := range []TapeCursor{root.Get("missing"), root.Get("missing").Get("x").Index(0), {}} {
+		if missing.Valid() || missing.Kind() != TAPE_INVALID || missing.Len() != 0 || missing.Bytes() != nil || missing.Bool() {
+			t.Fatal("invalid cursor")
+		}
+		for range missing.Items() {
+			t.Fatal("invalid cursor has items")
+		}
+		if _, err := missing.Int64(); err == nil {
+			t.Fatal("invalid cursor is a number")
+		}
+	}
+	for _, pointer := range []string{"/missing", "/list/4", "/list/x", "/id/0"} {
+		if _, err := root.Pointer(pointer); !errors.As(err, &PointerNotFoundError{}) {
+			t.Fatalf("%q: %v", pointer, err)
+		}
+	}
+}
+
+func TestTapeReuse(t *testing.T) {
+	tape := &Tape{}
+	if err := tape.Parse([]byte(TEST_TAPE_DOC)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tape.Parse([]byte(`[1, "a\nb"`)); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	if root := tape.Root(); root.Valid() || root.Kind() != TAPE_INVALID || root.Len() != 0 {
+		t.Fatal("root of a failed parse")
+	}
+	if err := tape.Parse([]byte(`["a\nb", 2]`)); err != nil {
+		t.Fatal(err)
+	}
+	if tape.Root().Index(0).Text() != "a\nb" || tape.Root().Index(1).Text() != "2" {
+		t.Fatalf("%+v", tape.Entries)
+	}
+	data := []byte(TEST_TAPE_DOC)
+	allocs := testing.AllocsPerRun(20, func() {
+		if err := tape.Parse(data); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("%v allocations reparsing into a tape", allocs)
+	}
+}
+
+func BenchmarkTapeNavigate(b *testing.B) {
+	var doc strings.Builder
+	doc.WriteString(`{"items": [`)
+	for i := 0; i < 1000; i++ {
+		doc.WriteString(`{"id": 1, "tags": ["a", "b", "c"], "nested": {"deep": [1, 2, 3]}}, `)
+	}
+	doc.WriteString(`{"id": 2}], "wanted": true}`)
+	tape, err := ParseToTape([]byte(doc.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !tape.Root().Get("wanted").Bool() {
+			b.Fatal("wanted not found")
+		}
+	}
+}
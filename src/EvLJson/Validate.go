@@ -0,0 +1,161 @@
+package EvLJson
+
+import (
+	"io"
+)
+
+// VALIDATE_BUFFER_SIZE is how much Validate reads from its reader at once
+const VALIDATE_BUFFER_SIZE = 4096
+
+// VALIDATE_OPTIONS are the options the input of Validate and Valid is
+// checked with: one document, surrounded by any whitespace, up to EOF
+const VALIDATE_OPTIONS = OPT_ALLOW_EXTRA_WHITESPACE | OPT_PARSE_UNTIL_EOF
+
+// validator walks the parser tables over a window of the input; the
+// window is the whole input for Valid and is refilled from reader for
+// Validate, which has to read up to EOF anyway
+type validator struct {
+	data   []byte
+	pos    int
+	reader io.ByteReader
+	buffer []byte
+	err    error
+}
+
+// refill replaces the window with the next bytes of the reader, false at
+// the end of the input or on an error, left in err
+func (v *validator) refill() bool {
+	if v.reader == nil || v.err != nil {
+		return false
+	}
+	if v.buffer == nil {
+		v.buffer = make([]byte, VALIDATE_BUFFER_SIZE)
+	}
+	n := 0
+	if reader, ok := v.reader.(io.Reader); ok {
+		for retries := 0; n == 0 && v.err == nil && retries < 100; retries++ {
+			n, v.err = reader.Read(v.buffer)
+		}
+		if n == 0 && v.err == nil {
+			v.err = io.ErrNoProgress
+		}
+	} else {
+		for n < len(v.buffer) {
+			var b byte
+			if b, v.err = v.reader.ReadByte(); v.err != nil {
+				break
+			}
+			v.buffer[n] = b
+			n++
+		}
+	}
+	v.data, v.pos = v.buffer[:n], 0
+	return n != 0
+}
+
+// validate runs the transitions of table over the input with nothing but
+// a stack of states, so it accepts exactly what Parser accepts with the
+// options of table
+func (v *validator) validate(table *parserTable_t) error {
+	handle := handle_t(HANDLE_START)
+	var stackArray [64]handle_t
+	stack := stackArray[:0]
+	literal := ""
+	hexDigits := 0
+	for {
+		if v.pos == len(v.data) && !v.refill() {
+			break
+		}
+		b := v.data[v.pos]
+		v.pos++
+	REUSE_BYTE:
+		if handle < HANDLE_TABLE_STATES {
+			transition := table[handle][byteClasses[b]&(parserTableRow-1)]
+			switch transition.action() {
+			case ACT_WHITESPACE:
+				v.pos += scanWhitespaceRun(v.data, v.pos)
+			case ACT_DIGITS:
+				v.pos += scanDigitRun(v.data, v.pos)
+			case ACT_STRING_DATA:
+				v.pos += scanStringRun(v.data, v.pos)
+			case ACT_SKIP, ACT_DATA, ACT_DATA_EVENT, ACT_ESCAPE:
+				handle = transition.next()
+			case ACT_END_NUMBER:
+				handle, stack = stack[len(stack)-1], stack[:len(stack)-1]
+				goto REUSE_BYTE
+			case ACT_CLOSE:
+				handle, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			case ACT_PUSH, ACT_PUSH_NUMBER:
+				stack = append(stack, transition.next())
+				handle = transition.push()
+			case ACT_PUSH_LITERAL:
+				stack = append(stack, transition.next())
+				handle = transition.push()
+				switch handle {
+				case HANDLE_NULL:
+					literal = VALUE_STR_NULL[1:]
+				case HANDLE_TRUE:
+					literal = VALUE_STR_TRUE[1:]
+				default:
+					literal = VALUE_STR_FALSE[1:]
+				}
+			case ACT_STOP:
+				return nil
+			case ACT_STRICT_EXPONENT:
+				return invalidStricterExponentFormat
+			default:
+				return unspecifiedParserError
+			}
+			continue
+		}
+		switch handle {
+		case HANDLE_STRING_RSP:
+			switch b {
+			case 'b', 'f', 'n', 'r', 't', '/', '\\', '"':
+				handle = HANDLE_STRING
+			case 'u':
+				handle, hexDigits = HANDLE_HEX_NR, 0
+			default:
+				return unspecifiedParserError
+			}
+		case HANDLE_HEX_NR:
+			if _, ok := hexCharValue(b); !ok {
+				return unspecifiedParserError
+			}
+			if hexDigits++; hexDigits == 4 {
+				handle = HANDLE_STRING
+			}
+		case HANDLE_NULL, HANDLE_TRUE, HANDLE_FALSE:
+			if b != literal[0] {
+				return unspecifiedParserError
+			}
+			if literal = literal[1:]; literal == "" {
+				handle, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		default:
+			return unspecifiedParserError
+		}
+	}
+	if v.err != nil && v.err != io.EOF {
+		return v.err
+	}
+	if len(stack) != 0 || handle == HANDLE_START {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+// Validate checks that reader holds a single json document, with nothing
+// but whitespace around it, reporting none of it; a truncated document is
+// io.ErrUnexpectedEOF. The reader is read ahead in chunks when it is also
+// an io.Reader
+func Validate(reader io.ByteReader) error {
+	v := validator{reader: reader}
+	return v.validate(parserTables[VALIDATE_OPTIONS&parserOverlayOptions])
+}
+
+// Valid reports whether data is a single json document, see Validate
+func Valid(data []byte) bool {
+	v := validator{data: data}
+	return v.validate(parserTables[VALIDATE_OPTIONS&parserOverlayOptions]) == nil
+}
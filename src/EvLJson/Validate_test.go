@@ -0,0 +1,98 @@
+package EvLJson
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+const TEST_VALIDATE_DOC = `{"a": [1, -0.5e+10, 0E-001, true, false, null], "s": "x\"\\\/\b\f\n\r\t\u00e9\uD83D\uDE00", "": {}, "e": []}`
+
+// byteOnlyReader hides every method of its reader but ReadByte
+type byteOnlyReader struct {
+	reader io.ByteReader
+}
+
+func (r byteOnlyReader) ReadByte() (byte, error) {
+	return r.reader.ReadByte()
+}
+
+func validateCases() []string {
+	cases := append(whitespaceTestCases(), TEST_VALIDATE_DOC, "", " ", "[1] [2]", "[1]x", "[1e00]", "[1,]", "[\"\\x\"]", "[\"\\u12g4\"]", "[nul]", "[nulll]", "[tru]", "[fals]")
+	for i := 0; i <= len(TEST_VALIDATE_DOC); i++ {
+		cases = append(cases, TEST_VALIDATE_DOC[:i])
+	}
+	return cases
+}
+
+func TestValidMatchesParse(t *testing.T) {
+	for _, doc := range append(validateCases(), string(BENCHMARK_BYTES), string(swarStringCorpus()), string(swarWhitespaceCorpus())) {
+		evLJsonParser := NewParser(nil, nil, VALIDATE_OPTIONS)
+		expected := evLJsonParser.Parse(bytes.NewReader([]byte(doc)), nil, nil) == nil
+		if Valid([]byte(doc)) != expected {
+			t.Fatalf("%q: Valid is %v", doc, !expected)
+		}
+		readers := []io.ByteReader{
+			bytes.NewReader([]byte(doc)),
+			byteOnlyReader{bytes.NewReader([]byte(doc))},
+			readerByteReader{iotest.OneByteReader(bytes.NewReader([]byte(doc)))},
+		}
+		for _, reader := range readers {
+			if err := Validate(reader); (err == nil) != expected {
+				t.Fatalf("%q: %T: %v", doc, reader, err)
+			}
+		}
+	}
+}
+
+// readerByteReader gives an io.Reader the ReadByte Validate needs, for
+// readers returning a byte per Read
+type readerByteReader struct {
+	io.Reader
+}
+
+func (r readerByteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r.Reader, b[:])
+	return b[0], err
+}
+
+func TestValidateErrors(t *testing.T) {
+	if err := Validate(bytes.NewReader([]byte(`{"a": [1`))); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	if err := Validate(bytes.NewReader(nil)); err != io.ErrUnexpectedEOF {
+		t.Fatal(err)
+	}
+	if err := Validate(bytes.NewReader([]byte(`[1] x`))); err != unspecifiedParserError {
+		t.Fatal(err)
+	}
+	if err := Validate(readerByteReader{iotest.TimeoutReader(bytes.NewReader([]byte(`[1, 2]`)))}); err != iotest.ErrTimeout {
+		t.Fatal(err)
+	}
+	deep := bytes.Repeat([]byte("["), 1000)
+	if !Valid(append(deep, bytes.Repeat([]byte("]"), 1000)...)) || Valid(append(deep, bytes.Repeat([]byte("]"), 999)...)) {
+		t.Fatal("deep nesting")
+	}
+}
+
+func BenchmarkCorpusValid(b *testing.B) {
+	b.SetBytes(int64(len(BENCHMARK_BYTES)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !Valid(BENCHMARK_BYTES) {
+			b.Fatal("invalid")
+		}
+	}
+}
+
+func BenchmarkCorpusValidate(b *testing.B) {
+	b.SetBytes(int64(len(BENCHMARK_BYTES)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := Validate(bytes.NewReader(BENCHMARK_BYTES)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
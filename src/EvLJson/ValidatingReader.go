@@ -0,0 +1,138 @@
+package EvLJson
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"strconv"
+)
+
+// ValidationError is the json read through a ValidatingReader going wrong
+// at Offset, counted from the first byte read; a document cut short is
+// io.ErrUnexpectedEOF at the end of the input
+type ValidationError struct {
+	Offset int64
+	Err    error
+}
+
+func (err ValidationError) Error() string {
+	return "Invalid json at offset " + strconv.FormatInt(err.Offset, 10) + ": " + err.Err.Error()
+}
+
+func (err ValidationError) Unwrap() error {
+	return err.Err
+}
+
+// errValidatingReaderClosed is what a ValidatingReader reads after Close
+var errValidatingReaderClosed = errors.New("ValidatingReader closed")
+
+// validatingInput hands the parser the bytes of each Read, suspending the
+// parse until the next one once they are used up
+type validatingInput struct {
+	data   []byte
+	pos    int
+	offset int64 // of data[0]
+	eof    bool
+	yield  func(struct{}) bool
+}
+
+func (in *validatingInput) ReadByte() (byte, error) {
+	for in.pos == len(in.data) {
+		if in.eof {
+			return 0, io.EOF
+		}
+		if !in.yield(struct{}{}) {
+			return 0, errValidatingReaderClosed
+		}
+	}
+	b := in.data[in.pos]
+	in.pos++
+	return b, nil
+}
+
+// ValidatingReader passes what it reads from its reader through unchanged
+// while parsing it as one json document, so a proxy can validate a body as
+// it streams it; see NewValidatingReader
+type ValidatingReader struct {
+	reader  io.Reader
+	parser  Parser
+	onEvent eventReceiver_t
+	onData  dataReceiver_t
+	input   validatingInput
+	next    func() (struct{}, bool)
+	stop    func()
+	valid   int  // bytes of the last Read before the error, if any
+	stopped bool // by a callback, the rest is passed through unchecked
+	err     error
+}
+
+// NewValidatingReader parses everything read from reader with a parser of
+// options and OPT_PARSE_UNTIL_EOF, calling onEvent and onData, either of
+// which may be nil, from within Read
+//
+// The Read that hits invalid json returns the bytes before the offending
+// one with a ValidationError, as does every Read after it, and a stream
+// ending before the document does is an error rather than io.EOF. The
+// parse is suspended between Reads; Close releases it when the stream is
+// abandoned before its end
+func NewValidatingReader(reader io.Reader, options uint8, onEvent eventReceiver_t, onData dataReceiver_t) *ValidatingReader {
+	r := &ValidatingReader{reader: reader, onEvent: onEvent, onData: onData}
+	r.parser = NewParser(make([]byte, 0, DOCUMENT_DATA_BUFFER_SIZE), nil, options|OPT_PARSE_UNTIL_EOF)
+	r.next, r.stop = iter.Pull(r.parse)
+	return r
+}
+
+func (r *ValidatingReader) parse(yield func(struct{}) bool) {
+	r.input.yield = yield
+	err := r.parser.Parse(&r.input, r.onEvent, r.onData)
+	r.input.yield = nil
+	offset := r.input.offset + int64(r.input.pos)
+	switch {
+	case err == nil && r.parser.userSignal == SIG_STOP:
+		r.stopped = true
+	case err == nil:
+		r.err = io.EOF
+	case err == errValidatingReaderClosed:
+		r.err = err
+	case err == io.EOF:
+		r.valid, r.err = r.input.pos, ValidationError{offset, io.ErrUnexpectedEOF}
+	default:
+		r.valid, r.err = r.input.pos-1, ValidationError{offset - 1, err}
+	}
+}
+
+// Parser is the parser the input is fed to, as handed to the callbacks
+func (r *ValidatingReader) Parser() *Parser {
+	return &r.parser
+}
+
+func (r *ValidatingReader) Read(b []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.reader.Read(b)
+	if r.stopped || n == 0 && err != io.EOF {
+		return n, err
+	}
+	r.input.offset += int64(r.input.pos)
+	r.input.data, r.input.pos, r.input.eof = b[:n], 0, err == io.EOF
+	r.next()
+	switch {
+	case r.err == nil, r.err == io.EOF:
+		return n, err
+	default:
+		return r.valid, r.err
+	}
+}
+
+// Close ends the parse and closes the reader if it is an io.Closer
+func (r *ValidatingReader) Close() error {
+	r.stop()
+	if r.err == nil {
+		r.err = errValidatingReaderClosed
+	}
+	if closer, ok := r.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package EvLJson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// validatingReaders are the ways the input may come in
+func validatingReaders(doc string) []io.Reader {
+	return []io.Reader{
+		strings.NewReader(doc),
+		iotest.OneByteReader(strings.NewReader(doc)),
+		iotest.HalfReader(strings.NewReader(doc)),
+		iotest.DataErrReader(strings.NewReader(doc)),
+	}
+}
+
+// validatingExpected is the offset a ValidationError of doc is expected
+// at, -1 for a valid doc
+func validatingExpected(doc string) int64 {
+	p := NewParser(nil, nil, VALIDATE_OPTIONS|OPT_TRACK_POSITIONS)
+	switch err := p.Parse(bytes.NewReader([]byte(doc)), nil, nil); err {
+	case nil:
+		return -1
+	case io.EOF:
+		return int64(len(doc))
+	default:
+		return p.Position().Offset
+	}
+}
+
+func TestValidatingReader(t *testing.T) {
+	for _, doc := range append(validateCases(), string(BENCHMARK_BYTES)) {
+		expected := validatingExpected(doc)
+		for i, reader := range validatingReaders(doc) {
+			var out bytes.Buffer
+			n, err := io.Copy(&out, NewValidatingReader(reader, VALIDATE_OPTIONS, nil, nil))
+			var validationErr ValidationError
+			valid := expected
+			switch {
+			case expected < 0 && err != nil:
+				t.Fatalf("%q reader %d: %v", doc, i, err)
+			case expected < 0:
+				valid = int64(len(doc))
+			case !errors.As(err, &validationErr) || validationErr.Offset != expected:
+				t.Fatalf("%q reader %d: %v, expected offset %d", doc, i, err, expected)
+			case expected == int64(len(doc)) && !errors.Is(err, io.ErrUnexpectedEOF):
+				t.Fatalf("%q reader %d: %v", doc, i, err)
+			}
+			// everything before the error is passed through
+			if n != valid || out.String() != doc[:valid] {
+				t.Fatalf("%q reader %d: %d %q", doc, i, n, out.String())
+			}
+		}
+	}
+}
+
+func TestValidatingReaderCallbacks(t *testing.T) {
+	var expected, out bytes.Buffer
+	onEvent, onData := recordingCallbacks(&expected)
+	p := NewParser(make([]byte, 0, DOCUMENT_DATA_BUFFER_SIZE), nil, VALIDATE_OPTIONS)
+	if err := p.Parse(bytes.NewReader(BENCHMARK_BYTES), onEvent, onData); err != nil {
+		t.Fatal(err)
+	}
+	onEvent, onData = recordingCallbacks(&out)
+	reader := NewValidatingReader(iotest.HalfReader(bytes.NewReader(BENCHMARK_BYTES)), VALIDATE_OPTIONS, onEvent, onData)
+	if _, err := io.Copy(io.Discard, reader); err != nil || out.String() != expected.String() {
+		t.Fatal(err)
+	}
+
+	// a stop passes the rest through unchecked
+	doc := `{"a": 1} trailing`
+	events := 0
+	reader = NewValidatingReader(iotest.OneByteReader(strings.NewReader(doc)), VALIDATE_OPTIONS, func(p *Parser, evt event_t) {
+		if events++; evt == EVT_DICT {
+			p.ParseStop()
+		}
+	}, nil)
+	if copied, err := io.ReadAll(reader); err != nil || string(copied) != doc || events != 2 {
+		t.Fatal(err, string(copied), events)
+	}
+}
+
+// failOnceReader fails its first read after fail bytes, without reading
+type failOnceReader struct {
+	reader io.Reader
+	fail   int
+	err    error
+}
+
+func (r *failOnceReader) Read(b []byte) (int, error) {
+	if r.fail == 0 && r.err != nil {
+		err := r.err
+		r.err = nil
+		return 0, err
+	}
+	if r.err != nil && len(b) > r.fail {
+		b = b[:r.fail]
+	}
+	n, err := r.reader.Read(b)
+	r.fail -= n
+	return n, err
+}
+
+func TestValidatingReaderReadError(t *testing.T) {
+	doc := TEST_VALIDATE_DOC
+	failure := errors.New("read failure")
+	reader := NewValidatingReader(&failOnceReader{strings.NewReader(doc), 10, failure}, VALIDATE_OPTIONS, nil, nil)
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, reader); err != failure || out.String() != doc[:10] {
+		t.Fatal(err, out.String())
+	}
+	// the parse resumes where it was
+	if _, err := io.Copy(&out, reader); err != nil || out.String() != doc {
+		t.Fatal(err, out.String())
+	}
+	if n, err := reader.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+		t.Fatal(n, err)
+	}
+}
+
+func TestValidatingReaderClose(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		reader := NewValidatingReader(iotest.OneByteReader(strings.NewReader(TEST_VALIDATE_DOC)), VALIDATE_OPTIONS, nil, nil)
+		if _, err := reader.Read(make([]byte, 10)); err != nil {
+			t.Fatal(err)
+		}
+		if err := reader.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := reader.Read(make([]byte, 10)); n != 0 || err != errValidatingReaderClosed {
+			t.Fatal(n, err)
+		}
+	}
+	for i := 0; runtime.NumGoroutine() > goroutines; i++ {
+		if i == 100 {
+			t.Fatalf("%d goroutines left", runtime.NumGoroutine()-goroutines)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func BenchmarkValidatingReader(b *testing.B) {
+	b.SetBytes(int64(len(BENCHMARK_BYTES)))
+	for i := 0; i < b.N; i++ {
+		if _, err := io.Copy(io.Discard, NewValidatingReader(bytes.NewReader(BENCHMARK_BYTES), VALIDATE_OPTIONS, nil, nil)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package EvLJson
+
+import (
+	"bufio"
+	"io"
+)
+
+const ( // ansi colour escapes used when Writer.Color is set
+	COLOR_RESET  = "\x1b[0m"
+	COLOR_KEY    = "\x1b[34;1m"
+	COLOR_STRING = "\x1b[32m"
+	COLOR_NUMBER = "\x1b[36m"
+	COLOR_BOOL   = "\x1b[33m"
+	COLOR_NULL   = "\x1b[90m"
+)
+
+const ( // writerFrame_t
+	WRITER_FRAME_ARRAY = iota
+	WRITER_FRAME_DICT
+)
+
+const ( // writerToken_t
+	WRITER_TOKEN_NONE = iota
+	WRITER_TOKEN_KEY
+	WRITER_TOKEN_STRING
+	WRITER_TOKEN_NUMBER
+)
+
+const hexDigits = "0123456789abcdef"
+
+type writerFrame_t uint8
+type writerToken_t uint8
+
+type writerFrame struct {
+	kind  writerFrame_t
+	count int
+}
+
+type pendingItem struct {
+	start int
+	color string
+}
+
+// Writer emits json one structural event at a time, mirroring the events
+// the Parser produces, so documents of any size can be re-serialised while
+// only holding the current nesting depth in memory
+//
+// Strings and numbers are streamed as Begin/Data/End triples so chunks
+// straight out of Parser.DataBuffer can be passed through untouched
+type Writer struct {
+	Indent     string // one level of indentation, "" writes minified output
+	ArrayWidth int    // arrays of scalars that fit in this many columns stay on one line, 0 disables
+	Color      bool   // wrap keys and values in ansi colour escapes
+	EscapeHTML bool   // escape <, > and & and U+2028/U+2029 like encoding/json, so output can be embedded in html
+
+	out       *bufio.Writer
+	err       error
+	frames    []writerFrame
+	token     writerToken_t
+	afterKey  bool
+	documents int
+	col       int
+
+	// BEGIN: array being held back while it may still fit on one line
+	isPending    bool
+	pendingOpen  bool // the last item is a string or number still being written
+	pendingCol   int
+	pendingCols  int
+	pendingBuf   []byte
+	pendingItems []pendingItem
+	// END: array being held back
+}
+
+func NewWriter(out io.Writer) Writer {
+	return Writer{
+		out:    bufio.NewWriter(out),
+		frames: make([]writerFrame, 0, MIN_STACK_DEPTH),
+	}
+}
+
+// Err returns the first error encountered writing to the output
+func (w *Writer) Err() error {
+	return w.err
+}
+
+func (w *Writer) Flush() error {
+	if w.err == nil {
+		w.err = w.out.Flush()
+	}
+	return w.err
+}
+
+// Depth returns the number of containers currently open
+func (w *Writer) Depth() int {
+	return len(w.frames)
+}
+
+func countColumns(data []byte) int {
+	n := 0
+	for _, b := range data {
+		if b&0xC0 != 0x80 {
+			n++
+		}
+	}
+	return n
+}
+
+func (w *Writer) write(data []byte) {
+	if w.isPending {
+		w.pendingBuf = append(w.pendingBuf, data...)
+		w.pendingCols += countColumns(data)
+		w.checkPendingWidth()
+		return
+	}
+	if w.err == nil {
+		_, w.err = w.out.Write(data)
+	}
+	w.col += countColumns(data)
+}
+
+func (w *Writer) writeString(s string) {
+	if w.isPending {
+		w.pendingBuf = append(w.pendingBuf, s...)
+		w.pendingCols += len(s)
+		w.checkPendingWidth()
+		return
+	}
+	if w.err == nil {
+		_, w.err = w.out.WriteString(s)
+	}
+	w.col += len(s)
+}
+
+func (w *Writer) writeColor(color string) {
+	if w.Color && !w.isPending && w.err == nil {
+		_, w.err = w.out.WriteString(color)
+	}
+}
+
+func (w *Writer) newline(level int) {
+	if w.Indent == "" {
+		return
+	}
+	w.writeString("\n")
+	w.col = 0
+	for i := 0; i < level; i++ {
+		w.writeString(w.Indent)
+	}
+}
+
+func (w *Writer) checkPendingWidth() {
+	// "[" + items joined by ", " + "]"
+	width := w.pendingCol + w.pendingCols + 2
+	if n := len(w.pendingItems); n > 1 {
+		width += 2 * (n - 1)
+	}
+	if width > w.ArrayWidth {
+		w.expandPending()
+	}
+}
+
+// give up on keeping the pending array on one line and write out what has
+// been buffered so far in the normal layout
+func (w *Writer) expandPending() {
+	w.isPending = false
+	level := len(w.frames)
+	w.writeString("[")
+	last := len(w.pendingItems) - 1
+	for i, item := range w.pendingItems {
+		end := len(w.pendingBuf)
+		if i != last {
+			end = w.pendingItems[i+1].start
+		}
+		if i != 0 {
+			w.writeString(",")
+		}
+		w.newline(level)
+		w.writeColor(item.color)
+		w.write(w.pendingBuf[item.start:end])
+		if i != last || !w.pendingOpen {
+			w.writeColor(COLOR_RESET)
+		}
+	}
+	w.frames[level-1].count = len(w.pendingItems)
+}
+
+func (w *Writer) writeCompactPending() {
+	w.isPending = false
+	w.writeString("[")
+	for i, item := range w.pendingItems {
+		end := len(w.pendingBuf)
+		if i != len(w.pendingItems)-1 {
+			end = w.pendingItems[i+1].start
+		}
+		if i != 0 {
+			w.writeString(", ")
+		}
+		w.writeColor(item.color)
+		w.write(w.pendingBuf[item.start:end])
+		w.writeColor(COLOR_RESET)
+	}
+	w.writeString("]")
+}
+
+// separators and indentation ahead of a value or key
+func (w *Writer) beforeValue(color string) {
+	if w.afterKey {
+		w.afterKey = false
+		w.writeColor(color)
+		return
+	}
+	level := len(w.frames)
+	if level == 0 {
+		if w.documents != 0 {
+			w.writeString("\n")
+			w.col = 0
+		}
+		w.documents++
+		w.writeColor(color)
+		return
+	}
+	if w.isPending {
+		w.pendingItems = append(w.pendingItems, pendingItem{len(w.pendingBuf), color})
+		return
+	}
+	frame := &w.frames[level-1]
+	if frame.count != 0 {
+		w.writeString(",")
+	}
+	frame.count++
+	w.newline(level)
+	w.writeColor(color)
+}
+
+func (w *Writer) beginContainer(kind writerFrame_t) {
+	if w.isPending {
+		// only arrays of scalars are kept on one line
+		w.expandPending()
+	}
+	w.beforeValue("")
+	w.frames = append(w.frames, writerFrame{kind: kind})
+	if kind == WRITER_FRAME_ARRAY && w.ArrayWidth > 0 && w.Indent != "" {
+		w.isPending = true
+		w.pendingOpen = false
+		w.pendingCol = w.col
+		w.pendingCols = 0
+		w.pendingBuf = w.pendingBuf[:0]
+		w.pendingItems = w.pendingItems[:0]
+		w.checkPendingWidth()
+		return
+	}
+	if kind == WRITER_FRAME_ARRAY {
+		w.writeString("[")
+	} else {
+		w.writeString("{")
+	}
+}
+
+func (w *Writer) endContainer(closer string) {
+	if w.isPending {
+		w.frames = w.frames[:len(w.frames)-1]
+		w.writeCompactPending()
+		return
+	}
+	level := len(w.frames) - 1
+	count := w.frames[level].count
+	w.frames = w.frames[:level]
+	if count != 0 {
+		w.newline(level)
+	}
+	w.writeString(closer)
+}
+
+func (w *Writer) BeginArray() {
+	w.beginContainer(WRITER_FRAME_ARRAY)
+}
+
+func (w *Writer) EndArray() {
+	w.endContainer("]")
+}
+
+func (w *Writer) BeginDict() {
+	w.beginContainer(WRITER_FRAME_DICT)
+}
+
+func (w *Writer) EndDict() {
+	w.endContainer("}")
+}
+
+func (w *Writer) BeginKey() {
+	w.beforeValue(COLOR_KEY)
+	w.token = WRITER_TOKEN_KEY
+	w.writeString(`"`)
+}
+
+func (w *Writer) BeginString() {
+	w.beforeValue(COLOR_STRING)
+	w.pendingOpen = true
+	w.token = WRITER_TOKEN_STRING
+	w.writeString(`"`)
+}
+
+// escape sequence for each byte json strings cannot hold verbatim, "" for
+// the rest; the same minimal set RFC 8785 canonical output uses
+var stringEscapes = func() (escapes [256]string) {
+	for b := 0; b < 0x20; b++ {
+		escapes[b] = string([]byte{'\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xF]})
+	}
+	escapes['\b'] = `\b`
+	escapes['\f'] = `\f`
+	escapes['\n'] = `\n`
+	escapes['\r'] = `\r`
+	escapes['\t'] = `\t`
+	escapes['"'] = `\"`
+	escapes['\\'] = `\\`
+	return
+}()
+
+var htmlStringEscapes = func() (escapes [256]string) {
+	escapes = stringEscapes
+	escapes['<'] = `\u003c`
+	escapes['>'] = `\u003e`
+	escapes['&'] = `\u0026`
+	return
+}()
+
+// lineSeparatorEscape returns the escape for U+2028 or U+2029 at the start
+// of data, which are valid in json strings but not in javascript ones
+func lineSeparatorEscape(data []byte) string {
+	if len(data) >= 3 && data[0] == 0xE2 && data[1] == 0x80 {
+		switch data[2] {
+		case 0xA8:
+			return `\u2028`
+		case 0xA9:
+			return `\u2029`
+		}
+	}
+	return ""
+}
+
+func appendEscaped(dst []byte, data []byte) []byte {
+	start := 0
+	for i, b := range data {
+		if escaped := stringEscapes[b]; escaped != "" {
+			dst = append(append(dst, data[start:i]...), escaped...)
+			start = i + 1
+		}
+	}
+	return append(dst, data[start:]...)
+}
+
+// StringData escapes and writes the next chunk of the current key or string
+//
+// With EscapeHTML, U+2028 and U+2029 are only escaped when their utf-8
+// bytes are not split across chunks
+func (w *Writer) StringData(data []byte) {
+	escapes := &stringEscapes
+	if w.EscapeHTML {
+		escapes = &htmlStringEscapes
+	}
+	start := 0
+	for i := 0; i < len(data); i++ {
+		escaped, width := escapes[data[i]], 1
+		if escaped == "" && w.EscapeHTML && data[i] == 0xE2 {
+			escaped, width = lineSeparatorEscape(data[i:]), 3
+		}
+		if escaped != "" {
+			if start != i {
+				w.write(data[start:i])
+			}
+			w.writeString(escaped)
+			i += width - 1
+			start = i + 1
+		}
+	}
+	if start != len(data) {
+		w.write(data[start:])
+	}
+}
+
+// EndString closes the current key or string; after a key the writer
+// expects that key's value next
+func (w *Writer) EndString() {
+	w.writeString(`"`)
+	w.pendingOpen = false
+	w.writeColor(COLOR_RESET)
+	if w.token == WRITER_TOKEN_KEY {
+		if w.Indent == "" {
+			w.writeString(":")
+		} else {
+			w.writeString(": ")
+		}
+		w.afterKey = true
+	}
+	w.token = WRITER_TOKEN_NONE
+}
+
+func (w *Writer) BeginNumber() {
+	w.beforeValue(COLOR_NUMBER)
+	w.pendingOpen = true
+	w.token = WRITER_TOKEN_NUMBER
+}
+
+// NumberData writes the next chunk of the current number verbatim
+func (w *Writer) NumberData(data []byte) {
+	w.write(data)
+}
+
+func (w *Writer) EndNumber() {
+	w.pendingOpen = false
+	w.writeColor(COLOR_RESET)
+	w.token = WRITER_TOKEN_NONE
+}
+
+// Leave closes whatever was most recently begun, mirroring EVT_LEAVE
+func (w *Writer) Leave() {
+	switch w.token {
+	case WRITER_TOKEN_NUMBER:
+		w.EndNumber()
+	case WRITER_TOKEN_KEY, WRITER_TOKEN_STRING:
+		w.EndString()
+	default:
+		if w.frames[len(w.frames)-1].kind == WRITER_FRAME_ARRAY {
+			w.EndArray()
+		} else {
+			w.EndDict()
+		}
+	}
+}
+
+func (w *Writer) Key(key string) {
+	w.BeginKey()
+	w.StringData([]byte(key))
+	w.EndString()
+}
+
+func (w *Writer) String(value string) {
+	w.BeginString()
+	w.StringData([]byte(value))
+	w.EndString()
+}
+
+// Number writes a number that is already in json syntax
+func (w *Writer) Number(value string) {
+	w.BeginNumber()
+	w.writeString(value)
+	w.EndNumber()
+}
+
+func (w *Writer) Null() {
+	w.beforeValue(COLOR_NULL)
+	w.writeString(VALUE_STR_NULL)
+	w.writeColor(COLOR_RESET)
+}
+
+func (w *Writer) Bool(value bool) {
+	w.beforeValue(COLOR_BOOL)
+	if value {
+		w.writeString(VALUE_STR_TRUE)
+	} else {
+		w.writeString(VALUE_STR_FALSE)
+	}
+	w.writeColor(COLOR_RESET)
+}
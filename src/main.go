@@ -3,22 +3,18 @@ package main
 import (
 	"./EvLJson"
 	"bufio"
-	//"fmt" // DEBUG
-	"log"
-	"net/http"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 /*
 
-Lets assume that there is copper somewhere between the target and us:
-
--
-http://stackoverflow.com/questions/2613734/maximum-packet-size-for-a-tcp-connection
--
-
-The absolute limitation on TCP packet size is 64K (65535 bytes), but in practicality this is far larger than the size of any packet you will see, because the lower layers (e.g. ethernet) have lower packet sizes. The MTU (Maximum Transmission Unit) for Ethernet, for instance, is 1500 bytes.
-
-
 default buffer size as of 7/24/2015:
 
 -
@@ -42,22 +38,440 @@ const TCP_HEADER_SIZE = 40
 const BUFIO_READER_SIZE = 1500 - TCP_HEADER_SIZE // between ( 1024 = 2 ^ 10 ) and ( 2048 = 2 ^ 11 )
 const LITERAL_BUFF_SIZE = 13200                  // 13200 ( 2200 * 6 )
 
-func main() {
+const ( // exit codes
+	EXIT_OK        = 0
+	EXIT_CHECK     = 1
+	EXIT_ERROR     = 2
+	EXIT_USAGE     = 2
+	STDIN_FILENAME = "-"
+)
+
+const USAGE = `usage: evljson <command> [arguments]
+
+commands:
+  fmt            re-indent or minify json from stdin or files
+  infer-schema   infer a json schema from documents or ndjson
+  gen            generate reflection free decoders for go struct types
+  gen-structs    generate go struct types from sample json documents
+`
+
+var errNotFormatted = errors.New("not formatted")
+
+type fmtOptions struct {
+	indent  int
+	tabs    bool
+	minify  bool
+	width   int
+	color   bool
+	check   bool
+	inPlace bool
+}
+
+func (opts *fmtOptions) newWriter(out io.Writer) EvLJson.Writer {
+	w := EvLJson.NewWriter(out)
+	if !opts.minify {
+		if opts.tabs {
+			w.Indent = "\t"
+		} else {
+			w.Indent = strings.Repeat(" ", opts.indent)
+		}
+		w.ArrayWidth = opts.width
+	}
+	w.Color = opts.color
+	return w
+}
+
+func (opts *fmtOptions) format(in io.Reader, out io.Writer) error {
+	w := opts.newWriter(out)
+	reader := bufio.NewReaderSize(in, BUFIO_READER_SIZE)
+	if err := EvLJson.Format(reader, &w, make([]byte, 0, LITERAL_BUFF_SIZE)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
+
+// compareWriter consumes formatted output and compares it against the
+// original input as it goes, so --check never holds either in memory
+type compareWriter struct {
+	original *bufio.Reader
+	buffer   []byte
+	differs  bool
+}
+
+func (c *compareWriter) Write(data []byte) (int, error) {
+	if c.differs {
+		return len(data), nil
+	}
+	if cap(c.buffer) < len(data) {
+		c.buffer = make([]byte, len(data))
+	}
+	buffer := c.buffer[:len(data)]
+	if _, err := io.ReadFull(c.original, buffer); err != nil || !bytes.Equal(buffer, data) {
+		c.differs = true
+	}
+	return len(data), nil
+}
+
+func (opts *fmtOptions) checkFile(path string) error {
+	in, original, err := openTwice(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	defer original.Close()
+	compare := compareWriter{original: bufio.NewReaderSize(original, BUFIO_READER_SIZE)}
+	if err = opts.format(in, &compare); err != nil {
+		return err
+	}
+	if _, err = compare.original.ReadByte(); err != io.EOF {
+		compare.differs = true
+	}
+	if compare.differs {
+		return errNotFormatted
+	}
+	return nil
+}
+
+func openTwice(path string) (*os.File, *os.File, error) {
+	if path == STDIN_FILENAME {
+		return nil, nil, errors.New("-check needs files, stdin can only be read once")
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	original, err := os.Open(path)
+	if err != nil {
+		in.Close()
+		return nil, nil, err
+	}
+	return in, original, nil
+}
+
+// rewrite formats path into a temporary file beside it and renames that
+// over the original only once formatting has succeeded
+func (opts *fmtOptions) rewrite(path string) error {
+	if path == STDIN_FILENAME {
+		return errors.New("-w needs files, not stdin")
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	out := bufio.NewWriter(tmp)
+	err = opts.format(in, out)
+	if err == nil {
+		err = out.Flush()
+	}
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (opts *fmtOptions) print(path string) error {
+	in := os.Stdin
+	if path != STDIN_FILENAME {
+		var err error
+		if in, err = os.Open(path); err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+	out := bufio.NewWriter(os.Stdout)
+	if err := opts.format(in, out); err != nil {
+		out.Flush()
+		return err
+	}
+	return out.Flush()
+}
+
+func runFmt(args []string) int {
+	opts := fmtOptions{}
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: evljson fmt [flags] [file ...]")
+		flags.PrintDefaults()
+	}
+	flags.IntVar(&opts.indent, "indent", 2, "number of spaces per indentation level")
+	flags.BoolVar(&opts.tabs, "tabs", false, "indent with tabs instead of spaces")
+	flags.BoolVar(&opts.minify, "minify", false, "write minified output without whitespace")
+	flags.IntVar(&opts.width, "width", 0, "keep arrays of scalars on one line when they fit in this many columns (0 disables)")
+	flags.BoolVar(&opts.color, "color", false, "colour output with ansi escapes")
+	flags.BoolVar(&opts.check, "check", false, "list files that are not already formatted and exit non-zero if there are any")
+	flags.BoolVar(&opts.inPlace, "w", false, "rewrite files in place instead of printing them")
+	if err := flags.Parse(args); err != nil {
+		return EXIT_USAGE
+	}
+	if opts.check && opts.inPlace {
+		fmt.Fprintln(os.Stderr, "evljson fmt: -check and -w are mutually exclusive")
+		return EXIT_USAGE
+	}
+	if opts.indent < 0 {
+		fmt.Fprintln(os.Stderr, "evljson fmt: -indent must not be negative")
+		return EXIT_USAGE
+	}
+	if opts.check || opts.inPlace {
+		// colour escapes never belong in files
+		opts.color = false
+	}
 
-	httpResponse, err := http.Get("http://127.0.0.1:8080")
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{STDIN_FILENAME}
+	}
 
+	exitCode := EXIT_OK
+	for _, path := range paths {
+		var err error
+		switch {
+		case opts.check:
+			err = opts.checkFile(path)
+		case opts.inPlace:
+			err = opts.rewrite(path)
+		default:
+			err = opts.print(path)
+		}
+		if err == errNotFormatted {
+			fmt.Println(path)
+			if exitCode == EXIT_OK {
+				exitCode = EXIT_CHECK
+			}
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "evljson fmt: %s: %s\n", path, err)
+			exitCode = EXIT_ERROR
+		}
+	}
+	return exitCode
+}
+
+// stringList collects every use of a repeatable flag
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+func loadInferredSchema(path string) (EvLJson.InferredSchema, error) {
+	in, err := os.Open(path)
 	if err != nil {
-		log.Fatal(err)
+		return EvLJson.InferredSchema{}, err
+	}
+	defer in.Close()
+	return EvLJson.LoadInferredSchema(bufio.NewReaderSize(in, BUFIO_READER_SIZE))
+}
+
+func observeFile(schema *EvLJson.InferredSchema, path string) error {
+	in := os.Stdin
+	if path != STDIN_FILENAME {
+		var err error
+		if in, err = os.Open(path); err != nil {
+			return err
+		}
+		defer in.Close()
 	}
+	return schema.ObserveStream(bufio.NewReaderSize(in, BUFIO_READER_SIZE))
+}
 
-	bufferedReader := bufio.NewReaderSize(httpResponse.Body, BUFIO_READER_SIZE)
-	evLJsonParser := EvLJson.NewParser(nil, nil, 0)
+func runInferSchema(args []string) int {
+	var merges stringList
+	opts := fmtOptions{}
+	flags := flag.NewFlagSet("infer-schema", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: evljson infer-schema [flags] [file ...]")
+		flags.PrintDefaults()
+	}
+	flags.Var(&merges, "merge", "merge in a schema inferred from an earlier batch (repeatable)")
+	maxProperties := flags.Int("max-properties", EvLJson.DEFAULT_MAX_INFERRED_PROPERTIES, "treat dicts with more distinct keys than this as maps")
+	flags.IntVar(&opts.indent, "indent", 2, "number of spaces per indentation level")
+	flags.BoolVar(&opts.minify, "minify", false, "write minified output without whitespace")
+	if err := flags.Parse(args); err != nil {
+		return EXIT_USAGE
+	}
 
-	err = evLJsonParser.Parse(bufferedReader, nil, nil)
+	schema := EvLJson.NewInferredSchema()
+	schema.MaxProperties = *maxProperties
+	for _, path := range merges {
+		loaded, err := loadInferredSchema(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "evljson infer-schema: %s: %s\n", path, err)
+			return EXIT_ERROR
+		}
+		schema.Merge(&loaded)
+	}
 
+	paths := flags.Args()
+	if len(paths) == 0 && len(merges) == 0 {
+		paths = []string{STDIN_FILENAME}
+	}
+	for _, path := range paths {
+		if err := observeFile(&schema, path); err != nil {
+			fmt.Fprintf(os.Stderr, "evljson infer-schema: %s: %s\n", path, err)
+			return EXIT_ERROR
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	w := opts.newWriter(out)
+	err := schema.Write(&w)
+	if err == nil {
+		_, err = out.WriteString("\n")
+	}
+	if err == nil {
+		err = out.Flush()
+	}
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintf(os.Stderr, "evljson infer-schema: %s\n", err)
+		return EXIT_ERROR
 	}
+	return EXIT_OK
+}
 
-	defer httpResponse.Body.Close()
+const DEFAULT_RUNTIME_IMPORT = "EvLJson"
+
+// packageSources lists the go files of the package in the current
+// directory, which is where go generate runs commands
+func packageSources(output string) ([]string, error) {
+	paths, err := filepath.Glob("*.go")
+	if err != nil {
+		return nil, err
+	}
+	var sources []string
+	for _, path := range paths {
+		if !strings.HasSuffix(path, "_test.go") && path != filepath.Base(output) {
+			sources = append(sources, path)
+		}
+	}
+	return sources, nil
+}
+
+func runGen(args []string) int {
+	flags := flag.NewFlagSet("gen", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: evljson gen -type T[,T...] [flags] [file.go ...]")
+		flags.PrintDefaults()
+	}
+	typeList := flags.String("type", "", "comma separated struct types to generate decoders for")
+	output := flags.String("o", "", "output file (default <type>_evljson.go)")
+	runtime := flags.String("runtime", DEFAULT_RUNTIME_IMPORT, "import path of the EvLJson package")
+	if err := flags.Parse(args); err != nil {
+		return EXIT_USAGE
+	}
+	if *typeList == "" {
+		fmt.Fprintln(os.Stderr, "evljson gen: -type is required")
+		return EXIT_USAGE
+	}
+	typeNames := strings.Split(*typeList, ",")
+	if *output == "" {
+		*output = strings.ToLower(typeNames[0]) + "_evljson.go"
+	}
+
+	sources := flags.Args()
+	if len(sources) == 0 {
+		var err error
+		if sources, err = packageSources(*output); err != nil {
+			fmt.Fprintf(os.Stderr, "evljson gen: %s\n", err)
+			return EXIT_ERROR
+		}
+	}
+	opts := EvLJson.GenerateOptions{RuntimeImport: *runtime, Command: "evljson gen " + strings.Join(args, " ")}
+	if os.Getenv("GOPACKAGE") == "EvLJson" {
+		// generating into the runtime package itself
+		opts.RuntimeImport = ""
+	}
+	generated, err := EvLJson.GenerateDecoders(sources, typeNames, opts)
+	if err == nil {
+		err = os.WriteFile(*output, generated, 0666)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "evljson gen: %s\n", err)
+		return EXIT_ERROR
+	}
+	return EXIT_OK
+}
+
+func runGenStructs(args []string) int {
+	flags := flag.NewFlagSet("gen-structs", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: evljson gen-structs [flags] [sample.json ...]")
+		flags.PrintDefaults()
+	}
+	typeName := flags.String("type", "Document", "name of the go type for a whole document")
+	packageName := flags.String("package", os.Getenv("GOPACKAGE"), "package of the generated file (default $GOPACKAGE or main)")
+	output := flags.String("o", "", "output file (default stdout)")
+	if err := flags.Parse(args); err != nil {
+		return EXIT_USAGE
+	}
+	if *packageName == "" {
+		*packageName = "main"
+	}
+
+	schema := EvLJson.NewInferredSchema()
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{STDIN_FILENAME}
+	}
+	for _, path := range paths {
+		if err := observeFile(&schema, path); err != nil {
+			fmt.Fprintf(os.Stderr, "evljson gen-structs: %s: %s\n", path, err)
+			return EXIT_ERROR
+		}
+	}
+	generated, err := schema.GenerateStructs(*packageName, *typeName)
+	if err == nil {
+		if *output == "" {
+			_, err = os.Stdout.Write(generated)
+		} else {
+			err = os.WriteFile(*output, generated, 0666)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "evljson gen-structs: %s\n", err)
+		return EXIT_ERROR
+	}
+	return EXIT_OK
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, USAGE)
+		os.Exit(EXIT_USAGE)
+	}
+	switch os.Args[1] {
+	case "fmt":
+		os.Exit(runFmt(os.Args[2:]))
+	case "infer-schema":
+		os.Exit(runInferSchema(os.Args[2:]))
+	case "gen":
+		os.Exit(runGen(os.Args[2:]))
+	case "gen-structs":
+		os.Exit(runGenStructs(os.Args[2:]))
+	case "help", "-h", "-help", "--help":
+		fmt.Print(USAGE)
+	default:
+		fmt.Fprintf(os.Stderr, "evljson: unknown command %q\n\n%s", os.Args[1], USAGE)
+		os.Exit(EXIT_USAGE)
+	}
 }